@@ -0,0 +1,136 @@
+package lexer
+
+import (
+	"bytes"
+	"fmt"
+)
+
+/* --- ACTIONS ---
+Actions are callbacks run as tokens of a kind
+are produced, for small context sensitive
+behaviors the grammar cannot express. An action
+may change the token, drop it, record a
+diagnostic, or switch grammars; the input after
+the token is then tokenized with the grammar
+pushed, until it is popped, much as with lexer
+modes. Grammars pushed last until the end of the
+input being tokenized. */
+
+/* Called as a token of the kind it is registered for is produced. */
+type TokenAction func(ctx *TokenContext)
+
+/* The token an action is run for, and what it may do about it. */
+type TokenContext struct {
+	Token TokenObject // The token produced; changes are kept.
+
+	lexer    *Lexer
+	grammar  *Grammar // Grammar the token was matched against.
+	run      *lexRun
+	drop     bool
+	switched bool
+}
+
+/*
+Run `action` as tokens of the named kind are
+produced. Actions of a kind run in the order
+registered. Actions must not be registered while
+the lexer is in use.
+*/
+func (lx *Lexer) OnToken(kind string, action TokenAction) {
+	if lx.actions == nil {
+		lx.actions = map[tokenName][]TokenAction{}
+	}
+	lx.actions[tokenName(kind)] = append(lx.actions[tokenName(kind)], action)
+}
+
+/*
+Run the actions registered for the kind of a
+token. Returns the token as the actions left it,
+its origin recorded if changed, whether to keep
+it, and whether the grammar was switched.
+*/
+func (lx *Lexer) act(g *Grammar, to TokenObject, run *lexRun) (TokenObject, bool, bool) {
+	actions := lx.actions[to.Kind.Name]
+	if len(actions) == 0 {
+		return to, true, false
+	}
+
+	ctx := TokenContext{Token: to, lexer: lx, grammar: g, run: run}
+	for _, action := range actions {
+		action(&ctx)
+	}
+	changed := ctx.Token.Kind.Id != to.Kind.Id || !bytes.Equal(ctx.Token.Symbol, to.Symbol)
+	if changed && ctx.Token.Origin == to.Origin {
+		// Actions producing tokens of their own
+		// record their origins themselves.
+		ctx.Token.Origin = to.Derive("action").Origin
+	}
+	return ctx.Token, !ctx.drop, ctx.switched
+}
+
+/* Leave the token out of the results. */
+func (ctx *TokenContext) Drop() {
+	ctx.drop = true
+}
+
+/*
+Change the token to the named kind of the
+grammar it was matched against.
+*/
+func (ctx *TokenContext) SetKind(name string) error {
+	kind, ok := ctx.grammar.kinds.ByName(tokenName(name))
+	if !ok {
+		return fmt.Errorf("unknown kind %q", name)
+	}
+	ctx.Token.Kind = ctx.grammar.kinds.Ref(kind.Id)
+	return nil
+}
+
+/*
+Record a warning about the token, if the lexer
+records diagnostics and its line does not
+silence it.
+*/
+func (ctx *TokenContext) Diagnose(msg string) {
+	ctx.lexer.diagnose(ctx.run, Diagnostic{Token: ctx.Token, Message: msg, Code: CodeAction, Severity: SeverityWarning})
+}
+
+/* Tokenize the input after the token with `g`. */
+func (ctx *TokenContext) PushGrammar(g *Grammar) {
+	ctx.run.modes = append(ctx.run.modes, g)
+	ctx.switched = true
+}
+
+/*
+Go back to tokenizing with the grammar in use
+before the last push. Returns false if no
+grammar was pushed.
+*/
+func (ctx *TokenContext) PopGrammar() bool {
+	if len(ctx.run.modes) == 0 {
+		return false
+	}
+	ctx.run.modes = ctx.run.modes[:len(ctx.run.modes)-1]
+	ctx.switched = true
+	return true
+}
+
+/* State of tokenizing one input, kept from line to line. */
+type lexRun struct {
+	stopwatch
+	modes    []*Grammar  // Grammars pushed by actions, the last in use.
+	suppress suppression // Diagnostics silenced on the line.
+}
+
+/* Start tokenizing an input. */
+func (lx *Lexer) newRun() lexRun {
+	return lexRun{stopwatch: lx.stopwatch()}
+}
+
+/* The grammar to tokenize with next. */
+func (run *lexRun) grammar(lx *Lexer) *Grammar {
+	if len(run.modes) > 0 {
+		return run.modes[len(run.modes)-1]
+	}
+	return lx.grammar()
+}
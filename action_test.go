@@ -0,0 +1,61 @@
+package lexer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestTokenActions(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	g.AddKind("MINUS", "-", "")
+	if err := g.SetIdentClasses("letter"); err != nil {
+		t.Fatal(err)
+	}
+	lx := &lexer.Lexer{Grammar: g, Diagnostics: &lexer.Diagnostics{}}
+
+	lx.OnToken("PLUS", func(ctx *lexer.TokenContext) {
+		if err := ctx.SetKind("MINUS"); err != nil {
+			t.Error(err)
+		}
+	})
+	lx.OnToken("WHTSPACE", func(ctx *lexer.TokenContext) { ctx.Drop() })
+	lx.OnToken("MINUS", func(ctx *lexer.TokenContext) { ctx.Diagnose("minus") })
+
+	if got := renderKinds(lx.TokenizeLine("a + b - c", 1)); got != "GENIDEN:a MINUS:+ GENIDEN:b MINUS:- GENIDEN:c" {
+		t.Errorf("unexpected tokens %s", got)
+	}
+	if len(lx.Diagnostics.List) != 1 || string(lx.Diagnostics.List[0].Token.Symbol) != "-" {
+		t.Errorf("expected one diagnostic on '-', got %v", lx.Diagnostics.List)
+	}
+}
+
+func TestTokenActionModes(t *testing.T) {
+	outer := lexer.NewGrammar("outer")
+	outer.AddKind("OPEN", "<<", "")
+	outer.AddKind("PLUS", "+", "")
+	inner := lexer.NewGrammar("inner")
+	inner.AddKind("CLOSE", ">>", "")
+	inner.AddKind("STAR", "*", "")
+
+	lx := &lexer.Lexer{Grammar: outer}
+	lx.OnToken("OPEN", func(ctx *lexer.TokenContext) { ctx.PushGrammar(inner) })
+	lx.OnToken("CLOSE", func(ctx *lexer.TokenContext) {
+		if !ctx.PopGrammar() {
+			t.Error("expected a grammar to pop")
+		}
+	})
+
+	// '+' is only known outside, '*' only inside; the
+	// mode carries over to the next line.
+	tokens, err := lx.TokenizeReader(strings.NewReader("a+<<b*\nc>>+*\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "GENIDEN:a PLUS:+ OPEN:<< GENIDEN:b STAR:* GENIDEN:c CLOSE:>> PLUS:+ GENIDEN:*"
+	if got := renderKinds(tokens); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
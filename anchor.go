@@ -0,0 +1,118 @@
+package lexer
+
+import "fmt"
+
+/* --- ANCHORS ---
+Kinds may be anchored to the start or end of a
+line, with the `@anchor` directive or
+`SetAnchor`, say for `#` preprocessor directives
+or a trailing `\` continuing a line. An anchored
+kind is only matched where its anchor holds;
+elsewhere its signature is matched as if the
+kind were not defined.
+
+start: Only whitespace comes before the token on
+its line.
+end: Only whitespace comes after the token on
+its line. */
+
+/* Where on a line an anchored kind may be matched. */
+type Anchor uint8
+
+const (
+	AnchorNone  Anchor = iota // Matched anywhere.
+	AnchorStart               // Matched at the start of a line.
+	AnchorEnd                 // Matched at the end of a line.
+)
+
+var anchorNames = []string{"none", "start", "end"}
+
+func (a Anchor) String() string {
+	if int(a) < len(anchorNames) {
+		return anchorNames[a]
+	}
+	return fmt.Sprintf("Anchor(%d)", uint8(a))
+}
+
+/* Look up an anchor by its name, e.g. `start`. */
+func ParseAnchor(name string) (Anchor, error) {
+	for i, known := range anchorNames {
+		if name == known {
+			return Anchor(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown anchor %q, expected none, start or end", name)
+}
+
+/*
+Anchor the named kind to the start or end of a
+line; `AnchorNone` lets it match anywhere again.
+*/
+func (g *Grammar) SetAnchor(kind string, anchor Anchor) error {
+	if anchor > AnchorEnd {
+		return fmt.Errorf("unknown anchor %s", anchor)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	found, ok := g.kinds.ByName(tokenName(kind))
+	if !ok {
+		return fmt.Errorf("unknown kind %q", kind)
+	}
+	g.setAnchor(found.Id, anchor)
+	return nil
+}
+
+/* Anchor a kind by ID. */
+func (g *Grammar) setAnchor(id tokenId, anchor Anchor) {
+	if anchor == AnchorNone {
+		delete(g.anchors, id)
+		return
+	}
+	if g.anchors == nil {
+		g.anchors = map[tokenId]Anchor{}
+	}
+	g.anchors[id] = anchor
+}
+
+/*
+Anchor kinds, by name. Kinds already anchored
+keep their anchor unless `overwrite` is set.
+*/
+func (g *Grammar) applyAnchors(anchors map[tokenName]Anchor, overwrite bool) {
+	for name, anchor := range anchors {
+		kind, ok := g.kinds.ByName(name)
+		if !ok {
+			continue
+		}
+		if _, anchored := g.anchors[kind.Id]; anchored && !overwrite {
+			continue
+		}
+		g.setAnchor(kind.Id, anchor)
+	}
+}
+
+/*
+Determine if the anchor of a kind holds for a
+token of `n` bytes starting `line`, the rest of a
+line. `atStart` is set if only whitespace comes
+before it.
+*/
+func (g *Grammar) anchorHolds(id tokenId, line string, n int, atStart bool) bool {
+	switch g.anchors[id] {
+	case AnchorStart:
+		return atStart
+	case AnchorEnd:
+		return g.isBlank(line[n:])
+	}
+	return true
+}
+
+/* The anchor of a kind by ID; `AnchorNone` if not anchored. */
+func (g *Grammar) anchorOf(id tokenId) Anchor {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.anchors[id]
+}
@@ -0,0 +1,82 @@
+package lexer_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestAnchoredKinds(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("HASH", "#", "")
+	g.AddKind("CONT", "\\", "")
+	if err := g.SetAnchor("HASH", lexer.AnchorStart); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetAnchor("CONT", lexer.AnchorEnd); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetAnchor("MISSING", lexer.AnchorEnd); err == nil {
+		t.Error("expected an error for an unknown kind")
+	}
+
+	cases := []struct {
+		line string
+		want []string
+	}{
+		{"#a", []string{"HASH", "GENIDEN"}},
+		{"  #a", []string{"WHTSPACE", "WHTSPACE", "HASH", "GENIDEN"}},
+		{"a#", []string{"GENIDEN"}},
+		{"a \\", []string{"GENIDEN", "WHTSPACE", "CONT"}},
+		{"a\\ ", []string{"GENIDEN", "CONT", "WHTSPACE"}},
+		{"\\a", []string{"GENIDEN"}},
+	}
+	for _, c := range cases {
+		tokens := g.TokenizeLine(c.line, 1)
+		var got []string
+		for _, to := range tokens {
+			got = append(got, string(to.Kind.Name))
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("%q: expected %v, got %v", c.line, c.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%q: expected %v, got %v", c.line, c.want, got)
+				break
+			}
+		}
+	}
+
+	// Unanchored, the kind matches anywhere again.
+	if err := g.SetAnchor("HASH", lexer.AnchorNone); err != nil {
+		t.Fatal(err)
+	}
+	if tokens := g.TokenizeLine("a#", 1); len(tokens) != 2 || tokens[1].Kind.Name != "HASH" {
+		t.Errorf("expected HASH after unanchoring, got %v", tokens)
+	}
+}
+
+func TestAnchorDirective(t *testing.T) {
+	fsys := fstest.MapFS{
+		"anchor.tokens": {Data: []byte("HASH #\n@anchor HASH start\n")},
+		"bad.tokens":    {Data: []byte("HASH #\n@anchor HASH middle\n")},
+		"early.tokens":  {Data: []byte("@anchor HASH start\nHASH #\n")},
+	}
+
+	g := lexer.NewGrammar("x")
+	if err := g.LoadTokensFS(fsys, "anchor.tokens"); err != nil {
+		t.Fatal(err)
+	}
+	if tokens := g.TokenizeLine("#x#", 1); len(tokens) != 2 || tokens[0].Kind.Name != "HASH" {
+		t.Errorf("expected HASH then GENIDEN, got %v", tokens)
+	}
+
+	for _, name := range []string{"bad.tokens", "early.tokens"} {
+		if err := g.LoadTokensFS(fsys, name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/* --- ANONYMIZING ---
+Reproducing a bug often takes a file from a
+proprietary codebase. Anonymizing its tokens
+replaces the spellings of identifiers, the
+content of string and number literals, and the
+text of comments with placeholders: `ident_1`,
+`"str_1"`, `1`, `// comment_1`. The same symbol is
+always given the same placeholder, so the
+structure of the source survives, and every
+token keeps its kind and position as tokenized.
+
+Keywords, operators and other kinds defined by
+the grammar are left as they are, as is input
+matching no kind, which is most often the bug
+being reproduced. */
+
+/*
+Replaces symbols with placeholders, consistently
+across every series of tokens it anonymizes.
+*/
+type Anonymizer struct {
+	placeholders map[string]string // By category and symbol.
+	counts       map[string]int    // Placeholders given per category.
+}
+
+/* Initialize a new `Anonymizer`. */
+func NewAnonymizer() *Anonymizer {
+	return &Anonymizer{placeholders: map[string]string{}, counts: map[string]int{}}
+}
+
+/*
+Replace the symbols of identifiers, literals and
+comments of the tokens with placeholders.
+Returns a new series of tokens; the given tokens
+are not modified.
+*/
+func (an *Anonymizer) Anonymize(tokens TokenObjects) TokenObjects {
+	anonymized := make(TokenObjects, len(tokens))
+	for i, to := range tokens {
+		anonymized[i] = an.anonymize(to)
+	}
+	return anonymized
+}
+
+/* Replace the symbol of a single token, and of its comments. */
+func (an *Anonymizer) anonymize(to TokenObject) TokenObject {
+	// Origins would give away the symbols replaced.
+	to.Origin = nil
+	if len(to.Comments) > 0 {
+		to.Comments = an.Anonymize(to.Comments)
+	}
+	if to.Kind == nil {
+		return to
+	}
+
+	symbol := string(to.Symbol)
+	switch to.Value.(type) {
+	case string:
+		quote := "\""
+		if strings.HasPrefix(symbol, "`") {
+			quote = "`"
+		}
+		placeholder := an.placeholder("str", symbol, func(n int) string { return fmt.Sprintf("str_%d", n) })
+		to.Symbol, to.Value = tokenSignature(quote+placeholder+quote), placeholder
+		return to
+	case int64:
+		placeholder := an.placeholder("num", symbol, strconv.Itoa)
+		to.Symbol = tokenSignature(placeholder)
+		to.Value, _ = strconv.ParseInt(placeholder, 10, 64)
+		return to
+	case float64:
+		placeholder := an.placeholder("num", symbol, func(n int) string { return strconv.Itoa(n) + ".0" })
+		to.Symbol = tokenSignature(placeholder)
+		to.Value, _ = strconv.ParseFloat(placeholder, 64)
+		return to
+	case bool:
+		return to
+	}
+
+	switch to.Kind.Id {
+	case GenIdenId, GenTypeId, GenObjId:
+		to.Symbol = tokenSignature(an.placeholder("ident", symbol, func(n int) string { return fmt.Sprintf("ident_%d", n) }))
+	case CommentId:
+		marker := symbol[:len(symbol)-len(strings.TrimLeftFunc(symbol, isCommentMarkerRune))]
+		placeholder := an.placeholder("comment", symbol, func(n int) string { return fmt.Sprintf("comment_%d", n) })
+		to.Symbol = tokenSignature(marker + " " + placeholder)
+	}
+	return to
+}
+
+/* Determine if a rune may be part of a comment marker. */
+func isCommentMarkerRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
+
+/*
+The placeholder of a symbol of the category,
+giving it the next placeholder per `format` if
+it has none yet.
+*/
+func (an *Anonymizer) placeholder(category string, symbol string, format func(n int) string) string {
+	key := category + "\x00" + symbol
+	if placeholder, ok := an.placeholders[key]; ok {
+		return placeholder
+	}
+	an.counts[category]++
+	placeholder := format(an.counts[category])
+	an.placeholders[key] = placeholder
+	return placeholder
+}
+
+/*
+Replace the symbols of identifiers, literals and
+comments of the tokens with placeholders.
+*/
+func Anonymize(tokens TokenObjects) TokenObjects {
+	return NewAnonymizer().Anonymize(tokens)
+}
+
+/*
+Rebuild text from the symbols of the tokens, a
+line per line number from that of the first
+token to that of the last. Lines without tokens
+are kept as empty lines.
+*/
+func (tom TokenObjects) Text() string {
+	var sb strings.Builder
+	if len(tom) == 0 {
+		return ""
+	}
+	lineNo := tom[0].LineNo
+	for _, to := range tom {
+		for ; lineNo < to.LineNo; lineNo++ {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(string(to.Symbol))
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
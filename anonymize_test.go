@@ -0,0 +1,48 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestAnonymize(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("LET", "let", "")
+	g.AddKind("ASSIGN", "=", "")
+	if err := g.SetLiteral("GENIDEN", lexer.LiteralInt); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetLineComments("//"); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := g.TokenizeLines([]string{"let secret = 42 // the answer", "", "let other = secret"})
+	an := lexer.NewAnonymizer()
+	anonymized := an.Anonymize(tokens)
+	if got := anonymized.Text(); got != "let ident_1 = 1 // comment_1\n\nlet ident_2 = ident_1\n" {
+		t.Errorf("unexpected text %q", got)
+	}
+	for i := range tokens {
+		if anonymized[i].Kind != tokens[i].Kind || anonymized[i].Position != tokens[i].Position || anonymized[i].LineNo != tokens[i].LineNo {
+			t.Errorf("expected kind and position kept, got %v for %v", anonymized[i], tokens[i])
+		}
+	}
+	if v, ok := anonymized[6].IntValue(); !ok || v != 1 {
+		t.Errorf("expected the value of the placeholder, got %v", anonymized[6].Value)
+	}
+	if tokens[2].Symbol.String() != "secret" {
+		t.Error("expected the given tokens not to be modified")
+	}
+
+	// Consistent across calls of the same anonymizer.
+	if got := an.Anonymize(g.TokenizeLine("other", 1)).Text(); got != "ident_2\n" {
+		t.Errorf("expected ident_2, got %q", got)
+	}
+
+	str, _ := g.LookupKind("GENIDEN")
+	quoted := lexer.TokenObjects{{Kind: &str, LineNo: 1, Position: 1, Symbol: []byte("`raw`"), Value: "raw"}}
+	if got := lexer.Anonymize(quoted); got.Text() != "`str_1`\n" || got[0].Value != "str_1" {
+		t.Errorf("unexpected string placeholder %v", got)
+	}
+}
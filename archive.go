@@ -0,0 +1,170 @@
+package lexer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+/* --- ARCHIVE TOKENIZING ---
+Tokenize the files held in zip or tar archives
+without unpacking them to disk first. Each entry
+is tokenized with the grammar resolved for it, or
+the default grammar. Results are keyed by each
+entry's path within the archive.
+
+Entries that appear to be binary are skipped, as
+archives often hold images and the like beside
+sources. Any other error of an entry stops the
+walk, and is returned naming the entry. */
+
+/*
+Determine if an archive entry matches the given
+glob. Patterns without a '/' are matched against
+the entry's base name, so `*.pz` matches files in
+any directory.
+*/
+func matchEntry(pattern string, name string) (bool, error) {
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	}
+	return path.Match(pattern, name)
+}
+
+/*
+Name the archive entry in an error of it, as
+positioned errors do, or as a prefix otherwise.
+*/
+func entryError(err error, name string) error {
+	switch err.(type) {
+	case UnknownInputError, StepLimitError:
+		return withFilename(err, name)
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+/*
+Pick a registered grammar for the named file of
+the given file system, falling back on the
+default grammar if none match.
+*/
+func resolveGrammarFS(fsys fs.FS, name string) (*Grammar, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	firstLine, err := readFirstLine(file)
+	if err != nil {
+		return nil, err
+	}
+	if g, ok := ResolveGrammar(name, firstLine); ok {
+		return g, nil
+	}
+	return defaultGrammar, nil
+}
+
+/*
+Tokenize every file of the given file system
+matching the glob pattern.
+*/
+func tokenizeFS(fsys fs.FS, pattern string) (map[string]TokenObjects, error) {
+	found := map[string]TokenObjects{}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ok, err := matchEntry(pattern, name)
+		if err != nil || !ok {
+			return err
+		}
+		g, err := resolveGrammarFS(fsys, name)
+		if err != nil {
+			return entryError(err, name)
+		}
+		tokens, err := g.TokenizeFileFS(fsys, name)
+		if errors.Is(err, ErrBinaryInput) {
+			return nil
+		}
+		if err != nil {
+			return entryError(err, name)
+		}
+		found[name] = tokens
+		return nil
+	})
+	return found, err
+}
+
+/*
+Tokenize every file of a zip archive matching
+the glob pattern. Returns the tokens of each
+file keyed by its path in the archive.
+*/
+func TokenizeZip(r io.ReaderAt, size int64, pattern string) (map[string]TokenObjects, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return tokenizeFS(zr, pattern)
+}
+
+/*
+Tokenize every regular file of a tar archive
+matching the glob pattern. Returns the tokens of
+each file keyed by its path in the archive.
+Compressed archives must be decompressed by the
+caller, e.g. with `gzip.NewReader`.
+*/
+func TokenizeTar(r io.Reader, pattern string) (map[string]TokenObjects, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	found := map[string]TokenObjects{}
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return found, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := strings.TrimPrefix(hdr.Name, "./")
+		if ok, _ := matchEntry(pattern, name); !ok {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, entryError(err, name)
+		}
+		firstLine, _ := readFirstLine(bytes.NewReader(data))
+		g, ok := ResolveGrammar(name, firstLine)
+		if !ok {
+			g = defaultGrammar
+		}
+		tokens, err := g.lexer().TokenizeReader(bytes.NewReader(data))
+		if errors.Is(err, ErrBinaryInput) {
+			continue
+		}
+		if err != nil {
+			return nil, entryError(err, name)
+		}
+		found[name] = tokens
+	}
+}
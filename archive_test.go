@@ -0,0 +1,126 @@
+package lexer_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+var archiveFiles = map[string]string{
+	"main.pz":      " \t\n",
+	"lib/util.pz":  "\t\n",
+	"lib/notes.md": " \n",
+}
+
+func TestTokenizeZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, text := range archiveFiles {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(text))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := lexer.TokenizeZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "*.pz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkArchiveTokens(t, found)
+}
+
+func TestTokenizeTar(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, text := range archiveFiles {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(text)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(text))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := lexer.TokenizeTar(&buf, "*.pz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkArchiveTokens(t, found)
+}
+
+func checkArchiveTokens[T ~[]lexer.TokenObject](t *testing.T, found map[string]T) {
+	t.Helper()
+	if len(found) != 2 {
+		t.Fatalf("expected 2 matching files, got %d", len(found))
+	}
+	if len(found["main.pz"]) != 2 {
+		t.Errorf("expected 2 tokens in main.pz, got %v", found["main.pz"])
+	}
+	if len(found["lib/util.pz"]) != 1 {
+		t.Errorf("expected 1 token in lib/util.pz, got %v", found["lib/util.pz"])
+	}
+}
+
+func TestTokenizeArchiveBinary(t *testing.T) {
+	binary := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	var tbuf bytes.Buffer
+	tw := tar.NewWriter(&tbuf)
+	files := map[string]string{"logo.pz": binary}
+	for name, text := range archiveFiles {
+		files[name] = text
+	}
+	for name, text := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(text))
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(text)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(text))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Binary entries are skipped, not fatal.
+	zipped, err := lexer.TokenizeZip(bytes.NewReader(zbuf.Bytes()), int64(zbuf.Len()), "*.pz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkArchiveTokens(t, zipped)
+	tarred, err := lexer.TokenizeTar(bytes.NewReader(tbuf.Bytes()), "*.pz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkArchiveTokens(t, tarred)
+
+	// Other errors name the entry.
+	var truncated bytes.Buffer
+	tw = tar.NewWriter(&truncated)
+	if err := tw.WriteHeader(&tar.Header{Name: "cut.pz", Mode: 0o644, Size: 100, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write([]byte("a b\n"))
+	if _, err := lexer.TokenizeTar(&truncated, "*.pz"); err == nil || !strings.HasPrefix(err.Error(), "cut.pz: ") {
+		t.Errorf("expected an error naming the entry, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+/* --- BATCHES ---
+Many files may be tokenized at once, by a pool of
+workers. Failures do not stop the batch; each is
+reported along with the file it occurred in. */
+
+/* Options of a batch of files. */
+type BatchOptions struct {
+	Workers int // Files tokenized at once; the number of CPUs if 0.
+}
+
+/* An error tokenizing a file of a batch. */
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (fe FileError) Error() string {
+	return fmt.Sprintf("%s: %s", fe.Path, fe.Err)
+}
+
+func (fe FileError) Unwrap() error { return fe.Err }
+
+/* The errors of the files of a batch that failed, in the order given. */
+type BatchError []FileError
+
+func (be BatchError) Error() string {
+	if len(be) == 1 {
+		return be[0].Error()
+	}
+	msgs := make([]string, len(be))
+	for i, fe := range be {
+		msgs[i] = fe.Error()
+	}
+	return fmt.Sprintf("%d files failed: %s", len(be), strings.Join(msgs, "; "))
+}
+
+/* Determine if the error of any file is `target`. */
+func (be BatchError) Is(target error) bool {
+	for _, fe := range be {
+		if errors.Is(fe, target) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Tokenize the given files concurrently, returning
+the result of each by path. Results of files that
+failed hold what was tokenized before the
+failure; the failures are returned together as a
+`BatchError`.
+*/
+func (lx *Lexer) TokenizeFiles(paths []string, opts BatchOptions) (map[string]TokenResult, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	results := make([]TokenResult, len(paths))
+	errs := make([]error, len(paths))
+	next := make(chan int)
+	var mu sync.Mutex // Guards the lexer's profile and timings.
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				// Profiles and timings are added up
+				// once each file is done.
+				run := *lx
+				if lx.Profile != nil {
+					run.Profile = &Profile{}
+				}
+				if lx.Timings != nil {
+					run.Timings = &KindTimings{}
+				}
+				results[i], errs[i] = run.TokenizeFileResult(paths[i])
+				mu.Lock()
+				if lx.Profile != nil {
+					lx.Profile.add(*run.Profile)
+				}
+				if lx.Timings != nil {
+					lx.Timings.add(run.Timings)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	byPath := make(map[string]TokenResult, len(paths))
+	var failed BatchError
+	for i, path := range paths {
+		byPath[path] = results[i]
+		if errs[i] != nil {
+			failed = append(failed, FileError{path, errs[i]})
+		}
+	}
+	if failed != nil {
+		return byPath, failed
+	}
+	return byPath, nil
+}
+
+/* Tokenize the given files concurrently. */
+func (g *Grammar) TokenizeFiles(paths []string, opts BatchOptions) (map[string]TokenResult, error) {
+	return g.lexer().TokenizeFiles(paths, opts)
+}
+
+/* Tokenize the given files concurrently using the default grammar. */
+func TokenizeFiles(paths []string, opts BatchOptions) (map[string]TokenResult, error) {
+	return defaultGrammar.TokenizeFiles(paths, opts)
+}
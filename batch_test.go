@@ -0,0 +1,45 @@
+package lexer_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestTokenizeFiles(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{}
+	for i, text := range []string{"a + b\n", "c\n", "d + e + f\n"} {
+		path := filepath.Join(dir, string(rune('a'+i))+".x")
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	missing := filepath.Join(dir, "missing.x")
+	paths = append(paths, missing)
+
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	lx := &lexer.Lexer{Grammar: g, Profile: &lexer.Profile{}, Timings: &lexer.KindTimings{}}
+
+	results, err := lx.TokenizeFiles(paths, lexer.BatchOptions{Workers: 2})
+	var batch lexer.BatchError
+	if !errors.As(err, &batch) || len(batch) != 1 || batch[0].Path != missing || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the missing file reported, got %v", err)
+	}
+	if len(results) != 4 || results[paths[0]].Len() != 5 || results[paths[2]].Len() != 9 {
+		t.Errorf("unexpected results %v", results)
+	}
+	if lx.Profile.Lines != 3 || lx.Profile.Tokens != 15 {
+		t.Errorf("expected the profile of every file, got %+v", lx.Profile)
+	}
+	for _, kt := range lx.Timings.Report() {
+		if kt.Kind == "PLUS" && kt.Count != 3 {
+			t.Errorf("expected the timings of every file, got %+v", kt)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package lexer
+
+/* --- BLOCK STRUCTURE ---
+An optional pass grouping tokens into logical
+blocks, either by pairs of open and close kinds or
+by the indentation of lines. The result is a tree
+of token ranges; not a syntax tree, but enough for
+folding in editors and coarse structural checks. */
+
+/*
+A range of tokens, and the blocks nested within
+it. Ranges run from `Start` up to, not including,
+`End`.
+*/
+type Block struct {
+	Start    int
+	End      int
+	Open     string // Kind opening the block; empty for indentation blocks.
+	Children []Block
+}
+
+/* Number of tokens in this block. */
+func (b Block) Len() int {
+	return b.End - b.Start
+}
+
+/*
+Group tokens into blocks per pairs of open and
+close kinds. Each block runs from its open token
+through its close token. Brackets left unbalanced,
+as reported by `CheckBrackets`, open no block.
+The returned root block spans every token.
+*/
+func BracketBlocks(tokens []TokenObject, pairs ...BracketPair) Block {
+	report := CheckBrackets(tokens, pairs...)
+	root := &Block{Start: 0, End: len(tokens)}
+	stack := []*Block{root}
+
+	for i, to := range tokens {
+		partner := report.Partners[i]
+		switch {
+		case partner > i:
+			stack = append(stack, &Block{Start: i, End: partner + 1, Open: string(to.Kind.Name)})
+		case partner >= 0:
+			closed := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, *closed)
+		}
+	}
+	return *root
+}
+
+/*
+Group tokens into blocks per the indentation of
+their lines. A line indented deeper than the one
+before it opens a block, which runs until the
+first line indented less than it. Indentation is
+the length of the whitespace starting a line;
+lines of only whitespace belong to the block they
+are in. The returned root block spans every token.
+*/
+func IndentBlocks(tokens []TokenObject) Block {
+	type open struct {
+		block  *Block
+		indent int
+	}
+	root := &Block{Start: 0, End: len(tokens)}
+	stack := []open{{root, 0}}
+
+	closeTo := func(indent int, end int) {
+		for len(stack) > 1 && indent < stack[len(stack)-1].indent {
+			closed := stack[len(stack)-1].block
+			closed.End = end
+			stack = stack[:len(stack)-1]
+			parent := stack[len(stack)-1].block
+			parent.Children = append(parent.Children, *closed)
+		}
+	}
+
+	for start := 0; start < len(tokens); {
+		end := start
+		for end < len(tokens) && tokens[end].LineNo == tokens[start].LineNo {
+			end += 1
+		}
+
+		indent, blank := lineIndent(tokens[start:end])
+		if !blank {
+			closeTo(indent, start)
+			if indent > stack[len(stack)-1].indent {
+				stack = append(stack, open{&Block{Start: start}, indent})
+			}
+		}
+		start = end
+	}
+	closeTo(-1, len(tokens))
+	return *root
+}
+
+/*
+The length of the whitespace starting a line of
+tokens, and whether the line is only whitespace.
+*/
+func lineIndent(line []TokenObject) (int, bool) {
+	indent := 0
+	for _, to := range line {
+		if !to.IsTrivia() {
+			return indent, false
+		}
+		indent += len(to.Symbol)
+	}
+	return indent, true
+}
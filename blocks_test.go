@@ -0,0 +1,63 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestBracketBlocks(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("LBRACE", "{", "")
+	g.AddKind("RBRACE", "}", "")
+	g.AddKind("LPAREN", "(", "")
+	g.AddKind("RPAREN", ")", "")
+
+	tokens := g.TokenizeLines([]string{"a{b(c)}", "{", "}("})
+	root := lexer.BracketBlocks(tokens,
+		lexer.BracketPair{Open: "LBRACE", Close: "RBRACE"},
+		lexer.BracketPair{Open: "LPAREN", Close: "RPAREN"})
+
+	if root.Start != 0 || root.End != len(tokens) || len(root.Children) != 2 {
+		t.Fatalf("unexpected root %+v", root)
+	}
+	outer := root.Children[0]
+	if outer.Start != 1 || outer.End != 7 || outer.Open != "LBRACE" || len(outer.Children) != 1 {
+		t.Errorf("unexpected outer block %+v", outer)
+	}
+	if inner := outer.Children[0]; inner.Start != 3 || inner.End != 6 || inner.Open != "LPAREN" {
+		t.Errorf("unexpected inner block %+v", inner)
+	}
+	if second := root.Children[1]; second.Start != 7 || second.Len() != 2 {
+		t.Errorf("unexpected second block %+v", second)
+	}
+}
+
+func TestIndentBlocks(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	lines := []string{
+		"if",    // 0
+		"  a",   // 1-3
+		"    b", // 4-8
+		"  ",    // 9-10
+		"  c",   // 11-13
+		"d",     // 14
+		"\te",   // 15-16
+	}
+	tokens := g.TokenizeLines(lines)
+	root := lexer.IndentBlocks(tokens)
+
+	if len(root.Children) != 2 {
+		t.Fatalf("expected 2 top level blocks, got %+v", root)
+	}
+	first := root.Children[0]
+	if first.Start != 1 || first.End != 14 || len(first.Children) != 1 {
+		t.Errorf("unexpected first block %+v", first)
+	}
+	if nested := first.Children[0]; nested.Start != 4 || nested.End != 11 {
+		t.Errorf("unexpected nested block %+v", nested)
+	}
+	if second := root.Children[1]; second.Start != 15 || second.End != len(tokens) {
+		t.Errorf("unexpected second block %+v", second)
+	}
+}
@@ -0,0 +1,198 @@
+package lexer
+
+import "fmt"
+
+/* --- BRACKET BALANCING ---
+An optional pass over tokens pairing up open and
+close kinds, such as parentheses and braces. Each
+token's nesting depth and the partner of each
+bracket are recorded, and unbalanced brackets are
+reported with their positions. Editors may take
+each bracket's depth and partner, for rainbow
+colors and matching highlights, from
+`BracketHighlights`. */
+
+/* Kinds, by name, opening and closing a nested group. */
+type BracketPair struct {
+	Open  string
+	Close string
+}
+
+/* An unbalanced bracket. */
+type BracketError struct {
+	Index  int // Index of the offending token.
+	Token  TokenObject
+	Reason string
+}
+
+func (be BracketError) Error() string {
+	return fmt.Sprintf("%s: %s '%s'", be.Token.Pos(), be.Reason, be.Token.Symbol)
+}
+
+/* The outcome of balancing a series of tokens. */
+type BracketReport struct {
+	// Nesting depth of each token. Brackets
+	// have the depth of the group they are in,
+	// not the one they open or close.
+	Depths []int
+
+	// Index of each bracket's partner, or -1
+	// for unbalanced brackets and other tokens.
+	Partners []int
+
+	Errors []BracketError
+}
+
+/* Determine if every bracket was balanced. */
+func (br BracketReport) Balanced() bool {
+	return len(br.Errors) == 0
+}
+
+/*
+Pair up the brackets in the given tokens.
+
+A close bracket without a matching open bracket
+is reported and skipped. A close bracket matching
+an open bracket further out closes it, reporting
+any open brackets between the two as unclosed.
+*/
+func CheckBrackets(tokens []TokenObject, pairs ...BracketPair) BracketReport {
+	br := BracketReport{
+		Depths:   make([]int, len(tokens)),
+		Partners: make([]int, len(tokens)),
+	}
+	opens := map[string]bool{}
+	closes := map[string]string{} // Close kind to open kind.
+	for _, pair := range pairs {
+		opens[pair.Open] = true
+		closes[pair.Close] = pair.Open
+	}
+
+	stack := []int{} // Indexes of unclosed open brackets.
+	unclosed := func(i int) {
+		br.Errors = append(br.Errors, BracketError{i, tokens[i], "unclosed"})
+	}
+
+	for i, to := range tokens {
+		br.Partners[i] = -1
+		br.Depths[i] = len(stack)
+		if to.Kind == nil {
+			continue
+		}
+		name := string(to.Kind.Name)
+
+		if open, ok := closes[name]; ok {
+			// Find the innermost open bracket
+			// this closes.
+			match := -1
+			for j := len(stack) - 1; j >= 0; j-- {
+				if string(tokens[stack[j]].Kind.Name) == open {
+					match = j
+					break
+				}
+			}
+			if match < 0 {
+				br.Errors = append(br.Errors, BracketError{i, to, "unexpected"})
+				continue
+			}
+			for _, j := range stack[match+1:] {
+				unclosed(j)
+			}
+
+			openIndex := stack[match]
+			stack = stack[:match]
+			br.Depths[i] = len(stack)
+			br.Partners[i] = openIndex
+			br.Partners[openIndex] = i
+			continue
+		}
+
+		if opens[name] {
+			stack = append(stack, i)
+		}
+	}
+
+	for _, j := range stack {
+		unclosed(j)
+	}
+	return br
+}
+
+/* A matched pair of brackets. */
+type BracketMatch struct {
+	Open  int // Index of the open bracket.
+	Close int // Index of the close bracket.
+	Depth int // Nesting depth of the pair, 0 for the outermost.
+}
+
+/* Retrieve the matched pairs of brackets, in order of their open brackets. */
+func (br BracketReport) Pairs() []BracketMatch {
+	pairs := []BracketMatch{}
+	for i, partner := range br.Partners {
+		if partner > i {
+			pairs = append(pairs, BracketMatch{i, partner, br.Depths[i]})
+		}
+	}
+	return pairs
+}
+
+/*
+A bracket, as editors highlight it: colored per
+its depth, rainbow style, and marking its
+partner when the cursor is on it.
+*/
+type BracketHighlight struct {
+	Token   TokenObject
+	Depth   int      // Nesting depth of its pair, 0 for the outermost.
+	Matched bool     // Unset for unbalanced brackets.
+	Partner Position // Of the matching bracket; invalid if unmatched.
+}
+
+/*
+Describe every bracket of the given tokens, in
+order, for rainbow colorization and highlighting
+matching brackets. Unbalanced brackets are
+included, unmatched, so they may be flagged.
+*/
+func BracketHighlights(tokens []TokenObject, pairs ...BracketPair) []BracketHighlight {
+	br := CheckBrackets(tokens, pairs...)
+	unbalanced := map[int]bool{}
+	for _, be := range br.Errors {
+		unbalanced[be.Index] = true
+	}
+
+	highlights := []BracketHighlight{}
+	for i, partner := range br.Partners {
+		switch {
+		case partner >= 0:
+			highlights = append(highlights, BracketHighlight{tokens[i], br.Depths[i], true, tokens[partner].Pos()})
+		case unbalanced[i]:
+			highlights = append(highlights, BracketHighlight{tokens[i], br.Depths[i], false, Position{Offset: -1}})
+		}
+	}
+	return highlights
+}
+
+// Symbols of the brackets `BracketPairs` looks for.
+var bracketSymbols = []BracketPair{{"(", ")"}, {"[", "]"}, {"{", "}"}}
+
+/*
+Pairs of the kinds matched for parentheses,
+square brackets and braces, of those this
+grammar has both an open and close kind for.
+*/
+func (g *Grammar) BracketPairs() []BracketPair {
+	pairs := []BracketPair{}
+	for _, symbols := range bracketSymbols {
+		open, close := g.FindEx(symbols.Open), g.FindEx(symbols.Close)
+		if len(open) > 0 && len(close) > 0 {
+			pairs = append(pairs, BracketPair{string(open[0].Name), string(close[0].Name)})
+		}
+	}
+	return pairs
+}
+
+/* Pairs of the bracket kinds of the default grammar. */
+func BracketPairs() []BracketPair {
+	return defaultGrammar.BracketPairs()
+}
@@ -0,0 +1,87 @@
+package lexer_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestCheckBrackets(t *testing.T) {
+	g := lexer.NewGrammar("brackets")
+	g.AddKind("LPAREN", "(", "")
+	g.AddKind("RPAREN", ")", "")
+	g.AddKind("LBRACE", "{", "")
+	g.AddKind("RBRACE", "}", "")
+	pairs := []lexer.BracketPair{{"LPAREN", "RPAREN"}, {"LBRACE", "RBRACE"}}
+
+	report := lexer.CheckBrackets(g.TokenizeLine("(a{b})", 1), pairs...)
+	if !report.Balanced() {
+		t.Errorf("expected balanced brackets, got %v", report.Errors)
+	}
+	if found := fmt.Sprint(report.Depths); found != "[0 1 1 2 1 0]" {
+		t.Errorf("unexpected depths %s", found)
+	}
+	if found := fmt.Sprint(report.Partners); found != "[5 -1 4 -1 2 0]" {
+		t.Errorf("unexpected partners %s", found)
+	}
+
+	report = lexer.CheckBrackets(g.TokenizeLine("}({)", 1), pairs...)
+	if len(report.Errors) != 2 {
+		t.Fatalf("expected 2 errors, got %v", report.Errors)
+	}
+	if found := report.Errors[0].Error(); found != "1:1: unexpected '}'" {
+		t.Errorf("unexpected error %s", found)
+	}
+	if found := report.Errors[1].Error(); found != "1:3: unclosed '{'" {
+		t.Errorf("unexpected error %s", found)
+	}
+	if report.Partners[1] != 3 {
+		t.Errorf("expected paren to be paired across the unclosed brace, got %v", report.Partners)
+	}
+}
+
+func TestBracketHighlights(t *testing.T) {
+	g := lexer.NewGrammar("brackets")
+	g.AddKind("LPAREN", "(", "")
+	g.AddKind("RPAREN", ")", "")
+	g.AddKind("LBRACE", "{", "")
+	g.AddKind("RBRACE", "}", "")
+	pairs := []lexer.BracketPair{{"LPAREN", "RPAREN"}, {"LBRACE", "RBRACE"}}
+	tokens := g.TokenizeLine("(a{b}) }", 1)
+
+	if found := fmt.Sprint(lexer.CheckBrackets(tokens, pairs...).Pairs()); found != "[{0 5 0} {2 4 1}]" {
+		t.Errorf("unexpected pairs %s", found)
+	}
+
+	highlights := lexer.BracketHighlights(tokens, pairs...)
+	if len(highlights) != 5 {
+		t.Fatalf("expected 5 brackets, got %v", highlights)
+	}
+	var found []string
+	for _, h := range highlights {
+		found = append(found, fmt.Sprintf("%s%d>%s", h.Token.Symbol, h.Depth, h.Partner))
+	}
+	if fmt.Sprint(found) != "[(0>1:6 {1>1:5 }1>1:3 )0>1:1 }0>-]" {
+		t.Errorf("unexpected highlights %v", found)
+	}
+	if last := highlights[4]; last.Matched || last.Partner.IsValid() {
+		t.Errorf("expected the stray brace to be unmatched, got %v", last)
+	}
+}
+
+func TestBracketPairs(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("LPAREN", "(", "")
+	g.AddKind("RPAREN", ")", "")
+	g.AddKind("LBRACE", "{", "")
+	g.AddKind("LBRACKET", "[", "")
+	g.AddKind("RBRACKET", "]", "")
+
+	got := g.BracketPairs()
+	want := []lexer.BracketPair{{Open: "LPAREN", Close: "RPAREN"}, {Open: "LBRACKET", Close: "RBRACKET"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
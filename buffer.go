@@ -0,0 +1,106 @@
+package lexer
+
+import "bytes"
+
+/* --- BUFFERS ---
+Input held in memory as a whole may be tokenized
+without copying each symbol out of it. The
+symbol of each token is then a slice of the
+input buffer itself, which spares an allocation
+per token on big inputs.
+
+Such tokens are only valid while the buffer is:
+the buffer must not be modified, or reused for
+other input, while its tokens are in use. Tokens
+that must outlive the buffer are copied out of
+it with `Detach`. */
+
+/*
+Break down each line of `src` into tokens whose
+symbols are slices of `src` rather than copies.
+Lines are numbered from 1, and end at a line
+feed with an optional carriage return before it.
+Returns `ErrBinaryInput` if `src` appears to be
+binary. `Decode` is not applied.
+*/
+func (lx *Lexer) TokenizeBytes(src []byte) (TokenObjects, error) {
+	head := src
+	if len(head) > binarySniffSize {
+		head = head[:binarySniffSize]
+	}
+	if isBinary(head) {
+		return nil, ErrBinaryInput
+	}
+
+	tokens := TokenObjects{}
+	lineNo := tokenLineNo(0)
+	run := lx.newRun()
+
+	for len(src) > 0 {
+		line, terminator := src, ""
+		if i := bytes.IndexByte(src, '\n'); i >= 0 {
+			line, src, terminator = src[:i], src[i+1:], "\n"
+		} else {
+			src = nil
+		}
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		lineNo += 1
+		start := len(tokens)
+
+		run.lap(stageScanning)
+
+		var err error
+		tokens, err = lx.tokenizeLineLocked(tokens, string(line), line, lineNo, &run)
+		if err != nil {
+			return tokens, err
+		}
+		tokens = lx.endLine(tokens, start, len(line), terminator, lineNo, &run)
+	}
+	return tokens, nil
+}
+
+/*
+Break down each line of `src` into tokens whose
+symbols are slices of `src`, using the default
+grammar.
+*/
+func TokenizeBytes(src []byte) (TokenObjects, error) {
+	return defaultGrammar.lexer().TokenizeBytes(src)
+}
+
+/*
+Copy of this token whose symbol no longer shares
+memory with the input it was tokenized from.
+*/
+func (to TokenObject) Detach() TokenObject {
+	to.Symbol = append(tokenSignature(nil), to.Symbol...)
+	if len(to.Comments) > 0 {
+		to.Comments = to.Comments.Detach()
+	}
+	return to
+}
+
+/*
+Copy of these tokens whose symbols no longer
+share memory with the input they were tokenized
+from. The symbols are copied into one buffer.
+*/
+func (tom TokenObjects) Detach() TokenObjects {
+	size := 0
+	for _, to := range tom {
+		size += len(to.Symbol)
+	}
+
+	buf := make([]byte, 0, size)
+	detached := make(TokenObjects, len(tom))
+	for i, to := range tom {
+		start := len(buf)
+		buf = append(buf, to.Symbol...)
+		to.Symbol = buf[start:len(buf):len(buf)]
+		if len(to.Comments) > 0 {
+			to.Comments = to.Comments.Detach()
+		}
+		detached[i] = to
+	}
+	return detached
+}
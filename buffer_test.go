@@ -0,0 +1,73 @@
+package lexer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestTokenizeBytes(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "plus")
+	lx := &lexer.Lexer{Grammar: g}
+
+	src := []byte("ab+c\r\n\n+d")
+	tokens, err := lx.TokenizeBytes(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := lx.TokenizeReader(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("expected %v, got %v", want, tokens)
+	}
+	for i := range want {
+		if !tokens[i].EqualIgnoringPosition(want[i]) || tokens[i].LineNo != want[i].LineNo || tokens[i].Position != want[i].Position {
+			t.Errorf("expected %v, got %v", want[i], tokens[i])
+		}
+	}
+
+	detached := tokens.Detach()
+	single := tokens[0].Detach()
+	copy(src, "xy")
+	if got := tokens[0].Symbol.String(); got != "xy" {
+		t.Errorf("expected symbol to share the buffer, got %q", got)
+	}
+	if got := detached[0].Symbol.String(); got != "ab" {
+		t.Errorf("expected detached symbol to keep its value, got %q", got)
+	}
+	if got := single.Symbol.String(); got != "ab" {
+		t.Errorf("expected detached symbol to keep its value, got %q", got)
+	}
+
+	// Appending to a symbol must not write into the buffer.
+	_ = append(tokens[0].Symbol, '!')
+	if src[2] != '+' {
+		t.Errorf("expected buffer to be untouched, got %q", src)
+	}
+
+	if _, err := lx.TokenizeBytes([]byte{0, 1, 2}); err != lexer.ErrBinaryInput {
+		t.Errorf("expected ErrBinaryInput, got %v", err)
+	}
+}
+
+func BenchmarkTokenizeBytes(b *testing.B) {
+	src := []byte(strings.Repeat("fn main() { x = 1 + 2; if x == 3 { return x; } }\n", 100))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lexer.TokenizeBytes(src)
+	}
+}
+
+func BenchmarkTokenizeReader(b *testing.B) {
+	src := []byte(strings.Repeat("fn main() { x = 1 + 2; if x == 3 { return x; } }\n", 100))
+	lx := &lexer.Lexer{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lx.TokenizeReader(bytes.NewReader(src))
+	}
+}
@@ -0,0 +1,333 @@
+package lexer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+/* --- CACHING ---
+Lexers given a `TokenCache` look files up in it
+before tokenizing them, by a key hashing the
+file's content along with the grammar's checksum,
+the lexer version and the options affecting the
+tokens given. Unchanged files are then not
+tokenized again; changing either the file or the
+grammar changes the key. Files are looked up
+whether their tokens or results are asked for,
+one at a time or in batches.
+
+Tokens only depend on what the key covers if the
+lexer has no actions, normalizer or diagnostics,
+so lexers with any of them skip the cache. Of
+decoders, only those of this package are told
+apart in the key; lexers with any other skip the
+cache too. */
+
+/* Holds tokens of files by key. */
+type TokenCache interface {
+	Get(key string) (TokenObjects, bool)
+	Put(key string, tokens TokenObjects)
+}
+
+/*
+Key the tokens this lexer gives `src` by, or
+false if they cannot be cached.
+*/
+func (lx *Lexer) cacheKey(src []byte) (string, bool) {
+	if lx.actions != nil || lx.Normalize != nil || lx.Diagnostics != nil {
+		return "", false
+	}
+	decoder, ok := decoderName(lx.Decode)
+	if !ok {
+		return "", false
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s %s %s %s %t %t %d %q %q %s\n", Version, lx.grammar().Checksum(), lx.Comments, lx.Newlines, lx.SkipWhitespace, lx.KeepTrivia, lx.Strict, lx.MaxSteps, lx.Suppress, lx.Fallback, decoder)
+	h.Write(src)
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+/*
+Name a decoder for cache keys; false if it is
+not one of this package's. Functions cannot be
+compared, and closures share their code, so
+others cannot be told apart.
+*/
+func decoderName(decode Decoder) (string, bool) {
+	if decode == nil {
+		return "none", true
+	}
+	pc := reflect.ValueOf(decode).Pointer()
+	switch pc {
+	case reflect.ValueOf(DetectEncoding).Pointer():
+		return "detect", true
+	case reflect.ValueOf(DecodeLatin1).Pointer():
+		return "latin1", true
+	}
+	return "", false
+}
+
+/* Read the named file to look up in the lexer's cache. */
+func (lx *Lexer) readCached(name string) ([]byte, error) {
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if lx.MaxInput > 0 && int64(len(src)) > lx.MaxInput {
+		return nil, ErrInputTooLarge
+	}
+	return src, nil
+}
+
+/*
+Look the tokens of `src` up in the lexer's
+cache, giving them the grammar's kinds. On a
+miss, returns the key to put the tokens by, or
+an empty key if they cannot be cached.
+*/
+func (lx *Lexer) lookupCached(src []byte) (TokenObjects, string, bool) {
+	key, ok := lx.cacheKey(src)
+	if !ok {
+		return nil, "", false
+	}
+	tokens, hit := lx.Cache.Get(key)
+	if !hit {
+		return nil, key, false
+	}
+
+	g := lx.grammar()
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	kr := kindResolver{g: g}
+	for i := range tokens {
+		if err := kr.rekind(&tokens[i]); err != nil {
+			return nil, key, false
+		}
+	}
+	return tokens, key, true
+}
+
+/*
+Tokenize the named file through the lexer's
+cache. Tokens read from the cache are given the
+grammar's kinds.
+*/
+func (lx *Lexer) tokenizeCached(name string) (TokenObjects, error) {
+	src, err := lx.readCached(name)
+	if err != nil {
+		return nil, err
+	}
+	tokens, key, hit := lx.lookupCached(src)
+	if hit {
+		return tokens, nil
+	}
+
+	tokens, err = lx.TokenizeReader(bytes.NewReader(src))
+	if err == nil && key != "" {
+		lx.Cache.Put(key, tokens)
+	}
+	return tokens, withFilename(err, name)
+}
+
+/*
+Tokenize the named file through the lexer's
+cache into a result. The lines of a result read
+from the cache are read again from the file.
+*/
+func (lx *Lexer) tokenizeResultCached(name string) (TokenResult, error) {
+	src, err := lx.readCached(name)
+	if err != nil {
+		return TokenResult{Source: name}, err
+	}
+	tokens, key, hit := lx.lookupCached(src)
+	if !hit {
+		tr, err := lx.TokenizeSource(name, bytes.NewReader(src))
+		if err == nil && key != "" {
+			lx.Cache.Put(key, tr.Tokens)
+		}
+		return tr, err
+	}
+
+	start := time.Now()
+	li, err := lx.indexCached(src, tokens)
+	tr := TokenResult{Source: name, Tokens: tokens, Index: li, Bytes: int64(len(src))}
+	tr.Provenance = newProvenance(lx.grammar(), name)
+	sum := sha256.Sum256(src)
+	tr.Provenance.SourceChecksum = hex.EncodeToString(sum[:])
+	tr.Stats.Lines = len(li.input)
+	tr.Stats.Tokens = len(tokens)
+	tr.Elapsed = time.Since(start)
+	return tr, withFilename(err, name)
+}
+
+/*
+Index tokens read from the cache by line,
+alongside the lines of `src`, as
+`TokenizeIndexed` would have.
+*/
+func (lx *Lexer) indexCached(src []byte, tokens TokenObjects) (LineIndex, error) {
+	li := LineIndex{tokens, map[tokenLineNo]lineRange{}, []InputLine{}}
+	r, err := newTextReader(bytes.NewReader(src), lx.decoder())
+	if err != nil {
+		return li, err
+	}
+	scanner := newLineScanner(r)
+	scanner.Split(scanLinesKeep)
+	offset := 0
+	for scanner.Scan() {
+		line := splitTerminator(scanner.Text(), offset)
+		offset += len(line.Text) + len(line.Terminator)
+		li.input = append(li.input, line)
+	}
+
+	for start := 0; start < len(tokens); {
+		end := start + 1
+		for end < len(tokens) && tokens[end].LineNo == tokens[start].LineNo {
+			end += 1
+		}
+		li.lines[tokens[start].LineNo] = lineRange{start, end}
+		start = end
+	}
+	return li, scanner.Err()
+}
+
+/*
+Holds tokens in memory. Tokens are copied in and
+out, along with their symbols, comments and
+origins, so callers may modify those they are
+given.
+*/
+type MemoryCache struct {
+	mu      sync.RWMutex
+	entries map[string]TokenObjects
+}
+
+/* Initialize a new, empty `MemoryCache`. */
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: map[string]TokenObjects{}}
+}
+
+func (mc *MemoryCache) Get(key string) (TokenObjects, bool) {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	tokens, ok := mc.entries[key]
+	return copyTokens(tokens), ok
+}
+
+func (mc *MemoryCache) Put(key string, tokens TokenObjects) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.entries[key] = copyTokens(tokens)
+}
+
+/* Copy tokens along with their symbols, comments and origins. */
+func copyTokens(tokens TokenObjects) TokenObjects {
+	if tokens == nil {
+		return nil
+	}
+	copied := make(TokenObjects, len(tokens))
+	for i, to := range tokens {
+		to.Symbol = copySymbol(to.Symbol)
+		to.Comments = copyTokens(to.Comments)
+		to.Origin = copyOrigin(to.Origin)
+		copied[i] = to
+	}
+	return copied
+}
+
+/* Copy a symbol, keeping a nil symbol nil. */
+func copySymbol(symbol tokenSignature) tokenSignature {
+	if symbol == nil {
+		return nil
+	}
+	return append(make(tokenSignature, 0, len(symbol)), symbol...)
+}
+
+/* Copy an origin along with those it was produced from. */
+func copyOrigin(o *Origin) *Origin {
+	if o == nil {
+		return nil
+	}
+	copied := *o
+	copied.Symbol = copySymbol(o.Symbol)
+	copied.Origin = copyOrigin(o.Origin)
+	return &copied
+}
+
+/* Number of entries in the cache. */
+func (mc *MemoryCache) Len() int {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	return len(mc.entries)
+}
+
+/*
+Holds tokens in files of a directory, one per
+key, encoded as MessagePack, so they outlive the
+process. Entries unreadable for any reason are
+misses, and failures to write them are ignored;
+the cache only ever saves work.
+*/
+type DiskCache struct {
+	Dir string
+}
+
+/* Initialize a new `DiskCache` in the given directory. */
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{Dir: dir}
+}
+
+func (dc *DiskCache) path(key string) string {
+	return filepath.Join(dc.Dir, key+".tokens.msgpack")
+}
+
+func (dc *DiskCache) Get(key string) (TokenObjects, bool) {
+	file, err := os.Open(dc.path(key))
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	tokens := TokenObjects{}
+	dec := NewMsgpackDecoder(file, nil)
+	for {
+		to, err := dec.Decode()
+		if err != nil {
+			return tokens, err == io.EOF
+		}
+		tokens = append(tokens, to)
+	}
+}
+
+func (dc *DiskCache) Put(key string, tokens TokenObjects) {
+	if err := os.MkdirAll(dc.Dir, 0o755); err != nil {
+		return
+	}
+	// Written aside and renamed into place, so
+	// readers never see a partial entry.
+	tmp, err := os.CreateTemp(dc.Dir, "*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	err = MsgpackFormatter{}.Format(tmp, tokens)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		os.Rename(tmp.Name(), dc.path(key))
+	}
+}
@@ -0,0 +1,185 @@
+package lexer_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+// Counts lookups that hit.
+type countingCache struct {
+	lexer.TokenCache
+	hits int64
+}
+
+func (cc *countingCache) Get(key string) (lexer.TokenObjects, bool) {
+	tokens, ok := cc.TokenCache.Get(key)
+	if ok {
+		atomic.AddInt64(&cc.hits, 1)
+	}
+	return tokens, ok
+}
+
+func TestTokenCache(t *testing.T) {
+	caches := map[string]lexer.TokenCache{
+		"memory": lexer.NewMemoryCache(),
+		"disk":   lexer.NewDiskCache(t.TempDir()),
+	}
+	for name, cache := range caches {
+		g := lexer.NewGrammar("x")
+		g.AddKind("PLUS", "+", "")
+		counted := &countingCache{TokenCache: cache}
+		lx := &lexer.Lexer{Grammar: g, Cache: counted}
+		source := writeSource(t, "a + b\n")
+
+		first, err := lx.TokenizeFile(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := lx.TokenizeFile(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counted.hits != 1 || renderKinds(second) != renderKinds(first) || second[2].Kind != first[2].Kind {
+			t.Errorf("%s: expected the second call to hit, got %d hits and %s", name, counted.hits, renderKinds(second))
+		}
+
+		// Changing either the file or the grammar misses.
+		if err := os.WriteFile(source, []byte("a - b\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if tokens, _ := lx.TokenizeFile(source); counted.hits != 1 || string(tokens[2].Symbol) != "-" {
+			t.Errorf("%s: expected a miss for a changed file", name)
+		}
+		g.AddKind("MINUS", "-", "")
+		if tokens, _ := lx.TokenizeFile(source); counted.hits != 1 || tokens[2].Kind.Name != "MINUS" {
+			t.Errorf("%s: expected a miss for a changed grammar", name)
+		}
+
+		// Lexers with diagnostics skip the cache.
+		lx.Diagnostics = &lexer.Diagnostics{}
+		lx.TokenizeFile(source)
+		if counted.hits != 1 {
+			t.Errorf("%s: expected the cache skipped", name)
+		}
+	}
+}
+
+func TestTokenizeFilesCache(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{}
+	for i, text := range []string{"a + b\n", "c\n\nd\n", "e + f + g\n"} {
+		path := filepath.Join(dir, string(rune('a'+i))+".x")
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	counted := &countingCache{TokenCache: lexer.NewMemoryCache()}
+	lx := &lexer.Lexer{Grammar: g, Cache: counted}
+
+	first, err := lx.TokenizeFiles(paths, lexer.BatchOptions{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counted.hits != 0 {
+		t.Fatalf("expected no hits of an empty cache, got %d", counted.hits)
+	}
+	second, err := lx.TokenizeFiles(paths, lexer.BatchOptions{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counted.hits != int64(len(paths)) {
+		t.Errorf("expected every file of the second batch to hit, got %d hits", counted.hits)
+	}
+
+	// Results read from the cache are as those tokenized.
+	for _, path := range paths {
+		a, b := first[path], second[path]
+		if renderKinds(a.Tokens) != renderKinds(b.Tokens) || !reflect.DeepEqual(a.Index.Lines(), b.Index.Lines()) || a.Index.Source() != b.Index.Source() ||
+			a.Stats.Lines != b.Stats.Lines || a.Provenance.SourceChecksum != b.Provenance.SourceChecksum {
+			t.Errorf("%s: expected %+v from the cache, got %+v", path, a, b)
+		}
+	}
+}
+
+func TestDiskCacheCounts(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	lx := &lexer.Lexer{Grammar: g, Newlines: lexer.NewlineCollapse, Cache: lexer.NewDiskCache(t.TempDir())}
+	source := writeSource(t, "a\n\n\nb\n")
+
+	missed, err := lx.TokenizeFile(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hit, err := lx.TokenizeFile(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missed) != 4 || missed[1].Count != 3 || !reflect.DeepEqual(hit, missed) {
+		t.Errorf("expected collapsed line breaks read back from the cache, got %+v then %+v", missed, hit)
+	}
+}
+
+func TestTokenCacheDecoders(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	counted := &countingCache{TokenCache: lexer.NewMemoryCache()}
+	source := writeSource(t, "caf\xe9\n")
+
+	latin1 := &lexer.Lexer{Grammar: g, Cache: counted, Decode: lexer.DecodeLatin1}
+	detect := &lexer.Lexer{Grammar: g, Cache: counted, Decode: lexer.DetectEncoding}
+	for _, lx := range []*lexer.Lexer{latin1, detect, latin1} {
+		if _, err := lx.TokenizeFile(source); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counted.hits != 1 {
+		t.Errorf("expected decoders keyed apart, got %d hits", counted.hits)
+	}
+
+	// Other decoders cannot be told apart, so skip the cache.
+	custom := &lexer.Lexer{Grammar: g, Cache: counted, Decode: func(r io.Reader) (io.Reader, error) { return lexer.DecodeLatin1(r) }}
+	custom.TokenizeFile(source)
+	custom.TokenizeFile(source)
+	if counted.hits != 1 {
+		t.Errorf("expected a custom decoder to skip the cache, got %d hits", counted.hits)
+	}
+}
+
+func TestMemoryCacheCopies(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	if err := g.SetLineComments("//"); err != nil {
+		t.Fatal(err)
+	}
+	lx := &lexer.Lexer{Grammar: g, Comments: lexer.CommentAttach}
+	tokens, err := lx.TokenizeReader(strings.NewReader("a // note\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens[0] = tokens[0].Derive("remap")
+	if len(tokens[0].Comments) != 1 {
+		t.Fatalf("expected a comment attached, got %+v", tokens)
+	}
+
+	cache := lexer.NewMemoryCache()
+	cache.Put("k", tokens)
+	given, _ := cache.Get("k")
+	given[0].Symbol[0] = 'z'
+	given[0].Comments[0].Symbol[0] = 'z'
+	given[0].Origin.Symbol[0] = 'z'
+	given[0].Origin.By = "changed"
+
+	again, _ := cache.Get("k")
+	if !reflect.DeepEqual(again, tokens) {
+		t.Errorf("expected cached tokens unchanged, got %+v", again)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	lexer "github.com/WilkinsonK/panza-lexer"
+)
+
+/*
+Run the `contract` subcommand, writing a
+diagnostic for each token of the given files
+breaking the contract of the named contract
+file. Returns the exit status; 1 if there was
+any.
+*/
+func contract(lx *lexer.Lexer, args []string) int {
+	if len(args) < 2 {
+		fmt.Fprintf(os.Stderr, "usage: panza-lex [flags] contract contract-file file...\n")
+		return 2
+	}
+	c, err := lexer.LoadContract(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "panza-lex: %s: %s\n", args[0], err)
+		return 2
+	}
+
+	status := 0
+	for _, name := range args[1:] {
+		result, err := lx.TokenizeFileResult(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+			return 1
+		}
+		diags := lexer.SuppressDiagnostics(lexer.CheckContract(result.Tokens, c), result, lx.Suppress)
+		if len(diags) > 0 {
+			status = 1
+		}
+		for _, d := range diags {
+			lexer.FormatDiagnostic(os.Stdout, result, d.Token, d.Describe())
+		}
+	}
+	return status
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	lexer "github.com/WilkinsonK/panza-lexer"
+)
+
+/*
+Run the `corpus` subcommand: `run` compares the
+tokens of every file of the corpus against their
+expected dumps, `update` writes the dumps anew.
+Returns the exit status.
+*/
+func corpus(lx *lexer.Lexer, args []string) int {
+	if len(args) != 2 || (args[0] != "run" && args[0] != "update") {
+		fmt.Fprintf(os.Stderr, "usage: panza-lex [flags] corpus run|update dir\n")
+		return 2
+	}
+
+	if args[0] == "update" {
+		written, err := lx.UpdateCorpus(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+			return 1
+		}
+		fmt.Printf("%d dumps written\n", len(written))
+		return 0
+	}
+
+	report, err := lx.RunCorpus(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+		return 1
+	}
+	if err := report.Format(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+		return 1
+	}
+	if !report.Ok() {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	lexer "github.com/WilkinsonK/panza-lexer"
+)
+
+/* Load a grammar of its own from the named tokens file. */
+func loadGrammarFile(name string, flags []string) (*lexer.Grammar, error) {
+	g := lexer.NewGrammar(filepath.Base(name))
+	g.Flags = flags
+	if err := g.LoadTokensFile(name); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+/*
+Run the `grammar-diff` subcommand, writing what
+changed between the grammars of two tokens
+files and the version bump it calls for,
+checked against their `@version`s if both
+declare one. Returns the exit status; 1 if they
+differ or the version bump falls short.
+*/
+func grammarDiff(flags []string, args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "usage: panza-lex [flags] grammar-diff old.tokens new.tokens\n")
+		return 2
+	}
+	var grammars [2]*lexer.Grammar
+	for i, name := range args {
+		g, err := loadGrammarFile(name, flags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+			return 2
+		}
+		grammars[i] = g
+	}
+
+	diff := lexer.DiffGrammars(grammars[0], grammars[1])
+	if err := diff.Format(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+		return 1
+	}
+	fmt.Printf("change: %s\n", diff.Compatibility())
+	if old, new := grammars[0].Version, grammars[1].Version; old != "" && new != "" {
+		if err := lexer.CheckVersionBump(old, new, diff.Compatibility()); err != nil {
+			fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+			return 1
+		}
+	}
+	if diff.Empty() {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	lexer "github.com/WilkinsonK/panza-lexer"
+)
+
+// Rule of diagnostics about unbalanced brackets.
+const bracketsRule = "brackets"
+
+/*
+Load the lint settings from the named lint file,
+or else from the project's lint file in the
+current directory, if there is one.
+*/
+func loadLintConfig(name string) (lexer.LintConfig, error) {
+	if name != "" {
+		return lexer.LoadLintConfig(name)
+	}
+	lc, err := lexer.LoadLintConfig(lexer.LintFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return lexer.DefaultLintConfig(), nil
+	}
+	return lc, err
+}
+
+/*
+Every diagnostic about a file: tokens of the
+ERROR kind, unbalanced brackets and lint rules
+broken, in order of position. Diagnostics
+silenced on their line are left out.
+*/
+func lintFile(lx *lexer.Lexer, lc lexer.LintConfig, result lexer.TokenResult) []lexer.Diagnostic {
+	diags := []lexer.Diagnostic{}
+	for _, to := range result.Tokens {
+		if to.Kind != nil && to.Kind.Id == lexer.ErrorId {
+			msg := fmt.Sprintf("unknown input '%s'", to.Symbol)
+			diags = append(diags, lexer.Diagnostic{Token: to, Message: msg, Code: lexer.CodeUnknownInput})
+		}
+	}
+	pairs := lexer.BracketPairs()
+	if lx.Grammar != nil {
+		pairs = lx.Grammar.BracketPairs()
+	}
+	for _, be := range lexer.CheckBrackets(result.Tokens, pairs...).Errors {
+		msg := fmt.Sprintf("%s '%s'", be.Reason, be.Token.Symbol)
+		diags = append(diags, lexer.Diagnostic{Token: be.Token, Message: msg, Code: lexer.CodeUnbalancedBracket, Rule: bracketsRule})
+	}
+	diags = append(diags, lc.Lint(result.Tokens)...)
+	diags = lexer.SuppressDiagnostics(diags, result, lx.Suppress)
+
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Token, diags[j].Token
+		if a.LineNo != b.LineNo {
+			return a.LineNo < b.LineNo
+		}
+		return a.Position < b.Position
+	})
+	return diags
+}
+
+/*
+Run the `lint` subcommand, writing a diagnostic
+for each token of the given files breaking a
+lint rule, of the ERROR kind, or an unbalanced
+bracket. Returns the
+exit status; 1 if there was any.
+*/
+func lint(lx *lexer.Lexer, configName string, args []string) int {
+	fset := flag.NewFlagSet("lint", flag.ContinueOnError)
+	format := fset.String("format", "text", "output format: text, sarif")
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	if fset.NArg() == 0 || *format != "text" && *format != "sarif" {
+		fmt.Fprintf(os.Stderr, "usage: panza-lex [flags] lint [-format text|sarif] file...\n")
+		return 2
+	}
+	lc, err := loadLintConfig(configName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+		return 2
+	}
+
+	status := 0
+	files := []lexer.FileDiagnostics{}
+	for _, name := range fset.Args() {
+		result, err := lx.TokenizeFileResult(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+			return 1
+		}
+		diags := lintFile(lx, lc, result)
+		if len(diags) > 0 {
+			status = 1
+		}
+		if *format == "sarif" {
+			files = append(files, lexer.FileDiagnostics{Source: name, Diagnostics: diags})
+			continue
+		}
+		for _, d := range diags {
+			lexer.FormatDiagnostic(os.Stdout, result, d.Token, d.Describe())
+		}
+	}
+
+	if *format == "sarif" {
+		if err := lexer.WriteSARIF(os.Stdout, "panza-lex", files); err != nil {
+			fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+			return 1
+		}
+	}
+	return status
+}
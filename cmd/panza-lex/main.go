@@ -0,0 +1,495 @@
+/*
+Command panza-lex tokenizes Panza source files and
+prints the resulting tokens.
+
+Usage:
+
+	panza-lex [flags] file...
+
+A file may be given as a glob pattern, e.g.
+`src/*.pz`, to tokenize each file matching it;
+binary files matched this way are skipped with a
+notice.
+
+Each file is tokenized with the grammar
+registered for its extension or `#!` line,
+falling back on the default grammar. The default
+grammar is loaded from the tokens file given
+with `-tokens`, or else found per the lookup
+order of the lexer package: `$PANZA_TOKENS`, the
+current directory, the parent directory, then
+the user config directory.
+
+Each token is printed on its own line, colored
+per the selected theme, or shaped by a Go
+text/template or any registered formatter
+instead. Input matching no token kind falls
+back on a kind, or fails the command in strict
+mode. Files may instead be minified or
+re-printed, rather than their tokens written.
+
+Flags choose what becomes of comments, line
+breaks and whitespace, how input is read and
+checked, and what is reported alongside the
+tokens; `panza-lex -h` lists them all.
+
+With `-playground`, requests are read from
+standard input instead, each a JSON object per
+line of a grammar, in the tokens file format,
+and source to tokenize against it:
+
+	{"grammar": "PLUS +", "source": "a+b"}
+
+Each is answered with a JSON object per line of
+its tokens, or its error. Grammars are compiled
+once and kept for later requests.
+
+The `corpus` subcommand guards against
+regressions of the grammar. `corpus run dir`
+tokenizes every file under dir and compares its
+tokens against those expected of it, dumped in
+the file of the same name with the `.expected`
+extension; mismatches are summarized, failing the
+command. `corpus update dir` writes the dumps
+anew, to be reviewed and checked in.
+
+The `shrink` subcommand cuts input tripping up
+the lexer down to a minimal reproduction, written
+to standard output. `shrink kind=ERROR file`
+keeps what of the file still gives an ERROR
+token, with `-fallback ERROR`; `shrink error`
+what still fails to tokenize, with `-strict`;
+`shrink panic` what still panics.
+
+The `lint` subcommand checks files against the
+whitespace rules of the lexer package, writing a
+diagnostic per token breaking one, per token of
+the ERROR kind, given `-fallback ERROR`, and per
+unbalanced bracket, and fails if there are any.
+With `lint -format sarif`, diagnostics are
+written as a SARIF log for code scanning. Rules
+are configured per project in the lint file,
+`.panza-lint` in the current directory, or the
+file given with `-lint-config`.
+Rules registered with `lexer.RegisterLintRule`
+by packages built into the tool are run too.
+
+The `contract` subcommand checks files against
+the lexical invariants declared by a contract
+file, e.g. `forbid ERROR`, writing a diagnostic
+per token breaking one, and fails if there are
+any; see `lexer.ParseContract`.
+
+The `grammar-diff` subcommand compares the
+grammars of two tokens files, writing a line per
+kind added (+), removed (-), renamed or given a
+new signature (~), and whose ID changed (!); the
+last breaks tokens stored by ID, then the
+version bump the changes call for: patch, minor
+or major. It fails if the grammars differ, as
+`diff` does, and if both declare a `@version`
+not bumped enough for the changes.
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	lexer "github.com/WilkinsonK/panza-lexer"
+)
+
+var (
+	themeName    = flag.String("theme", "default", "color theme: "+themeNames())
+	templateText = flag.String("template", "", "Go text/template applied per token")
+	formatName   = flag.String("format", "text", "output format: "+strings.Join(lexer.FormatterNames(), ", "))
+	profile      = flag.Bool("profile", false, "print time spent per tokenizing stage to stderr")
+	kindTimings  = flag.Bool("kind-timings", false, "print a histogram of time spent matching per kind to stderr")
+	detectEnc    = flag.Bool("detect-encoding", false, "transcode UTF-16 and Latin-1 input to UTF-8")
+	verifyRuns   = flag.Int("verify", 0, "tokenize each file this many times concurrently, failing if results differ")
+	tokensPath   = flag.String("tokens", "", "tokens file of the default grammar; overrides $"+lexer.TokensEnv)
+	tokensMode   = flag.String("tokens-mode", "replace", "how -tokens combines with the tokens found otherwise: replace, extend, override")
+	grammarFlags = flag.String("flags", "", "comma separated flags enabling @when guarded tokens")
+	commentsName = flag.String("comments", "emit", "what becomes of comments: emit, attach, drop")
+	newlinesName = flag.String("newlines", "omit", "what becomes of line breaks: omit, emit, collapse")
+	columnsName  = flag.String("columns", "bytes", "unit columns of tokens are written in: bytes, runes, graphemes")
+	skipSpaces   = flag.String("skip-whitespace", "none", "comma separated classes of whitespace left out: space, tab, newline, cr; or all, none")
+	cacheDir     = flag.String("cache", "", "directory caching the tokens of files by their content, so unchanged files are not tokenized again")
+	nfc          = flag.Bool("nfc", false, "normalize identifiers to Unicode Normalization Form C")
+	keepTrivia   = flag.Bool("keep-trivia", false, "keep tokens the grammar's @trivia policies attach or drop")
+	strict       = flag.Bool("strict", false, "fail on input matching no token kind")
+	suppress     = flag.String("suppress", "", "marker silencing diagnostics on its line, followed by the codes silenced, e.g. lex-ignore")
+	fallbackKind = flag.String("fallback", "", "kind of input matching no token kind, e.g. ERROR; GENIDEN if empty")
+	diagnostics  = flag.Bool("diagnostics", false, "report input matching no token kind to stderr")
+	maxDiags     = flag.Int("max-diagnostics", 0, "most diagnostics reported per file with -diagnostics; 0 for the default, negative for no limit")
+	provenance   = flag.Bool("provenance", false, "write the lexer version, grammar and source checksums with tokens, for formats supporting it")
+	grammarRep   = flag.Bool("report", false, "summarize the kinds of the default grammar and exit")
+	maxInput     = flag.Int64("max-input", 0, "most bytes read per file; 0 for no limit")
+	maxSteps     = flag.Int("max-steps", 0, "most kinds tried matching at any one position before giving up; no limit if 0")
+	anonymize    = flag.Bool("anonymize", false, "replace identifiers, literals and comments with placeholders, consistently across files")
+	minify       = flag.Bool("minify", false, "write each file's text without comments and with the least whitespace, instead of its tokens")
+	pretty       = flag.Bool("pretty", false, "write each file's text re-printed with spaced operators, a statement per line and indented blocks, instead of its tokens")
+	lintConfig   = flag.String("lint-config", "", "lint file configuring the lint subcommand; "+lexer.LintFileName+" in the current directory if present")
+	playgroundOn = flag.Bool("playground", false, "answer JSON requests of a grammar and source read from stdin, one per line")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: panza-lex [flags] file...\n       panza-lex [flags] -report\n       panza-lex [flags] -playground\n       panza-lex [flags] corpus run|update dir\n       panza-lex [flags] shrink panic|error|kind=NAME file\n       panza-lex [flags] lint [-format text|sarif] file...\n       panza-lex [flags] contract contract-file file...\n       panza-lex [flags] grammar-diff old.tokens new.tokens\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *playgroundOn {
+		comments, err := lexer.ParseCommentMode(*commentsName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+			os.Exit(2)
+		}
+		lx := &lexer.Lexer{Comments: comments, Strict: *strict, Suppress: *suppress, Fallback: *fallbackKind, MaxInput: *maxInput, MaxSteps: *maxSteps}
+		if err := playground(lx, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.NArg() == 0 && !*grammarRep {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if flag.Arg(0) == "grammar-diff" {
+		os.Exit(grammarDiff(splitFlags(*grammarFlags), flag.Args()[1:]))
+	}
+
+	if err := loadTokens(*tokensPath, *tokensMode, splitFlags(*grammarFlags)); err != nil {
+		fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+		os.Exit(2)
+	}
+	if *grammarRep {
+		if err := lexer.DefaultGrammar().Report().Format(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cmd := flag.Arg(0); cmd == "corpus" || cmd == "shrink" || cmd == "lint" || cmd == "contract" {
+		comments, err := lexer.ParseCommentMode(*commentsName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+			os.Exit(2)
+		}
+		lx := &lexer.Lexer{Comments: comments, KeepTrivia: *keepTrivia, Strict: *strict, Suppress: *suppress, Fallback: *fallbackKind, MaxInput: *maxInput, MaxSteps: *maxSteps}
+		if *cacheDir != "" {
+			lx.Cache = lexer.NewDiskCache(*cacheDir)
+		}
+		switch cmd {
+		case "corpus":
+			os.Exit(corpus(lx, flag.Args()[1:]))
+		case "lint":
+			os.Exit(lint(lx, *lintConfig, flag.Args()[1:]))
+		case "contract":
+			os.Exit(contract(lx, flag.Args()[1:]))
+		}
+		os.Exit(shrink(lx, flag.Args()[1:]))
+	}
+
+	formatter, err := newFormatter(*formatName, *themeName, *templateText)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+		os.Exit(2)
+	}
+
+	comments, err := lexer.ParseCommentMode(*commentsName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+		os.Exit(2)
+	}
+	newlines, err := lexer.ParseNewlineMode(*newlinesName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+		os.Exit(2)
+	}
+	skipWhitespace, err := lexer.ParseWhitespaceClass(*skipSpaces)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+		os.Exit(2)
+	}
+	columns, err := lexer.ParseColumnUnit(*columnsName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+		os.Exit(2)
+	}
+
+	names, err := expandArgs(flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+		os.Exit(2)
+	}
+
+	an := lexer.NewAnonymizer()
+	for _, name := range names {
+		g, err := lexer.ResolveGrammarFile(name.path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+			os.Exit(1)
+		}
+		lx := &lexer.Lexer{Grammar: g, Comments: comments, Newlines: newlines, SkipWhitespace: skipWhitespace, KeepTrivia: *keepTrivia, Strict: *strict, Suppress: *suppress, Fallback: *fallbackKind, MaxInput: *maxInput, MaxSteps: *maxSteps}
+		if *profile {
+			lx.Profile = &lexer.Profile{}
+		}
+		if *kindTimings {
+			lx.Timings = &lexer.KindTimings{}
+		}
+		if *nfc {
+			lx.Normalize = lexer.NFC
+		}
+		if *detectEnc {
+			lx.Decode = lexer.DetectEncoding
+		}
+		if *cacheDir != "" {
+			lx.Cache = lexer.NewDiskCache(*cacheDir)
+		}
+		if *diagnostics {
+			lx.Diagnostics = &lexer.Diagnostics{Max: *maxDiags}
+		}
+		if *verifyRuns > 0 {
+			if err := verify(lx, name.path, *verifyRuns); err != nil {
+				fmt.Fprintf(os.Stderr, "panza-lex: %s: %s\n", name.path, err)
+				os.Exit(1)
+			}
+		}
+		result, err := lx.TokenizeFileResult(name.path)
+		if errors.Is(err, lexer.ErrBinaryInput) && name.globbed {
+			fmt.Fprintf(os.Stderr, "panza-lex: skipping binary file %s\n", name.path)
+			continue
+		}
+		var unknown lexer.UnknownInputError
+		var gaveUp lexer.StepLimitError
+		if errors.As(err, &unknown) || errors.As(err, &gaveUp) {
+			// Already names the file.
+			fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "panza-lex: %s: %s\n", name.path, err)
+			os.Exit(1)
+		}
+		if *anonymize {
+			result.Tokens = an.Anonymize(result.Tokens)
+		}
+		if columns != lexer.ColumnBytes && !*minify && !*pretty {
+			result.Tokens = result.Tokens.InColumns(result, columns)
+		}
+		switch {
+		case *minify:
+			_, err = fmt.Fprintln(os.Stdout, lx.Minify(result.Tokens))
+		case *pretty:
+			_, err = fmt.Fprint(os.Stdout, lexer.DefaultPrettyConfig().Print(result.Tokens))
+		case *provenance:
+			err = lexer.FormatResult(os.Stdout, formatter, result)
+		default:
+			err = formatter.Format(os.Stdout, result.Tokens)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+			os.Exit(1)
+		}
+		if lx.Profile != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name.path, lx.Profile)
+		}
+		if lx.Timings != nil {
+			fmt.Fprintf(os.Stderr, "%s:\n", name.path)
+			lx.Timings.Format(os.Stderr)
+		}
+		if result.Diagnostics != nil {
+			report(result)
+		}
+	}
+}
+
+/*
+Write the diagnostics recorded tokenizing a file
+to standard error, each naming the file.
+*/
+func report(result lexer.TokenResult) {
+	result.Diagnostics.Format(os.Stderr, result)
+}
+
+/*
+Load the default grammar from the given tokens
+file, if any, combined per the mode with the
+tokens found at init. Otherwise the default
+grammar must have found a tokens file at init.
+The tokens found at init are loaded again if
+flags are given, so guarded tokens are enabled.
+*/
+func loadTokens(name string, modeName string, flags []string) error {
+	mode, err := lexer.ParseMergeMode(modeName)
+	if err != nil {
+		return err
+	}
+
+	lexer.DefaultGrammar().Flags = flags
+	if found := lexer.DefaultTokensFile(); len(flags) > 0 && found != "" {
+		if err := lexer.LoadTokensFile(found); err != nil {
+			return err
+		}
+	}
+
+	if name == "" {
+		_, err := lexer.FindTokensFile("")
+		return err
+	}
+	path, err := lexer.FindTokensFile(name)
+	if err != nil {
+		return err
+	}
+	return lexer.MergeTokensFile(path, mode)
+}
+
+/* Split a comma separated list of flags. */
+func splitFlags(list string) []string {
+	var flags []string
+	for _, flag := range strings.Split(list, ",") {
+		if flag = strings.TrimSpace(flag); flag != "" {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+/* Verify tokenizing a file gives the same tokens each run. */
+func verify(lx *lexer.Lexer, name string, runs int) error {
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return lx.VerifyDeterminism(src, runs)
+}
+
+/* A file to tokenize, and whether it matched a glob pattern. */
+type inputFile struct {
+	path    string
+	globbed bool
+}
+
+/*
+Expand any glob patterns among the arguments.
+Arguments without pattern characters are taken
+as is, so a missing file is still reported.
+*/
+func expandArgs(args []string) ([]inputFile, error) {
+	var files []inputFile
+	for _, arg := range args {
+		if !strings.ContainsAny(arg, "*?[") {
+			files = append(files, inputFile{arg, false})
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.IsDir() {
+				continue
+			}
+			files = append(files, inputFile{match, true})
+		}
+	}
+	return files, nil
+}
+
+/*
+Select the formatter for the given flags. Plain
+text output goes through the themed `printer`;
+a template always implies text output.
+*/
+func newFormatter(formatName string, themeName string, templateText string) (lexer.TokenFormatter, error) {
+	if formatName == "text" || templateText != "" {
+		return newPrinter(os.Stdout, themeName, templateText)
+	}
+	f, ok := lexer.Formatter(formatName)
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q, expected one of: %s", formatName, strings.Join(lexer.FormatterNames(), ", "))
+	}
+	return f, nil
+}
+
+/* Writes tokens out per a theme or template. */
+type printer struct {
+	w        io.Writer
+	theme    theme
+	template *template.Template
+}
+
+/*
+Initialize a new `printer`. Colors are disabled
+when writing to something other than a terminal,
+or when `NO_COLOR` is set.
+*/
+func newPrinter(w io.Writer, themeName string, templateText string) (*printer, error) {
+	th, ok := themes[themeName]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q, expected one of: %s", themeName, themeNames())
+	}
+	if !isTerminal(w) || os.Getenv("NO_COLOR") != "" {
+		th = themes["none"]
+	}
+
+	p := &printer{w: w, theme: th}
+	if templateText != "" {
+		tmpl, err := template.New("token").Parse(templateText)
+		if err != nil {
+			return nil, err
+		}
+		p.template = tmpl
+	}
+	return p, nil
+}
+
+/* Write a series of tokens. */
+func (p *printer) Format(w io.Writer, toks []lexer.TokenObject) error {
+	p.w = w
+	for _, tok := range toks {
+		if err := p.Print(tok); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* Write a single token. */
+func (p *printer) Print(tok lexer.TokenObject) error {
+	if p.template != nil {
+		if err := p.template.Execute(p.w, tok); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(p.w)
+		return err
+	}
+
+	color := p.theme.colorOf(tok.Kind)
+	_, err := fmt.Fprintf(p.w, "%d:%d\t%s%s%s\t'%s'\n", tok.LineNo, tok.Position, color, tok.Kind.Name, p.theme.reset(color), tok.Symbol)
+	return err
+}
+
+/* Determine if the given writer is a terminal. */
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the command's output")
+
+// Environment variable running the command in
+// place of the tests, so each case runs it in a
+// process of its own with its own flags.
+const runMainEnv = "PANZA_LEX_TEST_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// Run the command with the given arguments in
+// testdata, against its own tokens file.
+func runMain(t *testing.T, args ...string) (string, string, int) {
+	t.Helper()
+	dir, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), runMainEnv+"=1", "PANZA_TOKENS="+filepath.Join(dir, "lexer.tokens"))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err = cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return stdout.String(), stderr.String(), exit.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), 0
+}
+
+func TestCommand(t *testing.T) {
+	cases := []struct {
+		name   string
+		args   []string
+		exit   int
+		stderr string // Expected within standard error.
+	}{
+		{"usage", nil, 2, "usage: panza-lex [flags] file..."},
+		{"unknown-flag", []string{"-no-such-flag", "sum.pz"}, 2, "flag provided but not defined"},
+		{"bad-comments", []string{"-comments", "keep", "sum.pz"}, 2, "panza-lex: "},
+		{"missing-file", []string{"missing.pz"}, 1, "missing.pz"},
+		{"tokens", []string{"sum.pz"}, 0, ""},
+		{"tokens-json", []string{"-format", "json", "sum.pz"}, 0, ""},
+		{"corpus-run", []string{"corpus", "run", "corpus"}, 0, ""},
+		{"corpus-stale", []string{"corpus", "run", "stale"}, 1, ""},
+		{"corpus-usage", []string{"corpus", "check", "corpus"}, 2, "usage: panza-lex [flags] corpus run|update dir"},
+		{"shrink", []string{"shrink", "kind=PLUS", "sum.pz"}, 0, "shrunk 18 bytes to 1"},
+		{"shrink-unsatisfied", []string{"shrink", "error", "sum.pz"}, 1, "input does not satisfy error"},
+		{"shrink-predicate", []string{"shrink", "crash", "sum.pz"}, 2, "unknown predicate"},
+		{"lint", []string{"lint", "lint.pz"}, 1, ""},
+		{"lint-sarif", []string{"lint", "-format", "sarif", "lint.pz"}, 1, ""},
+		{"lint-clean", []string{"lint", "-format", "sarif", "sum.pz"}, 0, ""},
+		{"lint-usage", []string{"lint", "-format", "xml", "lint.pz"}, 2, "usage: panza-lex [flags] lint"},
+		{"grammar-diff", []string{"grammar-diff", "old.tokens", "new.tokens"}, 1, "needs a major version bump"},
+		{"grammar-diff-same", []string{"grammar-diff", "old.tokens", "old.tokens"}, 0, ""},
+		{"grammar-diff-usage", []string{"grammar-diff", "old.tokens"}, 2, "usage: panza-lex [flags] grammar-diff"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout, stderr, exit := runMain(t, tc.args...)
+			if exit != tc.exit {
+				t.Errorf("expected exit status %d, got %d; stderr:\n%s", tc.exit, exit, stderr)
+			}
+			if !strings.Contains(stderr, tc.stderr) {
+				t.Errorf("expected %q within stderr, got:\n%s", tc.stderr, stderr)
+			}
+
+			golden := filepath.Join("testdata", "golden", tc.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(stdout), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stdout != string(expected) {
+				t.Errorf("expected stdout\n%s\ngot\n%s", expected, stdout)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+
+	lexer "github.com/WilkinsonK/panza-lexer"
+)
+
+// Most a grammar given with a request may hold.
+var playgroundLimits = lexer.GrammarLimits{
+	MaxDefinition: 1 << 20,
+	MaxKinds:      4096,
+	MaxSignature:  256,
+	MaxCompile:    time.Second,
+}
+
+/* A grammar and source to tokenize against it. */
+type playgroundRequest struct {
+	Grammar string `json:"grammar"` // In the tokens file format.
+	Source  string `json:"source"`
+	Name    string `json:"name,omitempty"`
+}
+
+/* The tokens of a request, or why there are none. */
+type playgroundResponse struct {
+	Provenance *lexer.Provenance  `json:"provenance,omitempty"`
+	Tokens     lexer.TokenObjects `json:"tokens"`
+	Error      string             `json:"error,omitempty"`
+}
+
+/*
+Answer each request, a JSON object per line read
+from `r`, with a JSON object per line written to
+`w`. Grammars are compiled once and kept for
+later requests; those exceeding the limits of
+playground grammars are refused. A request
+failing is answered with its error rather than
+ending the others.
+*/
+func playground(lx *lexer.Lexer, r io.Reader, w io.Writer) error {
+	cache := lexer.NewGrammarCache(0)
+	cache.Limits = playgroundLimits
+	dec := json.NewDecoder(bufio.NewReader(r))
+	enc := json.NewEncoder(w)
+
+	for {
+		var req playgroundRequest
+		if err := dec.Decode(&req); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		resp := playgroundResponse{Tokens: lexer.TokenObjects{}}
+		result, err := cache.TokenizeSource(lx, req.Grammar, req.Name, req.Source)
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Provenance = &result.Provenance
+			resp.Tokens = result.Tokens
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+}
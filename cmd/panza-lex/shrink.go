@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	lexer "github.com/WilkinsonK/panza-lexer"
+)
+
+/*
+Run the `shrink` subcommand, writing the input of
+the file shrunk per the predicate to standard
+output. The predicate is `panic`, `error` or
+`kind=NAME`. Returns the exit status.
+*/
+func shrink(lx *lexer.Lexer, args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "usage: panza-lex [flags] shrink panic|error|kind=NAME file\n")
+		return 2
+	}
+
+	var fails lexer.ShrinkPredicate
+	switch pred := args[0]; {
+	case pred == "panic":
+		fails = lx.Panics()
+	case pred == "error":
+		fails = lx.Fails()
+	case strings.HasPrefix(pred, "kind="):
+		fails = lx.ProducesKind(strings.TrimPrefix(pred, "kind="))
+	default:
+		fmt.Fprintf(os.Stderr, "panza-lex: unknown predicate %q, expected panic, error or kind=NAME\n", pred)
+		return 2
+	}
+
+	input, err := os.ReadFile(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "panza-lex: %s\n", err)
+		return 1
+	}
+	shrunk, ok := lexer.Shrink(input, fails)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "panza-lex: %s: input does not satisfy %s\n", args[1], args[0])
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "panza-lex: shrunk %d bytes to %d\n", len(input), len(shrunk))
+	os.Stdout.Write(shrunk)
+	return 0
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	lexer "github.com/WilkinsonK/panza-lexer"
+)
+
+/* ANSI escape sequences used to color token kinds. */
+type theme struct {
+	Whitespace string
+	Generic    string
+	Kind       string
+}
+
+/* Terminates a colored sequence, if one was started. */
+func (th theme) reset(color string) string {
+	if color == "" {
+		return ""
+	}
+	return "\x1b[0m"
+}
+
+/* Pick the color for the given kind. */
+func (th theme) colorOf(kind *lexer.TokenKind) string {
+	switch kind.Name {
+	case "WHTSPACE", "NEWLINE", "CRETURN", "TABLINE":
+		return th.Whitespace
+	case "GENIDEN", "GENTYPE", "GENOBJ":
+		return th.Generic
+	}
+	return th.Kind
+}
+
+// Available themes, selected with `-theme`.
+var themes = map[string]theme{
+	"default": {Whitespace: "\x1b[90m", Generic: "\x1b[36m", Kind: "\x1b[33m"},
+	"light":   {Whitespace: "\x1b[37m", Generic: "\x1b[34m", Kind: "\x1b[35m"},
+	"none":    {},
+}
+
+/* List theme names for usage messages. */
+func themeNames() string {
+	names := []string{}
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,94 @@
+package lexer
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* --- COMMENTS ---
+Grammars may declare line comment markers, with
+the `@comment` directive or `SetLineComments`.
+From a marker on, the rest of the line is a
+single token of the built in `COMMENT` kind.
+
+What becomes of comments is up to each lexer, as
+formatters, parsers and minifiers each want
+something different:
+
+emit: Comments are tokens like any other.
+attach: Comments are attached to the `Comments`
+of the token before them, skipping whitespace. A
+comment with no token before it is emitted.
+drop: Comments are left out. */
+
+/* What a lexer does with comments. */
+type CommentMode uint8
+
+const (
+	CommentEmit CommentMode = iota
+	CommentAttach
+	CommentDrop
+)
+
+var commentModeNames = []string{"emit", "attach", "drop"}
+
+func (cm CommentMode) String() string {
+	if int(cm) < len(commentModeNames) {
+		return commentModeNames[cm]
+	}
+	return fmt.Sprintf("CommentMode(%d)", uint8(cm))
+}
+
+/* Look up a comment mode by its name, e.g. `drop`. */
+func ParseCommentMode(name string) (CommentMode, error) {
+	for i, known := range commentModeNames {
+		if name == known {
+			return CommentMode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown comment mode %q, expected emit, attach or drop", name)
+}
+
+/*
+Declare the markers starting line comments, as
+with the `@comment` directive. No markers turns
+comment lexing off.
+*/
+func (g *Grammar) SetLineComments(markers ...string) error {
+	for _, marker := range markers {
+		if marker == "" {
+			return fmt.Errorf("empty comment marker")
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.comments = append([]string(nil), markers...)
+	return nil
+}
+
+/* Determine if a line comment starts `line`. */
+func (g *Grammar) isComment(line string) bool {
+	for _, marker := range g.comments {
+		if strings.HasPrefix(line, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Append a comment to `tokens` per the lexer's
+comment mode, then per the grammar's trivia
+policy if emitted.
+*/
+func (lx *Lexer) retainComment(g *Grammar, tokens TokenObjects, comment TokenObject) TokenObjects {
+	switch lx.Comments {
+	case CommentDrop:
+		return tokens
+	case CommentAttach:
+		return attachTrivia(tokens, comment)
+	}
+	return lx.retainTrivia(g, tokens, comment)
+}
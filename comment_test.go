@@ -0,0 +1,69 @@
+package lexer_test
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestCommentModes(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("SEMI", ";", "")
+	if err := g.SetLineComments("//"); err != nil {
+		t.Fatal(err)
+	}
+	src := "// header\na; // trailing\n"
+
+	emitted, err := (&lexer.Lexer{Grammar: g}).TokenizeReader(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := symbolsOf(emitted); len(got) != 5 || got[0] != "// header" || got[4] != "// trailing" {
+		t.Errorf("expected comments emitted, got %q", got)
+	}
+
+	attached, err := (&lexer.Lexer{Grammar: g, Comments: lexer.CommentAttach}).TokenizeReader(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The header has no token before it.
+	if got := symbolsOf(attached); len(got) != 4 || got[0] != "// header" {
+		t.Fatalf("expected the header emitted alone, got %q", got)
+	}
+	if semi := attached[2]; len(semi.Comments) != 1 || string(semi.Comments[0].Symbol) != "// trailing" {
+		t.Errorf("expected the trailing comment attached to ';', got %v", semi.Comments)
+	}
+
+	dropped, err := (&lexer.Lexer{Grammar: g, Comments: lexer.CommentDrop}).TokenizeReader(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := symbolsOf(dropped); len(got) != 3 {
+		t.Errorf("expected comments dropped, got %q", got)
+	}
+}
+
+func TestCommentDirective(t *testing.T) {
+	fsys := fstest.MapFS{
+		"comment.tokens": {Data: []byte("@comment -- ;;\n")},
+		"bad.tokens":     {Data: []byte("@comment\n")},
+	}
+
+	g := lexer.NewGrammar("x")
+	if err := g.LoadTokensFS(fsys, "comment.tokens"); err != nil {
+		t.Fatal(err)
+	}
+	tokens := g.TokenizeLine("a ;; b -- c", 1)
+	if len(tokens) != 3 || tokens[2].Kind.Name != "COMMENT" || string(tokens[2].Symbol) != ";; b -- c" {
+		t.Errorf("expected a comment to the end of the line, got %v", tokens)
+	}
+	if err := g.LoadTokensFS(fsys, "bad.tokens"); err == nil {
+		t.Error("expected an error for @comment without markers")
+	}
+
+	if _, err := lexer.ParseCommentMode("keep"); err == nil {
+		t.Error("expected an error for an unknown comment mode")
+	}
+}
@@ -0,0 +1,163 @@
+package lexer
+
+import (
+	"fmt"
+	"sort"
+)
+
+/* --- COMPLETION CONTEXT ---
+Editors offer completions per what the cursor is
+in or after: names mid-identifier, operands
+after an operator, nothing inside a comment.
+Most of that is known from the tokens around the
+cursor alone, without a parser.
+
+A cursor sits between two bytes of its line; at
+column 3, it is before the third byte. It is
+inside a token starting before it and ending
+after it, and after a token ending right at
+it. */
+
+/* What the cursor of a completion is in or after. */
+type CompletionKind uint8
+
+const (
+	CompleteStart      CompletionKind = iota // Nothing before the cursor on its line.
+	CompleteIdentifier                       // In or right after a generic identifier.
+	CompleteKeyword                          // In or right after a keyword.
+	CompleteOperator                         // Right after an operator or punctuation.
+	CompleteLiteral                          // Right after a literal, such as a number.
+	CompleteSpace                            // After whitespace.
+	CompleteString                           // Inside a string literal.
+	CompleteComment                          // Inside a comment.
+)
+
+var completionKindNames = []string{"start", "identifier", "keyword", "operator", "literal", "space", "string", "comment"}
+
+func (ck CompletionKind) String() string {
+	if int(ck) < len(completionKindNames) {
+		return completionKindNames[ck]
+	}
+	return fmt.Sprintf("CompletionKind(%d)", uint8(ck))
+}
+
+/* The context of a cursor, for deciding on completions. */
+type Completion struct {
+	Kind CompletionKind
+
+	// Token the cursor is in or right after; zero if none.
+	Token TokenObject
+
+	// Of the token, the part before the cursor; what has been
+	// typed of an identifier or keyword, say.
+	Prefix string
+
+	// Last token before `Token` that is not whitespace, on any
+	// line; zero if none.
+	Previous TokenObject
+}
+
+/* Determine if a token is of a string literal. */
+func isStringToken(to TokenObject) bool {
+	if _, ok := to.Value.(string); ok {
+		return true
+	}
+	return len(to.Symbol) > 0 && (to.Symbol[0] == '"' || to.Symbol[0] == '`')
+}
+
+/* Determine if a string token is missing its closing quote. */
+func isUnterminated(to TokenObject) bool {
+	n := len(to.Symbol)
+	return n < 2 || to.Symbol[n-1] != to.Symbol[0]
+}
+
+/* Classify a token the cursor is right after. */
+func completionKindOf(to TokenObject) CompletionKind {
+	switch {
+	case to.Kind.Id == CommentId:
+		return CompleteComment
+	case to.IsTrivia():
+		return CompleteSpace
+	case isStringToken(to):
+		if isUnterminated(to) {
+			return CompleteString
+		}
+		return CompleteLiteral
+	case to.Value != nil:
+		return CompleteLiteral
+	case to.Kind.Id == GenIdenId || to.Kind.Id == GenTypeId || to.Kind.Id == GenObjId:
+		return CompleteIdentifier
+	case isKeyword(string(to.Kind.Signature)):
+		return CompleteKeyword
+	}
+	return CompleteOperator
+}
+
+/*
+Report what the cursor at the given line and
+column is in or after, per the tokens around it.
+The tokens are expected in order, as the
+tokenizer produces them.
+*/
+func CompletionContext(tokens TokenObjects, at Position) Completion {
+	line, col := tokenLineNo(at.Line), tokenPosition(at.Column)
+
+	// First token at or after the cursor.
+	i := sort.Search(len(tokens), func(i int) bool {
+		to := tokens[i]
+		return to.LineNo > line || to.LineNo == line && to.Position >= col
+	})
+
+	c := Completion{Kind: CompleteStart}
+	if i == 0 || tokens[i-1].LineNo != line || tokens[i-1].Kind == nil {
+		c.Previous = previousSignificant(tokens, i)
+		return c
+	}
+	c.Token = tokens[i-1]
+	c.Previous = previousSignificant(tokens, i-1)
+
+	offset := int(col - c.Token.Position)
+	if offset > len(c.Token.Symbol) {
+		// Past the end of the line's last token.
+		c.Kind = CompleteSpace
+		return c
+	}
+	c.Prefix = string(c.Token.Symbol[:offset])
+	c.Kind = completionKindOf(c.Token)
+	if c.Kind == CompleteLiteral && isStringToken(c.Token) && offset < len(c.Token.Symbol) {
+		c.Kind = CompleteString
+	}
+	return c
+}
+
+/* Last token before index `i` that is not whitespace. */
+func previousSignificant(tokens TokenObjects, i int) TokenObject {
+	c := tokens.CursorAt(i).Prev().SkipTriviaBack()
+	if !c.Valid() {
+		return TokenObject{}
+	}
+	return c.Token()
+}
+
+/*
+Report what the cursor at the given byte offset
+of the indexed input is in or after. The text of
+each line must have been kept by the index; an
+offset within a line terminator is at the end of
+its line.
+*/
+func (li LineIndex) CompletionContext(offset int) Completion {
+	at := Position{Offset: offset, Line: 1, Column: 1}
+	for n, line := range li.input {
+		end := line.Offset + len(line.Text) + len(line.Terminator)
+		if offset < end || n == len(li.input)-1 {
+			col := offset - line.Offset
+			if col > len(line.Text) {
+				col = len(line.Text)
+			}
+			at.Line, at.Column = n+1, col+1
+			break
+		}
+	}
+	return CompletionContext(li.tokens, at)
+}
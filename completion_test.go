@@ -0,0 +1,78 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestCompletionContext(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("LET", "let", "")
+	g.AddKind("ASSIGN", "=", "")
+	g.AddKind("STRING", "\"", "")
+	if err := g.SetIdentClasses("letter"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetLineComments("//"); err != nil {
+		t.Fatal(err)
+	}
+	tokens := g.TokenizeLines([]string{"", "let name = other // note"})
+
+	cases := []struct {
+		column   int
+		kind     lexer.CompletionKind
+		prefix   string
+		previous string
+	}{
+		{1, lexer.CompleteStart, "", ""},
+		{3, lexer.CompleteKeyword, "le", ""},
+		{4, lexer.CompleteKeyword, "let", ""},
+		{5, lexer.CompleteSpace, " ", "let"},
+		{7, lexer.CompleteIdentifier, "na", "let"},
+		{11, lexer.CompleteOperator, "=", "name"},
+		{12, lexer.CompleteSpace, " ", "="},
+		{15, lexer.CompleteIdentifier, "oth", "="},
+		{21, lexer.CompleteComment, "// ", "other"},
+		{40, lexer.CompleteSpace, "", "other"},
+	}
+	for _, c := range cases {
+		got := lexer.CompletionContext(tokens, lexer.Position{Line: 1, Column: c.column})
+		if got.Kind != c.kind || got.Prefix != c.prefix || got.Previous.Symbol.String() != c.previous {
+			t.Errorf("column %d: expected %s %q after %q, got %s %q after %q", c.column, c.kind, c.prefix, c.previous, got.Kind, got.Prefix, got.Previous.Symbol)
+		}
+	}
+}
+
+func TestCompletionContextStrings(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	str, _ := g.LookupKind("GENIDEN")
+	tokens := lexer.TokenObjects{
+		{Kind: &str, LineNo: 1, Position: 1, Symbol: []byte(`"ab"`), Value: "ab"},
+		{Kind: &str, LineNo: 2, Position: 1, Symbol: []byte(`"ab`)},
+	}
+	cases := []struct {
+		at   lexer.Position
+		kind lexer.CompletionKind
+	}{
+		{lexer.Position{Line: 1, Column: 3}, lexer.CompleteString},
+		{lexer.Position{Line: 1, Column: 5}, lexer.CompleteLiteral},
+		{lexer.Position{Line: 2, Column: 4}, lexer.CompleteString},
+	}
+	for _, c := range cases {
+		if got := lexer.CompletionContext(tokens, c.at); got.Kind != c.kind {
+			t.Errorf("%s: expected %s, got %s", c.at, c.kind, got.Kind)
+		}
+	}
+
+	index, err := lexer.NewGrammar("x").TokenizeText("a\r\nbc d\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := index.CompletionContext(5); got.Kind != lexer.CompleteIdentifier || got.Prefix != "bc" {
+		t.Errorf("expected to be after bc, got %s %q", got.Kind, got.Prefix)
+	}
+	if got := index.CompletionContext(2); got.Kind != lexer.CompleteIdentifier || got.Prefix != "a" {
+		t.Errorf("expected the terminator to be at the end of its line, got %s %q", got.Kind, got.Prefix)
+	}
+}
@@ -0,0 +1,76 @@
+package lexer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+// Operator families, each signature a prefix of
+// the next.
+var operatorKinds = [][2]string{
+	{"ASSIGN", "="}, {"EQ", "=="}, {"SEQ", "==="},
+	{"LT", "<"}, {"SHL", "<<"}, {"SHLEQ", "<<="}, {"LE", "<="},
+	{"DOT", "."}, {"RANGE", ".."}, {"ELLIPSIS", "..."},
+	{"MINUS", "-"}, {"ARROW", "->"}, {"GT", ">"},
+}
+
+/* Render tokens as `KIND:symbol`, space separated. */
+func renderKinds(tokens []lexer.TokenObject) string {
+	parts := []string{}
+	for _, to := range tokens {
+		parts = append(parts, string(to.Kind.Name)+":"+string(to.Symbol))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestOperatorConformance(t *testing.T) {
+	g := lexer.NewGrammar("operators")
+	for _, kind := range operatorKinds {
+		g.AddKind(kind[0], kind[1], "")
+	}
+
+	cases := []struct {
+		line string
+		want string
+	}{
+		{"a<<=b", "GENIDEN:a SHLEQ:<<= GENIDEN:b"},
+		{"a<<b", "GENIDEN:a SHL:<< GENIDEN:b"},
+		{"a<=b", "GENIDEN:a LE:<= GENIDEN:b"},
+		{"a<b", "GENIDEN:a LT:< GENIDEN:b"},
+		{"a<<<=b", "GENIDEN:a SHL:<< LE:<= GENIDEN:b"},
+		{"a===b", "GENIDEN:a SEQ:=== GENIDEN:b"},
+		{"a==b", "GENIDEN:a EQ:== GENIDEN:b"},
+		{"a=b", "GENIDEN:a ASSIGN:= GENIDEN:b"},
+		{"a====b", "GENIDEN:a SEQ:=== ASSIGN:= GENIDEN:b"},
+		{"x...y", "GENIDEN:x ELLIPSIS:... GENIDEN:y"},
+		{"x..y", "GENIDEN:x RANGE:.. GENIDEN:y"},
+		{"x.y", "GENIDEN:x DOT:. GENIDEN:y"},
+		{"x....y", "GENIDEN:x ELLIPSIS:... DOT:. GENIDEN:y"},
+		{"1..2", "GENIDEN:1 RANGE:.. GENIDEN:2"},
+		{"a->b", "GENIDEN:a ARROW:-> GENIDEN:b"},
+		{"a-->b", "GENIDEN:a MINUS:- ARROW:-> GENIDEN:b"},
+		{"a- >b", "GENIDEN:a MINUS:- WHTSPACE:  GT:> GENIDEN:b"},
+		// Operators ending the line.
+		{"a<<", "GENIDEN:a SHL:<<"},
+		{"x..", "GENIDEN:x RANGE:.."},
+		{"=", "ASSIGN:="},
+	}
+	for _, c := range cases {
+		if got := renderKinds(g.TokenizeLine(c.line, 1)); got != c.want {
+			t.Errorf("%q: expected %s, got %s", c.line, c.want, got)
+		}
+	}
+}
+
+func TestPartialSignatureAtEndOfLine(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("INC", "++", "")
+
+	// '+' only begins a signature; it must not
+	// be matched as one.
+	if got := renderKinds(g.TokenizeLine("a ++ +", 1)); got != "GENIDEN:a WHTSPACE:  INC:++ WHTSPACE:  GENIDEN:+" {
+		t.Errorf("unexpected tokens %s", got)
+	}
+}
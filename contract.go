@@ -0,0 +1,165 @@
+package lexer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/* --- CONTRACTS ---
+A contract declares lexical invariants a token
+stream must hold, for checking generated sources
+in CI. A contract file holds a statement per
+line:
+
+forbid KIND: No tokens of the kind, e.g.
+`forbid ERROR`.
+starts KIND TEXT: Symbols of the kind start
+with the text, e.g. `starts STRING '"'`.
+ends KIND TEXT: Symbols of the kind end with the
+text.
+not-adjacent KIND KIND: Tokens of the kinds are
+never next to one another, in either order,
+without whitespace between them.
+
+Text may be quoted as a Go string or rune
+literal, for quotes and spaces. Lines starting
+with `#` are comments. Kinds are named as the
+grammar names them; kinds the grammar lacks
+never match. */
+
+/* A single invariant of a contract. */
+type ContractRule struct {
+	Statement string // As written, e.g. `forbid ERROR`.
+	Check     string // `forbid`, `starts`, `ends` or `not-adjacent`.
+	Kind      string
+	Other     string // Second kind of `not-adjacent`.
+	Text      string // Of `starts` and `ends`, unquoted.
+}
+
+/* Lexical invariants of a token stream. */
+type Contract struct {
+	Rules []ContractRule
+}
+
+/* Read a contract, a statement per line. */
+func ParseContract(r io.Reader) (Contract, error) {
+	var contract Contract
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseContractRule(line)
+		if err != nil {
+			return contract, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		contract.Rules = append(contract.Rules, rule)
+	}
+	return contract, scanner.Err()
+}
+
+/* Read a contract from the named contract file. */
+func LoadContract(name string) (Contract, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return Contract{}, err
+	}
+	defer file.Close()
+
+	return ParseContract(file)
+}
+
+/* Parse a single statement. */
+func parseContractRule(line string) (ContractRule, error) {
+	rule := ContractRule{Statement: line}
+	check, args, _ := strings.Cut(line, " ")
+	rule.Check, args = check, strings.TrimSpace(args)
+	kind, rest, _ := strings.Cut(args, " ")
+	rule.Kind, rest = kind, strings.TrimSpace(rest)
+	if rule.Kind == "" {
+		return rule, fmt.Errorf("expected a kind for %s", check)
+	}
+
+	switch check {
+	case "forbid":
+		if rest != "" {
+			return rule, fmt.Errorf("unexpected %q after forbid %s", rest, kind)
+		}
+	case "starts", "ends":
+		text, err := unquoteContractText(rest)
+		if err != nil || text == "" {
+			return rule, fmt.Errorf("expected text for %s %s, got %q", check, kind, rest)
+		}
+		rule.Text = text
+	case "not-adjacent":
+		if rest == "" || strings.ContainsAny(rest, " \t") {
+			return rule, fmt.Errorf("expected a second kind for not-adjacent %s, got %q", kind, rest)
+		}
+		rule.Other = rest
+	default:
+		return rule, fmt.Errorf("unknown statement %q", check)
+	}
+	return rule, nil
+}
+
+/* Unquote text given as a Go string or rune literal; other text is taken as is. */
+func unquoteContractText(text string) (string, error) {
+	if text == "" || !strings.ContainsRune("\"'`", rune(text[0])) {
+		return text, nil
+	}
+	return strconv.Unquote(text)
+}
+
+/*
+Check the tokens against every rule of the
+contract. Diagnostics are in order of the
+tokens, then of the rules, each naming the
+statement broken as its rule.
+*/
+func CheckContract(tokens TokenObjects, contract Contract) []Diagnostic {
+	diags := []Diagnostic{}
+	for i, to := range tokens {
+		if to.Kind == nil {
+			continue
+		}
+		kind := string(to.Kind.Name)
+		for _, rule := range contract.Rules {
+			if d, broken := rule.check(tokens, i, kind); broken {
+				diags = append(diags, d)
+			}
+		}
+	}
+	return diags
+}
+
+/* Check the rule against the token at `i`, of the named kind. */
+func (cr ContractRule) check(tokens TokenObjects, i int, kind string) (Diagnostic, bool) {
+	to := tokens[i]
+	d := Diagnostic{Token: to, Rule: cr.Statement}
+	switch cr.Check {
+	case "forbid":
+		d.Code, d.Message = CodeContractForbid, fmt.Sprintf("%s token '%s' is forbidden", kind, to.Symbol)
+		return d, kind == cr.Kind
+	case "starts":
+		d.Code, d.Message = CodeContractStarts, fmt.Sprintf("%s token '%s' does not start with %q", kind, to.Symbol, cr.Text)
+		return d, kind == cr.Kind && !strings.HasPrefix(string(to.Symbol), cr.Text)
+	case "ends":
+		d.Code, d.Message = CodeContractEnds, fmt.Sprintf("%s token '%s' does not end with %q", kind, to.Symbol, cr.Text)
+		return d, kind == cr.Kind && !strings.HasSuffix(string(to.Symbol), cr.Text)
+	case "not-adjacent":
+		if i+1 >= len(tokens) || tokens[i+1].Kind == nil || tokens[i+1].LineNo != to.LineNo {
+			return d, false
+		}
+		next := string(tokens[i+1].Kind.Name)
+		d.Code, d.Message = CodeContractAdjacent, fmt.Sprintf("%s '%s' is right before %s '%s'", kind, to.Symbol, next, tokens[i+1].Symbol)
+		return d, kind == cr.Kind && next == cr.Other || kind == cr.Other && next == cr.Kind
+	}
+	return d, false
+}
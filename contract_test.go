@@ -0,0 +1,43 @@
+package lexer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestCheckContract(t *testing.T) {
+	contract, err := lexer.ParseContract(strings.NewReader(`
+# invariants of generated source
+forbid ASSIGN
+starts GENIDEN 'f'
+ends GENIDEN "1"
+not-adjacent GENIDEN PLUS
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contract.Rules) != 4 || contract.Rules[1].Text != "f" {
+		t.Fatalf("expected 4 rules, the second unquoted, got %+v", contract.Rules)
+	}
+
+	index, err := lexer.TokenizeText("x = foo1 + bar+foo1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, d := range lexer.CheckContract(index.Tokens(), contract) {
+		found = append(found, d.Code+":"+string(d.Token.Symbol))
+	}
+	expected := "CONTRACT002:x CONTRACT003:x CONTRACT001:= CONTRACT002:bar CONTRACT003:bar CONTRACT004:bar CONTRACT004:+"
+	if strings.Join(found, " ") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(found, " "))
+	}
+
+	for _, text := range []string{"forbid", "forbid ERROR NUMBER", "starts STRING", "not-adjacent IDENT", "allow ERROR"} {
+		if _, err := lexer.ParseContract(strings.NewReader("\n" + text + "\n")); err == nil || !strings.HasPrefix(err.Error(), "line 2: ") {
+			t.Errorf("expected an error on line 2 for %q, got %v", text, err)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package lexer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+/* --- CORPUS ---
+A corpus is a directory of source files, each
+checked in alongside the dump of the tokens it
+is expected to give: the file's name with the
+`.expected` extension, in the text format. Runs
+of the corpus tokenize every file and compare
+against its dump, so changes to a grammar that
+alter how existing sources are tokenized are
+caught. Updating the corpus writes the dumps of
+the current grammar, to be reviewed and checked
+in. */
+
+// Extension of the expected token dump of a corpus file.
+const CorpusExpectedExt = ".expected"
+
+/* Where the tokens of a corpus file differ from those expected. */
+type CorpusMismatch struct {
+	Path string // Of the source file.
+	Line int    // First line of the dump that differs, from 1.
+	Want string // Expected line of the dump; empty past its end.
+	Got  string // Line of the dump given; empty past its end.
+}
+
+func (cm CorpusMismatch) String() string {
+	return fmt.Sprintf("%s: line %d of dump: expected %q, got %q", cm.Path, cm.Line, cm.Want, cm.Got)
+}
+
+/* Outcome of running a corpus. */
+type CorpusReport struct {
+	Files      int              // Source files tokenized.
+	Passed     int              // Files giving the tokens expected.
+	Missing    []string         // Files without an expected dump.
+	Mismatches []CorpusMismatch // Files giving other tokens than expected.
+	Errors     []FileError      // Files failing to tokenize.
+}
+
+/* Determine if every file gave the tokens expected. */
+func (cr CorpusReport) Ok() bool {
+	return len(cr.Missing) == 0 && len(cr.Mismatches) == 0 && len(cr.Errors) == 0
+}
+
+/* Write a summary of the report, listing each failing file. */
+func (cr CorpusReport) Format(w io.Writer) error {
+	for _, path := range cr.Missing {
+		if _, err := fmt.Fprintf(w, "MISSING %s\n", path); err != nil {
+			return err
+		}
+	}
+	for _, cm := range cr.Mismatches {
+		if _, err := fmt.Fprintf(w, "FAIL    %s\n", cm); err != nil {
+			return err
+		}
+	}
+	for _, fe := range cr.Errors {
+		if _, err := fmt.Fprintf(w, "ERROR   %s\n", fe); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d files: %d passed, %d failed, %d missing, %d errors\n",
+		cr.Files, cr.Passed, len(cr.Mismatches), len(cr.Missing), len(cr.Errors))
+	return err
+}
+
+/* Find the source files of the corpus under `dir`, in order. */
+func corpusFiles(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() && filepath.Ext(path) != CorpusExpectedExt {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	sort.Strings(paths)
+	return paths, err
+}
+
+/* Dump tokens in the text format. */
+func corpusDump(tokens TokenObjects) []byte {
+	var buf bytes.Buffer
+	TextFormatter{}.Format(&buf, tokens)
+	return buf.Bytes()
+}
+
+/*
+Find the first line at which the dump `got`
+differs from `want`, or false if they are equal.
+*/
+func diffDump(path string, want, got []byte) (CorpusMismatch, bool) {
+	if bytes.Equal(want, got) {
+		return CorpusMismatch{}, false
+	}
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	for i := 0; ; i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g || i >= len(wantLines) || i >= len(gotLines) {
+			return CorpusMismatch{Path: path, Line: i + 1, Want: w, Got: g}, true
+		}
+	}
+}
+
+/*
+Tokenize every file of the corpus under `dir`,
+comparing its tokens against the dump expected
+of it. Files failing to tokenize are reported
+rather than ending the run; the error returned
+is of walking the corpus only.
+*/
+func (lx *Lexer) RunCorpus(dir string) (CorpusReport, error) {
+	paths, err := corpusFiles(dir)
+	if err != nil {
+		return CorpusReport{}, err
+	}
+	results, err := lx.TokenizeFiles(paths, BatchOptions{})
+	var failed BatchError
+	if err != nil && !errors.As(err, &failed) {
+		return CorpusReport{}, err
+	}
+
+	report := CorpusReport{Files: len(paths), Errors: failed}
+	for _, path := range paths {
+		if failed.failed(path) {
+			continue
+		}
+		want, err := os.ReadFile(path + CorpusExpectedExt)
+		if errors.Is(err, fs.ErrNotExist) {
+			report.Missing = append(report.Missing, path)
+			continue
+		} else if err != nil {
+			report.Errors = append(report.Errors, FileError{path, err})
+			continue
+		}
+		if cm, ok := diffDump(path, want, corpusDump(results[path].Tokens)); ok {
+			report.Mismatches = append(report.Mismatches, cm)
+			continue
+		}
+		report.Passed++
+	}
+	return report, nil
+}
+
+/*
+Tokenize every file of the corpus under `dir`,
+comparing its tokens against the dump expected
+of it.
+*/
+func (g *Grammar) RunCorpus(dir string) (CorpusReport, error) {
+	return g.lexer().RunCorpus(dir)
+}
+
+/*
+Tokenize every file of the corpus under `dir`
+using the default grammar, comparing its tokens
+against the dump expected of it.
+*/
+func RunCorpus(dir string) (CorpusReport, error) {
+	return defaultGrammar.RunCorpus(dir)
+}
+
+/*
+Write the dump of the tokens of every file of
+the corpus under `dir`, replacing those expected
+before. Returns the paths of the dumps written.
+*/
+func (lx *Lexer) UpdateCorpus(dir string) ([]string, error) {
+	paths, err := corpusFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	results, err := lx.TokenizeFiles(paths, BatchOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	written := []string{}
+	for _, path := range paths {
+		name := path + CorpusExpectedExt
+		if err := os.WriteFile(name, corpusDump(results[path].Tokens), 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, name)
+	}
+	return written, nil
+}
+
+/*
+Write the dump of the tokens of every file of
+the corpus under `dir`, replacing those expected
+before.
+*/
+func (g *Grammar) UpdateCorpus(dir string) ([]string, error) {
+	return g.lexer().UpdateCorpus(dir)
+}
+
+/*
+Write the dump of the tokens of every file of
+the corpus under `dir` using the default
+grammar, replacing those expected before.
+*/
+func UpdateCorpus(dir string) ([]string, error) {
+	return defaultGrammar.UpdateCorpus(dir)
+}
+
+/* Determine if the file at `path` is among those failed. */
+func (be BatchError) failed(path string) bool {
+	for _, fe := range be {
+		if fe.Path == path {
+			return true
+		}
+	}
+	return false
+}
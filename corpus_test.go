@@ -0,0 +1,54 @@
+package lexer_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestCorpus(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "sub"), 0o755)
+	for name, text := range map[string]string{"a.x": "a + b\n", "sub/b.x": "+\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+
+	report, err := g.RunCorpus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Ok() || report.Files != 2 || len(report.Missing) != 2 {
+		t.Errorf("expected 2 missing dumps, got %+v", report)
+	}
+
+	written, err := g.UpdateCorpus(dir)
+	if err != nil || len(written) != 2 {
+		t.Fatalf("expected 2 dumps written, got %v, %v", written, err)
+	}
+	if report, _ = g.RunCorpus(dir); !report.Ok() || report.Passed != 2 {
+		t.Errorf("expected the corpus to pass, got %+v", report)
+	}
+
+	// A kind of the grammar changing alters the tokens of a.x.
+	g.AddKind("A", "a", "")
+	report, _ = g.RunCorpus(dir)
+	if report.Ok() || report.Passed != 1 || len(report.Mismatches) != 1 {
+		t.Fatalf("expected a mismatch, got %+v", report)
+	}
+	if cm := report.Mismatches[0]; filepath.Base(cm.Path) != "a.x" || cm.Line != 1 || cm.Want != "1:1\tGENIDEN\t'a'" || cm.Got != "1:1\tA\t'a'" {
+		t.Errorf("unexpected mismatch %+v", cm)
+	}
+
+	var out strings.Builder
+	report.Format(&out)
+	if !strings.Contains(out.String(), "FAIL") || !strings.HasSuffix(out.String(), "2 files: 1 passed, 1 failed, 0 missing, 0 errors\n") {
+		t.Errorf("unexpected summary %q", out.String())
+	}
+}
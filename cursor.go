@@ -0,0 +1,211 @@
+package lexer
+
+import "unicode/utf8"
+
+/* --- TOKEN CURSORS ---
+A cursor points at a token of a series and moves
+through its neighbors, so checks on the context of
+a token, say an identifier right after a keyword,
+need no index arithmetic around whitespace.
+
+Cursors are values; moving one returns a new
+cursor and leaves the original where it was. A
+cursor may be moved off either end of the series,
+where it is no longer valid and points at no
+token. */
+
+// Kinds of tokens carrying no meaning of their own.
+var triviaKinds = []string{"WHTSPACE", "NEWLINE", "CRETURN", "TABLINE"}
+
+/*
+Determine if this token is whitespace; of a kind
+whose signature is only whitespace.
+*/
+func (to TokenObject) IsTrivia() bool {
+	return isWhitespaceKind(to.Kind)
+}
+
+/* Points at a token of a series. */
+type TokenCursor struct {
+	tokens TokenObjects
+	index  int
+}
+
+/*
+Initialize a new `TokenCursor` pointing at the
+token at index `i`.
+*/
+func (tom TokenObjects) CursorAt(i int) TokenCursor {
+	c := TokenCursor{tom, i}
+	return c.clamp()
+}
+
+/* Keep the index at most one past either end. */
+func (c TokenCursor) clamp() TokenCursor {
+	if c.index < -1 {
+		c.index = -1
+	}
+	if c.index > len(c.tokens) {
+		c.index = len(c.tokens)
+	}
+	return c
+}
+
+/* Index of the token pointed at. */
+func (c TokenCursor) Index() int {
+	return c.index
+}
+
+/* Determine if this cursor points at a token. */
+func (c TokenCursor) Valid() bool {
+	return c.index >= 0 && c.index < len(c.tokens)
+}
+
+/*
+The token pointed at. Returns the zero token if
+the cursor is not valid.
+*/
+func (c TokenCursor) Token() TokenObject {
+	if !c.Valid() {
+		return TokenObject{}
+	}
+	return c.tokens[c.index]
+}
+
+/* Cursor at the token after this one. */
+func (c TokenCursor) Next() TokenCursor {
+	c.index += 1
+	return c.clamp()
+}
+
+/* Cursor at the token before this one. */
+func (c TokenCursor) Prev() TokenCursor {
+	c.index -= 1
+	return c.clamp()
+}
+
+/*
+Cursor at the first token from this one on that
+is not trivia.
+*/
+func (c TokenCursor) SkipTrivia() TokenCursor {
+	for c.Valid() && c.Token().IsTrivia() {
+		c.index += 1
+	}
+	return c
+}
+
+/*
+Cursor at the first token from this one back
+that is not trivia.
+*/
+func (c TokenCursor) SkipTriviaBack() TokenCursor {
+	for c.Valid() && c.Token().IsTrivia() {
+		c.index -= 1
+	}
+	return c
+}
+
+/* Cursor at the next token that is not trivia. */
+func (c TokenCursor) NextSignificant() TokenCursor {
+	return c.Next().SkipTrivia()
+}
+
+/* Cursor at the previous token that is not trivia. */
+func (c TokenCursor) PrevSignificant() TokenCursor {
+	return c.Prev().SkipTriviaBack()
+}
+
+/* --- INPUT CURSORS ---
+Where a token cursor moves through tokens, an
+input cursor moves through the text of a line
+being matched, byte offset by byte offset. It
+keeps the offset within the input, so views of
+the input ahead of or behind it need no bounds
+checks of their own:
+
+Offset: bytes of input passed, from 0 to its length.
+Rest: the input from the offset on.
+Lookahead(n): at most `n` bytes from the offset on.
+Lookbehind(n): at most `n` bytes up to the offset.
+
+Like token cursors, input cursors are values;
+advancing one returns a new cursor. */
+
+/* Points at a byte offset of input. */
+type Cursor struct {
+	input  string
+	offset int
+}
+
+/* Initialize a new `Cursor` at the start of the input. */
+func NewCursor(input string) Cursor {
+	return Cursor{input, 0}
+}
+
+/* Bytes of input passed. */
+func (c Cursor) Offset() int {
+	return c.offset
+}
+
+/* Determine if the cursor is at the end of its input. */
+func (c Cursor) Done() bool {
+	return c.offset == len(c.input)
+}
+
+/* The input from the offset on. */
+func (c Cursor) Rest() string {
+	return c.input[c.offset:]
+}
+
+/* The input passed. */
+func (c Cursor) Passed() string {
+	return c.input[:c.offset]
+}
+
+/* At most `n` bytes of input from the offset on; fewer at the end. */
+func (c Cursor) Lookahead(n int) string {
+	if n < 0 {
+		n = 0
+	}
+	if end := c.offset + n; end < len(c.input) {
+		return c.input[c.offset:end]
+	}
+	return c.input[c.offset:]
+}
+
+/* At most `n` bytes of input up to the offset; fewer at the start. */
+func (c Cursor) Lookbehind(n int) string {
+	if n < 0 {
+		n = 0
+	}
+	if start := c.offset - n; start > 0 {
+		return c.input[start:c.offset]
+	}
+	return c.input[:c.offset]
+}
+
+/*
+Cursor `n` bytes further on; back, if negative.
+Stops at either end of the input.
+*/
+func (c Cursor) Advance(n int) Cursor {
+	c.offset += n
+	if c.offset < 0 {
+		c.offset = 0
+	}
+	if c.offset > len(c.input) {
+		c.offset = len(c.input)
+	}
+	return c
+}
+
+/*
+Cursor past the rune at the offset; past a single
+byte, if it is not valid UTF-8. Stays at the end
+of the input.
+*/
+func (c Cursor) AdvanceRune() Cursor {
+	_, size := utf8.DecodeRuneInString(c.Rest())
+	return c.Advance(size)
+}
@@ -0,0 +1,89 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestTokenCursor(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	fn := g.AddKind("FN", "fn", "")
+	g.AddKind("LPAREN", "(", "")
+
+	tokens := g.TokenizeLine("fn \t main(", 1)
+	c := tokens.CursorAt(0)
+	if !c.Valid() || !c.Token().Is(fn) {
+		t.Fatalf("expected cursor at FN, got %v", c.Token())
+	}
+
+	name := c.NextSignificant()
+	if name.Index() != 4 || name.Token().Symbol.String() != "main" {
+		t.Errorf("expected identifier after FN, got %v", name.Token())
+	}
+	if back := name.PrevSignificant(); back.Index() != 0 {
+		t.Errorf("expected FN before identifier, got %v", back.Token())
+	}
+	if next := c.Next(); next.Index() != 1 || !next.Token().IsTrivia() {
+		t.Errorf("expected whitespace after FN, got %v", next.Token())
+	}
+	if c.Index() != 0 {
+		t.Error("expected moving a cursor to leave it in place")
+	}
+
+	before := c.Prev()
+	if before.Valid() || before.Token().Kind != nil {
+		t.Errorf("expected cursor before the start to be invalid, got %v", before.Token())
+	}
+	if again := before.Prev().Next(); again.Index() != 0 {
+		t.Errorf("expected cursor to stop one before the start, got %d", again.Index())
+	}
+
+	end := tokens.CursorAt(len(tokens) - 1).NextSignificant()
+	if end.Valid() || end.Index() != len(tokens) {
+		t.Errorf("expected cursor past the end to be invalid, got %d", end.Index())
+	}
+	if clamped := tokens.CursorAt(100); clamped.Index() != len(tokens) {
+		t.Errorf("expected cursor to be clamped, got %d", clamped.Index())
+	}
+}
+
+func TestCursor(t *testing.T) {
+	c := lexer.NewCursor("héllo")
+	if c.Offset() != 0 || c.Done() || c.Passed() != "" || c.Rest() != "héllo" {
+		t.Fatalf("unexpected cursor at start: %d %q %q", c.Offset(), c.Passed(), c.Rest())
+	}
+	if found := c.Lookbehind(3); found != "" {
+		t.Errorf("expected nothing behind the start, got %q", found)
+	}
+
+	c = c.AdvanceRune().AdvanceRune()
+	if c.Offset() != 3 || c.Passed() != "hé" || c.Rest() != "llo" {
+		t.Errorf("expected to advance past whole runes, got %d %q", c.Offset(), c.Passed())
+	}
+	if found := c.Lookbehind(1); found != "\xa9" {
+		t.Errorf("expected a byte behind, got %q", found)
+	}
+	if found := c.Lookbehind(10); found != "hé" {
+		t.Errorf("expected the look behind cut off at the start, got %q", found)
+	}
+	if found := c.Lookahead(2); found != "ll" {
+		t.Errorf("expected two bytes ahead, got %q", found)
+	}
+	if found := c.Lookahead(10); found != "llo" {
+		t.Errorf("expected the look ahead cut off at the end, got %q", found)
+	}
+
+	if end := c.Advance(10); !end.Done() || end.Offset() != 6 || end.AdvanceRune().Offset() != 6 {
+		t.Errorf("expected to stop at the end, got %d", end.Offset())
+	}
+	if start := c.Advance(-10); start.Offset() != 0 {
+		t.Errorf("expected to stop at the start, got %d", start.Offset())
+	}
+	if c.Offset() != 3 {
+		t.Errorf("expected advancing to leave the original cursor, got %d", c.Offset())
+	}
+	if bad := lexer.NewCursor("\xffa").AdvanceRune(); bad.Offset() != 1 {
+		t.Errorf("expected invalid UTF-8 passed a byte at a time, got %d", bad.Offset())
+	}
+}
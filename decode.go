@@ -0,0 +1,172 @@
+package lexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+/* --- READING TOKENS ---
+Tokens written out by the JSON and MessagePack
+formatters may be read back, so tokenized input
+cached by one process can be reused by another.
+Kinds are resolved by name against a grammar, or
+its aliases; tokens of kinds the grammar lacks
+are an error, as they were tokenized with
+another grammar. */
+
+/* A token as written by `JSONFormatter`. */
+type jsonToken struct {
+	Kind struct {
+		Name string `json:"name"`
+	} `json:"kind"`
+	LineNo   uint64      `json:"line"`
+	Position uint64      `json:"position"`
+	Symbol   string      `json:"symbol"`
+	Count    int         `json:"count"`
+	Comments []jsonToken `json:"comments"`
+	Origin   *Origin     `json:"origin"`
+}
+
+/* Resolves kind names against a grammar, caching each. */
+type kindResolver struct {
+	g    *Grammar
+	refs map[tokenName]*TokenKind
+}
+
+/* Retrieve the grammar's kind of the given name. */
+func (kr *kindResolver) resolve(name tokenName) (*TokenKind, error) {
+	if ref, ok := kr.refs[name]; ok {
+		return ref, nil
+	}
+	kind, ok := kr.g.kinds.ByName(name)
+	if alias, aliased := kr.g.aliases[name]; !ok && aliased {
+		kind, ok = kr.g.kinds.ByName(alias)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown kind %q", name)
+	}
+	if kr.refs == nil {
+		kr.refs = map[tokenName]*TokenKind{}
+	}
+	kr.refs[name] = kr.g.kinds.Ref(kind.Id)
+	return kr.refs[name], nil
+}
+
+/* Build a token from its JSON form. */
+func (kr *kindResolver) jsonToken(jt jsonToken) (TokenObject, error) {
+	kind, err := kr.resolve(tokenName(jt.Kind.Name))
+	if err != nil {
+		return TokenObject{}, err
+	}
+	to := TokenObject{Kind: kind, LineNo: tokenLineNo(jt.LineNo), Position: tokenPosition(jt.Position), Symbol: tokenSignature(jt.Symbol), Count: jt.Count, Origin: jt.Origin}
+	// JSON does not tell int values from
+	// floats; parse them as tokenizing does.
+	to.Value = kr.g.literalValue(kind.Id, to.Symbol)
+	for _, jc := range jt.Comments {
+		comment, err := kr.jsonToken(jc)
+		if err != nil {
+			return TokenObject{}, err
+		}
+		to.Comments = append(to.Comments, comment)
+	}
+	return to, nil
+}
+
+/*
+Give a token, and its comments, the grammar's
+kinds of the same names.
+*/
+func (kr *kindResolver) rekind(to *TokenObject) error {
+	kind, err := kr.resolve(to.Kind.Name)
+	if err != nil {
+		return err
+	}
+	to.Kind = kind
+	for i := range to.Comments {
+		if err := kr.rekind(&to.Comments[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Read tokens written by `JSONFormatter`, either
+as an array or with their provenance.
+*/
+func (g *Grammar) ReadTokensJSON(r io.Reader) (TokenObjects, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var jts []jsonToken
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var result struct {
+			Tokens []jsonToken `json:"tokens"`
+		}
+		err = json.Unmarshal(data, &result)
+		jts = result.Tokens
+	} else {
+		err = json.Unmarshal(data, &jts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	kr := kindResolver{g: g}
+	tokens := make(TokenObjects, 0, len(jts))
+	for _, jt := range jts {
+		to, err := kr.jsonToken(jt)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, to)
+	}
+	return tokens, nil
+}
+
+/* Read tokens written by `JSONFormatter`, with the default grammar's kinds. */
+func ReadTokensJSON(r io.Reader) (TokenObjects, error) {
+	return defaultGrammar.ReadTokensJSON(r)
+}
+
+/*
+Read tokens written by `MsgpackFormatter` or a
+`MsgpackEncoder`, with or without provenance.
+*/
+func (g *Grammar) ReadTokensMsgpack(r io.Reader) (TokenObjects, error) {
+	dec := NewMsgpackDecoder(r, nil)
+	tokens := TokenObjects{}
+	for {
+		to, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, to)
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	kr := kindResolver{g: g}
+	for i := range tokens {
+		if err := kr.rekind(&tokens[i]); err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+/* Read tokens written by `MsgpackFormatter`, with the default grammar's kinds. */
+func ReadTokensMsgpack(r io.Reader) (TokenObjects, error) {
+	return defaultGrammar.ReadTokensMsgpack(r)
+}
@@ -0,0 +1,62 @@
+package lexer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestReadTokens(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	if err := g.SetLiteral("GENIDEN", lexer.LiteralInt); err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.TokenizeSource("a.x", strings.NewReader("1 + 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formats := []struct {
+		name   string
+		format lexer.TokenFormatter
+		read   func(r *bytes.Buffer) (lexer.TokenObjects, error)
+	}{
+		{"json", lexer.JSONFormatter{}, func(r *bytes.Buffer) (lexer.TokenObjects, error) { return g.ReadTokensJSON(r) }},
+		{"msgpack", lexer.MsgpackFormatter{}, func(r *bytes.Buffer) (lexer.TokenObjects, error) { return g.ReadTokensMsgpack(r) }},
+	}
+	for _, f := range formats {
+		for _, withProvenance := range []bool{false, true} {
+			var out bytes.Buffer
+			if withProvenance {
+				err = lexer.FormatResult(&out, f.format, result)
+			} else {
+				err = f.format.Format(&out, result.Tokens)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tokens, err := f.read(&out)
+			if err != nil {
+				t.Fatalf("%s: %s", f.name, err)
+			}
+			if renderKinds(tokens) != renderKinds(result.Tokens) {
+				t.Errorf("%s: expected %s, got %s", f.name, renderKinds(result.Tokens), renderKinds(tokens))
+			}
+			if tokens[2].Kind != result.Tokens[2].Kind || tokens[4].Value != int64(2) {
+				t.Errorf("%s: expected the grammar's kinds and values, got %#v", f.name, tokens)
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	if err := (lexer.JSONFormatter{}).Format(&out, result.Tokens); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lexer.NewGrammar("y").ReadTokensJSON(&out); err == nil || !strings.Contains(err.Error(), "PLUS") {
+		t.Errorf("expected an error for an unknown kind, got %v", err)
+	}
+}
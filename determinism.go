@@ -0,0 +1,128 @@
+package lexer
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+/* --- DETERMINISM ---
+Tokenizing the same input must always give the
+same tokens. Kinds are matched in the order they
+were added, never in map order, so this holds by
+construction; `VerifyDeterminism` checks it, say
+in CI, by tokenizing input over and over from
+several goroutines at once and comparing results. */
+
+/* Tokens differing between two runs over the same input. */
+type NondeterminismError struct {
+	Run   int // Run whose tokens differ from the first.
+	Index int // Index of the first differing token.
+	Want  TokenObject
+	Got   TokenObject
+}
+
+func (ne NondeterminismError) Error() string {
+	return fmt.Sprintf(
+		"run %d differs at token %d: want %s '%s' at %d:%d, got %s '%s' at %d:%d",
+		ne.Run, ne.Index,
+		kindName(ne.Want), ne.Want.Symbol, ne.Want.LineNo, ne.Want.Position,
+		kindName(ne.Got), ne.Got.Symbol, ne.Got.LineNo, ne.Got.Position)
+}
+
+/* Name of a token's kind, or `<none>` past the end of a run. */
+func kindName(to TokenObject) string {
+	if to.Kind == nil {
+		return "<none>"
+	}
+	return string(to.Kind.Name)
+}
+
+/*
+Determine if two tokens are identical but for
+where their kinds are stored. Values and origins
+are compared deeply, as actions may set values
+of any type.
+*/
+func identicalTokens(a TokenObject, b TokenObject) bool {
+	if (a.Kind == nil) != (b.Kind == nil) {
+		return false
+	}
+	if a.Kind != nil && (a.Kind.Id != b.Kind.Id || a.Kind.Name != b.Kind.Name) {
+		return false
+	}
+	if len(a.Comments) != len(b.Comments) {
+		return false
+	}
+	for i := range a.Comments {
+		if !identicalTokens(a.Comments[i], b.Comments[i]) {
+			return false
+		}
+	}
+	return a.LineNo == b.LineNo && a.Position == b.Position && a.Count == b.Count &&
+		bytes.Equal(a.Symbol, b.Symbol) && reflect.DeepEqual(a.Value, b.Value) &&
+		reflect.DeepEqual(a.Origin, b.Origin)
+}
+
+/*
+Compare the tokens of one run against another.
+Returns nil if they are identical.
+*/
+func compareRuns(run int, want TokenObjects, got TokenObjects) error {
+	for i := 0; i < len(want) || i < len(got); i++ {
+		var w, g TokenObject
+		if i < len(want) {
+			w = want[i]
+		}
+		if i < len(got) {
+			g = got[i]
+		}
+		if !identicalTokens(w, g) {
+			return NondeterminismError{run, i, w, g}
+		}
+	}
+	return nil
+}
+
+/*
+Tokenize `src` the given number of times, from
+as many goroutines at once, verifying every run
+gives identical tokens. Returns the first
+difference found as a `NondeterminismError`, or
+any error tokenizing.
+*/
+func (lx *Lexer) VerifyDeterminism(src []byte, runs int) error {
+	if runs < 2 {
+		runs = 2
+	}
+
+	// Runs share the lexer's options, but not
+	// its profile, timings or diagnostics.
+	run := *lx
+	run.Profile = nil
+	run.Timings = nil
+	run.Diagnostics = nil
+
+	results := make([]TokenObjects, runs)
+	errs := make([]error, runs)
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = run.TokenizeReader(bytes.NewReader(src))
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < runs; i++ {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		if err := compareRuns(i, results[0], results[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package lexer_test
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestVerifyDeterminism(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	for _, sig := range []string{"<", "<=", "<<", "<<=", "=", "==", "!="} {
+		g.AddKind("OP"+sig, sig, "")
+	}
+	src := []byte(strings.Repeat("a <<= b <= c == d != e << f\n", 50))
+
+	lx := &lexer.Lexer{Grammar: g, Timings: &lexer.KindTimings{}}
+	if err := lx.VerifyDeterminism(src, 8); err != nil {
+		t.Fatal(err)
+	}
+	if report := lx.Timings.Report(); len(report) != 0 {
+		t.Errorf("expected runs not to share the lexer's timings, got %v", report)
+	}
+
+	err := lx.VerifyDeterminism([]byte{0}, 2)
+	if !errors.Is(err, lexer.ErrBinaryInput) {
+		t.Errorf("expected ErrBinaryInput, got %v", err)
+	}
+}
+
+func TestNondeterminismError(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	tokens := g.TokenizeLine("a b", 1)
+
+	err := lexer.NondeterminismError{Run: 3, Index: 2, Want: tokens[2]}
+	want := "run 3 differs at token 2: want GENIDEN 'b' at 1:3, got <none> '' at 0:0"
+	if err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestVerifyDeterminismValues(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	src := []byte("a b\n\n\nc\n")
+
+	// Values of any type are compared, even those
+	// that cannot be compared with ==.
+	lx := &lexer.Lexer{Grammar: g, Newlines: lexer.NewlineCollapse}
+	lx.OnToken("GENIDEN", func(ctx *lexer.TokenContext) {
+		ctx.Token.Value = []string{string(ctx.Token.Symbol)}
+	})
+	if err := lx.VerifyDeterminism(src, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int64
+	lx = &lexer.Lexer{Grammar: g}
+	lx.OnToken("GENIDEN", func(ctx *lexer.TokenContext) {
+		ctx.Token.Value = []int64{atomic.AddInt64(&n, 1)}
+	})
+	var nondeterminism lexer.NondeterminismError
+	if err := lx.VerifyDeterminism(src, 4); !errors.As(err, &nondeterminism) {
+		t.Errorf("expected values differing between runs found, got %v", err)
+	}
+}
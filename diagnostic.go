@@ -0,0 +1,250 @@
+package lexer
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/* --- DIAGNOSTICS ---
+Rendering of messages about tokens, showing the
+line a token is on with a caret under it, in the
+style of compiler output:
+
+	3:6: unexpected token
+	  3 | if x == 3 {
+	    |      ^^
+*/
+
+/* Gives the original text of source lines. */
+type LineSource interface {
+	Text(lineNo tokenLineNo) (string, bool)
+}
+
+/* Source text as a series of lines, the first being line 1. */
+type SourceLines []string
+
+/* Split source text into lines. */
+func NewSourceLines(src string) SourceLines {
+	src = strings.TrimSuffix(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	return SourceLines(strings.Split(src, "\n"))
+}
+
+func (sl SourceLines) Text(lineNo tokenLineNo) (string, bool) {
+	if lineNo < 1 || int(lineNo) > len(sl) {
+		return "", false
+	}
+	return sl[lineNo-1], true
+}
+
+/*
+Build the padding placing a caret under the given
+byte offset of a line. Tabs are kept so the caret
+lines up however the line is displayed.
+*/
+func caretPadding(line string, offset int) string {
+	if offset > len(line) {
+		offset = len(line)
+	}
+
+	var pad strings.Builder
+	for _, r := range line[:offset] {
+		if r == '\t' {
+			pad.WriteByte('\t')
+		} else {
+			pad.WriteByte(' ')
+		}
+	}
+	return pad.String()
+}
+
+/*
+Write a message about the given token, followed
+by the line it is on with the token underlined.
+If the line is not available from `src`, only the
+message is written. The message is preceded by
+the token's position, naming its file if `src`
+locates tokens, as a `TokenResult` does. A note
+follows for each origin of a token produced.
+*/
+func FormatDiagnostic(w io.Writer, src LineSource, tok TokenObject, msg string) error {
+	if _, err := fmt.Fprintf(w, "%s: %s\n", sourcePosition(src, tok), msg); err != nil {
+		return err
+	}
+
+	line, ok := src.Text(tok.LineNo)
+	if !ok {
+		return formatOrigins(w, tok)
+	}
+
+	gutter := strconv.FormatUint(uint64(tok.LineNo), 10)
+	blank := strings.Repeat(" ", len(gutter))
+	width := utf8.RuneCount(tok.Symbol)
+	if width < 1 {
+		width = 1
+	}
+
+	pad := ""
+	if tok.Position > 0 {
+		pad = caretPadding(line, int(tok.Position-1))
+	}
+	if _, err := fmt.Fprintf(w, "  %s | %s\n  %s | %s%s\n", gutter, line, blank, pad, strings.Repeat("^", width)); err != nil {
+		return err
+	}
+	return formatOrigins(w, tok)
+}
+
+/* --- COLLECTING DIAGNOSTICS ---
+A lexer given a `Diagnostics` recovers from
+unknown input, recording a diagnostic for each
+and going on with the fallback kind. Only so many
+diagnostics are kept, so badly corrupted input
+does not exhaust memory; the rest are counted. */
+
+// Most diagnostics kept if `Diagnostics.Max` is 0.
+const DefaultMaxDiagnostics = 1000
+
+/* --- DIAGNOSTIC CODES ---
+Every diagnostic has a stable code naming its
+class, so tools may suppress or escalate classes
+and documentation may refer to them, and a
+severity. Those of the lexer are `LEX` codes,
+those of the built in lint rules `LINT` codes;
+rules registered without a code are known by
+their name. */
+
+/* How serious a diagnostic is. */
+type Severity uint8
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+var severityNames = []string{"error", "warning", "info"}
+
+func (s Severity) String() string {
+	if int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+	return fmt.Sprintf("Severity(%d)", uint8(s))
+}
+
+/* Look up a severity by its name, e.g. `warning`. */
+func ParseSeverity(name string) (Severity, error) {
+	for i, known := range severityNames {
+		if name == known {
+			return Severity(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q, expected error, warning or info", name)
+}
+
+// Codes of the lexer's own diagnostics, of the built in lint rules and of contracts.
+const (
+	CodeUnknownInput       = "LEX001"
+	CodeAction             = "LEX002"
+	CodeUnbalancedBracket  = "LEX003"
+	CodeStepLimit          = "LEX004"
+	CodeTrailingWhitespace = "LINT001"
+	CodeIndent             = "LINT002"
+	CodeOperatorSpacing    = "LINT003"
+	CodeLineLength         = "LINT004"
+	CodeContractForbid     = "CONTRACT001"
+	CodeContractStarts     = "CONTRACT002"
+	CodeContractEnds       = "CONTRACT003"
+	CodeContractAdjacent   = "CONTRACT004"
+)
+
+/* A class of diagnostics. */
+type DiagnosticCode struct {
+	Code     string
+	Severity Severity // Of diagnostics of the class.
+	Summary  string
+}
+
+var diagnosticCodes = []DiagnosticCode{
+	{CodeUnknownInput, SeverityError, "Input matching no kind."},
+	{CodeAction, SeverityWarning, "Reported by a token action."},
+	{CodeUnbalancedBracket, SeverityError, "Bracket without a partner."},
+	{CodeStepLimit, SeverityError, "Matching gave up past the most steps allowed."},
+	{CodeTrailingWhitespace, SeverityWarning, "Whitespace ending a line."},
+	{CodeIndent, SeverityWarning, "Indentation of the wrong, or mixed, characters."},
+	{CodeOperatorSpacing, SeverityWarning, "Binary operator without a space on either side."},
+	{CodeLineLength, SeverityWarning, "Line longer than the most allowed."},
+	{CodeContractForbid, SeverityError, "Token of a kind the contract forbids."},
+	{CodeContractStarts, SeverityError, "Symbol not starting as the contract requires."},
+	{CodeContractEnds, SeverityError, "Symbol not ending as the contract requires."},
+	{CodeContractAdjacent, SeverityError, "Tokens the contract keeps apart without whitespace between."},
+}
+
+/* Classes of the lexer's own diagnostics and the built in lint rules, in order of code. */
+func DiagnosticCodes() []DiagnosticCode {
+	return append([]DiagnosticCode{}, diagnosticCodes...)
+}
+
+/* A message about a token. */
+type Diagnostic struct {
+	Token    TokenObject
+	Message  string
+	Code     string   // Stable code of its class, e.g. `LEX001`.
+	Severity Severity // An error, unless set otherwise.
+	Rule     string   // Name of the rule or check reporting it; empty for the lexer's own.
+}
+
+/* Describe the diagnostic as `severity: message [CODE]`. */
+func (d Diagnostic) Describe() string {
+	if d.Code == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s [%s]", d.Severity, d.Message, d.Code)
+}
+
+/*
+Diagnostics recorded while tokenizing. Not safe
+for use by more than one goroutine at a time.
+*/
+type Diagnostics struct {
+	Max     int // Most diagnostics kept; `DefaultMaxDiagnostics` if 0, no limit if negative.
+	List    []Diagnostic
+	Dropped int // Diagnostics past `Max`, counted but not kept.
+}
+
+/* Record a diagnostic, unless the limit is reached. */
+func (ds *Diagnostics) add(d Diagnostic) {
+	max := ds.Max
+	if max == 0 {
+		max = DefaultMaxDiagnostics
+	}
+	if max > 0 && len(ds.List) >= max {
+		ds.Dropped += 1
+		return
+	}
+	ds.List = append(ds.List, d)
+}
+
+/* Determine if diagnostics were dropped past the limit. */
+func (ds *Diagnostics) Truncated() bool {
+	return ds.Dropped > 0
+}
+
+/*
+Write each diagnostic kept, as `FormatDiagnostic`
+does with its description, followed by a notice
+if any were dropped.
+*/
+func (ds *Diagnostics) Format(w io.Writer, src LineSource) error {
+	for _, d := range ds.List {
+		if err := FormatDiagnostic(w, src, d.Token, d.Describe()); err != nil {
+			return err
+		}
+	}
+	if ds.Truncated() {
+		_, err := fmt.Fprintf(w, "too many diagnostics; %d more not shown\n", ds.Dropped)
+		return err
+	}
+	return nil
+}
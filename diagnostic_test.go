@@ -0,0 +1,111 @@
+package lexer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestFormatDiagnostic(t *testing.T) {
+	g := lexer.NewGrammar("diagnostic")
+	g.AddKind("EQ", "==", "")
+	src := lexer.NewSourceLines("x\n\tif x == 3\n")
+	tokens := g.TokenizeLine("\tif x == 3", 2)
+
+	var buf bytes.Buffer
+	if err := lexer.FormatDiagnostic(&buf, src, tokens[5], "comparison"); err != nil {
+		t.Fatal(err)
+	}
+	expected := "2:7: comparison\n  2 | \tif x == 3\n    | \t     ^^\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	tokens[5].LineNo = 9
+	lexer.FormatDiagnostic(&buf, src, tokens[5], "comparison")
+	if buf.String() != "9:7: comparison\n" {
+		t.Errorf("expected only the message for a missing line, got %q", buf.String())
+	}
+}
+
+func TestDiagnosticsLimit(t *testing.T) {
+	g := lexer.NewGrammar("diagnostic")
+	g.AddKind("PLUS", "+", "")
+	if err := g.SetIdentClasses("letter"); err != nil {
+		t.Fatal(err)
+	}
+	diags := &lexer.Diagnostics{Max: 2}
+	lx := &lexer.Lexer{Grammar: g, Diagnostics: diags}
+
+	tokens, err := lx.TokenizeReader(strings.NewReader("a+1\n2+3+4\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 8 {
+		t.Errorf("expected tokenizing to recover, got %v", tokens)
+	}
+	if len(diags.List) != 2 || diags.Dropped != 2 || !diags.Truncated() {
+		t.Fatalf("expected 2 kept and 2 dropped, got %v, %d", diags.List, diags.Dropped)
+	}
+	if d := diags.List[1]; d.Token.LineNo != tokens[3].LineNo || string(d.Token.Symbol) != "2" {
+		t.Errorf("expected '2' on line 2, got %v", d.Token)
+	}
+
+	var buf bytes.Buffer
+	if err := diags.Format(&buf, lexer.NewSourceLines("a+1\n2+3+4\n")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(buf.String(), "too many diagnostics; 2 more not shown\n") {
+		t.Errorf("expected a truncation notice, got %q", buf.String())
+	}
+}
+
+func TestDiagnosticCodes(t *testing.T) {
+	g := lexer.NewGrammar("diagnostic")
+	g.AddKind("PLUS", "+", "")
+	diags := &lexer.Diagnostics{}
+	lx := &lexer.Lexer{Grammar: g, Diagnostics: diags}
+	if _, err := lx.TokenizeReader(strings.NewReader("1+\n")); err != nil {
+		t.Fatal(err)
+	}
+	if len(diags.List) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %v", diags.List)
+	}
+	d := diags.List[0]
+	if d.Code != lexer.CodeUnknownInput || d.Severity != lexer.SeverityError {
+		t.Errorf("expected an LEX001 error, got %s %s", d.Code, d.Severity)
+	}
+	if found := d.Describe(); found != "error: unknown input '1' [LEX001]" {
+		t.Errorf("unexpected description %q", found)
+	}
+
+	index, err := g.TokenizeText("a+b \n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := lexer.DefaultLintConfig().Lint(index.Tokens())
+	if len(hits) != 3 || hits[0].Code != lexer.CodeOperatorSpacing || hits[2].Code != lexer.CodeTrailingWhitespace || hits[2].Severity != lexer.SeverityWarning {
+		t.Errorf("expected LINT003 and LINT001 warnings, got %v", hits)
+	}
+
+	seen := map[string]bool{}
+	for _, dc := range lexer.DiagnosticCodes() {
+		if seen[dc.Code] || dc.Summary == "" {
+			t.Errorf("duplicate or undocumented code %s", dc.Code)
+		}
+		seen[dc.Code] = true
+	}
+	if !seen[lexer.CodeUnknownInput] || !seen[lexer.CodeLineLength] {
+		t.Errorf("expected every code documented, got %v", seen)
+	}
+
+	if s, err := lexer.ParseSeverity("info"); err != nil || s != lexer.SeverityInfo {
+		t.Errorf("expected info, got %s, %v", s, err)
+	}
+	if _, err := lexer.ParseSeverity("fatal"); err == nil {
+		t.Errorf("expected an unknown severity to be rejected")
+	}
+}
@@ -0,0 +1,85 @@
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/* --- TOKENS FILE DISCOVERY ---
+The default grammar is loaded at init from the
+first tokens file found, looking in order at:
+
+1. An explicit path, if given.
+2. The path named by `PANZA_TOKENS`.
+3. `lexer.tokens` in the current directory.
+4. `lexer.tokens` in the parent directory, where
+   the tokens file has long been kept.
+5. `panza/lexer.tokens` in the user config
+   directory; `$XDG_CONFIG_HOME` or `~/.config`
+   on Unix, `%AppData%` on Windows.
+
+An explicit path or `PANZA_TOKENS` must name a
+file that exists; the lookup does not fall back
+past either. If no tokens file is found, the
+default grammar holds only the built in kinds
+until one is loaded. */
+
+const (
+	TokensEnv      = "PANZA_TOKENS" // Environment variable naming the tokens file.
+	TokensFileName = "lexer.tokens" // Name of the tokens file looked for in directories.
+)
+
+// Returned when no tokens file is found.
+var ErrTokensNotFound = errors.New("lexer: no tokens file found")
+
+// Tokens file the default grammar was loaded from at init.
+var defaultTokensFile string
+
+/*
+Path of the tokens file the default grammar was
+loaded from at init. Empty if none was found.
+*/
+func DefaultTokensFile() string {
+	return defaultTokensFile
+}
+
+/*
+Paths looked at for a tokens file, in order. The
+explicit path, or `PANZA_TOKENS`, is the only
+path if given.
+*/
+func TokensSearchPath(explicit string) []string {
+	if explicit != "" {
+		return []string{explicit}
+	}
+	if env := os.Getenv(TokensEnv); env != "" {
+		return []string{env}
+	}
+
+	paths := []string{
+		TokensFileName,
+		filepath.Join("..", TokensFileName),
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "panza", TokensFileName))
+	}
+	return paths
+}
+
+/*
+Find the tokens file to load, per the lookup
+order. Returns an error wrapping
+`ErrTokensNotFound` if there is none.
+*/
+func FindTokensFile(explicit string) (string, error) {
+	paths := TokensSearchPath(explicit)
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w; looked for %s", ErrTokensNotFound, strings.Join(paths, ", "))
+}
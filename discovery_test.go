@@ -0,0 +1,95 @@
+package lexer_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+/* Change into the given directory for the rest of the test. */
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func writeTokens(t *testing.T, path string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("ARROW ->\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFindTokensFile(t *testing.T) {
+	root := t.TempDir()
+	work := filepath.Join(root, "work", "sub")
+	if err := os.MkdirAll(work, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, work)
+	t.Setenv(lexer.TokensEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "config"))
+	t.Setenv("HOME", root)
+
+	if _, err := lexer.FindTokensFile(""); !errors.Is(err, lexer.ErrTokensNotFound) {
+		t.Fatalf("expected ErrTokensNotFound, got %v", err)
+	}
+
+	// Each location found takes precedence over
+	// those found before it.
+	steps := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(root, "config", "panza", "lexer.tokens"), filepath.Join(root, "config", "panza", "lexer.tokens")},
+		{filepath.Join(root, "work", "lexer.tokens"), filepath.Join("..", "lexer.tokens")},
+		{filepath.Join(work, "lexer.tokens"), "lexer.tokens"},
+	}
+	for _, step := range steps {
+		writeTokens(t, step.path)
+		got, err := lexer.FindTokensFile("")
+		if err != nil || got != step.want {
+			t.Errorf("expected %s, got %q (%v)", step.want, got, err)
+		}
+	}
+
+	env := writeTokens(t, filepath.Join(root, "env.tokens"))
+	t.Setenv(lexer.TokensEnv, env)
+	if got, _ := lexer.FindTokensFile(""); got != env {
+		t.Errorf("expected %s from the environment, got %q", env, got)
+	}
+
+	explicit := writeTokens(t, filepath.Join(root, "explicit.tokens"))
+	if got, _ := lexer.FindTokensFile(explicit); got != explicit {
+		t.Errorf("expected explicit %s, got %q", explicit, got)
+	}
+	if _, err := lexer.FindTokensFile(filepath.Join(root, "missing.tokens")); !errors.Is(err, lexer.ErrTokensNotFound) {
+		t.Errorf("expected a missing explicit file not to fall back, got %v", err)
+	}
+}
+
+func TestLoadTokensFile(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	if err := g.LoadTokensFile(writeTokens(t, filepath.Join(t.TempDir(), "x.tokens"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.LookupKind("ARROW"); !ok {
+		t.Error("expected ARROW to be loaded")
+	}
+	if err := g.LoadTokensFile("missing.tokens"); err == nil {
+		t.Error("expected an error for a missing tokens file")
+	}
+}
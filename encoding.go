@@ -0,0 +1,173 @@
+package lexer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+/* --- ENCODINGS ---
+Input is taken to be UTF-8. Input in another
+encoding may be transcoded to UTF-8 before it is
+tokenized by setting `Lexer.Decode`; otherwise
+each byte outside of UTF-8 ends up in the symbols
+of generic tokens as is.
+
+`DetectEncoding` covers the common cases: UTF-16
+marked by a byte order mark, and Latin-1 for
+input that is not valid UTF-8. */
+
+/*
+Wraps `r` in a reader transcoding it to UTF-8.
+The returned reader is read in place of `r`.
+*/
+type Decoder func(r io.Reader) (io.Reader, error)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+/*
+Transcode UTF-16 input starting with a byte order
+mark, or Latin-1 input, to UTF-8. Input is
+Latin-1 if the start of it is not valid UTF-8. A
+UTF-8 byte order mark is dropped; other input is
+read as is.
+*/
+func DetectEncoding(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, binarySniffSize)
+	head, err := br.Peek(binarySniffSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		br.Discard(len(bomUTF8))
+		return br, nil
+	case bytes.HasPrefix(head, bomUTF16LE):
+		br.Discard(len(bomUTF16LE))
+		return &utf16Reader{r: br, bigEndian: false}, nil
+	case bytes.HasPrefix(head, bomUTF16BE):
+		br.Discard(len(bomUTF16BE))
+		return &utf16Reader{r: br, bigEndian: true}, nil
+	case !validUTF8Prefix(head):
+		return &latin1Reader{r: br}, nil
+	}
+	return br, nil
+}
+
+/* Transcode Latin-1 (ISO 8859-1) input to UTF-8. */
+func DecodeLatin1(r io.Reader) (io.Reader, error) {
+	return &latin1Reader{r: bufio.NewReader(r)}, nil
+}
+
+/*
+Determine if `head` is valid UTF-8, allowing for
+a rune cut off at its end.
+*/
+func validUTF8Prefix(head []byte) bool {
+	for len(head) > 0 {
+		r, size := utf8.DecodeRune(head)
+		if r == utf8.RuneError && size == 1 {
+			return !utf8.FullRune(head)
+		}
+		head = head[size:]
+	}
+	return true
+}
+
+/* Reads Latin-1 input as UTF-8. */
+type latin1Reader struct {
+	r   *bufio.Reader
+	buf []byte // Transcoded bytes not yet read.
+}
+
+func (lr *latin1Reader) Read(p []byte) (int, error) {
+	for len(lr.buf) < len(p) {
+		b, err := lr.r.ReadByte()
+		if err != nil {
+			if len(lr.buf) > 0 {
+				break
+			}
+			return 0, err
+		}
+		lr.buf = utf8.AppendRune(lr.buf, rune(b))
+	}
+	n := copy(p, lr.buf)
+	lr.buf = lr.buf[:copy(lr.buf, lr.buf[n:])]
+	return n, nil
+}
+
+/*
+Reads UTF-16 input as UTF-8. Unpaired surrogates
+and a trailing odd byte are read as U+FFFD.
+*/
+type utf16Reader struct {
+	r         *bufio.Reader
+	bigEndian bool
+	buf       []byte // Transcoded bytes not yet read.
+	unit      rune   // Code unit read ahead of a broken surrogate pair.
+	hasUnit   bool
+}
+
+/* Read the next code unit of input. */
+func (ur *utf16Reader) readUnit() (rune, error) {
+	if ur.hasUnit {
+		ur.hasUnit = false
+		return ur.unit, nil
+	}
+	var pair [2]byte
+	n, err := io.ReadFull(ur.r, pair[:])
+	if err == io.ErrUnexpectedEOF && n == 1 {
+		return utf8.RuneError, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if ur.bigEndian {
+		return rune(pair[0])<<8 | rune(pair[1]), nil
+	}
+	return rune(pair[1])<<8 | rune(pair[0]), nil
+}
+
+/* Read the next rune of input. */
+func (ur *utf16Reader) readRune() (rune, error) {
+	r1, err := ur.readUnit()
+	if err != nil || !utf16.IsSurrogate(r1) {
+		return r1, err
+	}
+	r2, err := ur.readUnit()
+	if err == io.EOF {
+		return utf8.RuneError, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if r := utf16.DecodeRune(r1, r2); r != utf8.RuneError {
+		return r, nil
+	}
+	// Not a pair; `r2` starts the next rune.
+	ur.unit, ur.hasUnit = r2, true
+	return utf8.RuneError, nil
+}
+
+func (ur *utf16Reader) Read(p []byte) (int, error) {
+	for len(ur.buf) < len(p) {
+		r, err := ur.readRune()
+		if err != nil {
+			if len(ur.buf) > 0 {
+				break
+			}
+			return 0, err
+		}
+		ur.buf = utf8.AppendRune(ur.buf, r)
+	}
+	n := copy(p, ur.buf)
+	ur.buf = ur.buf[:copy(ur.buf, ur.buf[n:])]
+	return n, nil
+}
@@ -0,0 +1,83 @@
+package lexer_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func decodeAll(t *testing.T, decode lexer.Decoder, input string) string {
+	t.Helper()
+	r, err := decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestDetectEncoding(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"utf8", "héllo π", "héllo π"},
+		{"utf8 bom", "\xef\xbb\xbfx = 1", "x = 1"},
+		{"utf16le", "\xff\xfeh\x00\xe9\x00", "hé"},
+		{"utf16be", "\xfe\xff\x00h\x00\xe9", "hé"},
+		{"utf16 pair", "\xff\xfe\x3d\xd8\x00\xdeA\x00", "😀A"},
+		{"utf16 unpaired", "\xff\xfe\x3d\xd8A\x00", "�A"},
+		{"utf16 odd byte", "\xff\xfeA\x00B", "A�"},
+		{"latin1", "caf\xe9 cr\xe8me", "café crème"},
+		{"empty", "", ""},
+	}
+	for _, c := range cases {
+		if got := decodeAll(t, lexer.DetectEncoding, c.input); got != c.want {
+			t.Errorf("%s: expected %q, got %q", c.name, c.want, got)
+		}
+	}
+}
+
+func TestDecodeLexer(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "plus")
+
+	// Without decoding, UTF-16 reads as binary.
+	input := "\xff\xfea\x00+\x00\xe9\x00\n\x00b\x00"
+	lx := &lexer.Lexer{Grammar: g}
+	if _, err := lx.TokenizeReader(strings.NewReader(input)); err != lexer.ErrBinaryInput {
+		t.Errorf("expected ErrBinaryInput, got %v", err)
+	}
+
+	lx.Decode = lexer.DetectEncoding
+	tokens, err := lx.TokenizeReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(symbolsOf(tokens), " "); got != "a + é b" {
+		t.Errorf("unexpected symbols %q", got)
+	}
+
+	tokens, err = lx.TokenizeFile(writeSource(t, "\xe9+\xe8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(symbolsOf(tokens), " "); got != "é + è" {
+		t.Errorf("unexpected symbols %q", got)
+	}
+
+	lx.Decode = lexer.DecodeLatin1
+	tokens, err = lx.TokenizeReader(strings.NewReader("\xc3\xa9"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(symbolsOf(tokens), " "); got != "Ã©" {
+		t.Errorf("unexpected symbols %q", got)
+	}
+}
@@ -0,0 +1,179 @@
+package lexer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/* --- MATCH EXPLANATIONS ---
+Why input is tokenized as it is may be asked of a
+lexer, for any position of a line. The kinds
+whose signatures start with the byte there are
+the candidates; each is either the winner, or
+rejected for a reason:
+
+prefix mismatch: The signature is not at the
+position.
+anchor: The kind is anchored, and its anchor does
+not hold there.
+shorter: A longer signature matched.
+priority: A signature as long matched, of a kind
+added before it.
+comment: A comment starts at the position.
+
+If no candidate wins, the input is a generic
+identifier, or falls back on the lexer's fallback
+kind. */
+
+/* Why a candidate kind did or did not win. */
+type MatchReason uint8
+
+const (
+	MatchWon MatchReason = iota
+	MatchPrefixMismatch
+	MatchAnchor
+	MatchShorter
+	MatchPriority
+	MatchComment
+)
+
+var matchReasonNames = []string{"won", "prefix mismatch", "anchor", "shorter", "priority", "comment"}
+
+func (mr MatchReason) String() string {
+	if int(mr) < len(matchReasonNames) {
+		return matchReasonNames[mr]
+	}
+	return fmt.Sprintf("MatchReason(%d)", uint8(mr))
+}
+
+/* A kind considered at a position. */
+type MatchCandidate struct {
+	Kind   TokenKind
+	Length int // Bytes of input its signature matched; 0 if none.
+	Reason MatchReason
+}
+
+/* How the input at a position is tokenized. */
+type MatchExplanation struct {
+	Position   int              // As in `TokenObject.Position`, from 1.
+	Candidates []MatchCandidate // In the order kinds were added.
+	Winner     TokenKind        // Kind of the token at the position.
+	Symbol     string           // Input the winner takes.
+	Rule       string           // What decided the winner.
+}
+
+func (me MatchExplanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d: %s '%s' (%s)\n", me.Position, me.Winner.Name, me.Symbol, me.Rule)
+	for _, c := range me.Candidates {
+		fmt.Fprintf(&b, "  %s %q: %s\n", c.Kind.Name, c.Kind.Signature.String(), c.Reason)
+	}
+	return b.String()
+}
+
+/*
+Explain how the input at a position of a line is
+tokenized, as if a token started there. Positions
+are byte offsets from 1, as in tokens; returns
+false if `pos` is outside of the line.
+*/
+func (lx *Lexer) Explain(line string, pos int) (MatchExplanation, bool) {
+	if pos < 1 || pos > len(line) {
+		return MatchExplanation{}, false
+	}
+	g := lx.grammar()
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	me := MatchExplanation{Position: pos}
+	rest, atStart := line[pos-1:], g.isBlank(line[:pos-1])
+
+	for _, kind := range g.candidates(rest[0]) {
+		c := MatchCandidate{Kind: kind, Reason: MatchPrefixMismatch}
+		n, ok := len(kind.Signature), strings.HasPrefix(rest, string(kind.Signature))
+		if isSpaced(string(kind.Signature)) {
+			n, ok = g.matchWords(rest, string(kind.Signature))
+		}
+		switch {
+		case !ok:
+		case !g.anchorHolds(kind.Id, rest, n, atStart):
+			c.Reason = MatchAnchor
+		default:
+			c.Length = n
+		}
+		me.Candidates = append(me.Candidates, c)
+	}
+
+	comment := g.isComment(rest)
+	id, size, matched := g.matchKind(rest, atStart)
+	for i := range me.Candidates {
+		c := &me.Candidates[i]
+		switch {
+		case c.Length == 0:
+		case comment:
+			c.Reason = MatchComment
+		case c.Kind.Id == id:
+			c.Reason = MatchWon
+		case c.Length < size:
+			c.Reason = MatchShorter
+		default:
+			c.Reason = MatchPriority
+		}
+	}
+
+	switch {
+	case comment:
+		id, me.Symbol, me.Rule = CommentId, rest, "comment"
+	case matched:
+		me.Symbol, me.Rule = rest[:size], "longest match"
+	default:
+		id, me.Symbol, me.Rule = GenIdenId, g.findIdenToken(rest), "identifier"
+		if !g.isIdentifier(me.Symbol) {
+			fallback, err := lx.fallback(g)
+			if err != nil {
+				fallback = GenIdenId
+			}
+			id, me.Rule = fallback, "fallback"
+		}
+	}
+	me.Winner = g.kinds.Get(id)
+	return me, true
+}
+
+/*
+Explain how the input at a position of a line is
+tokenized.
+*/
+func (g *Grammar) Explain(line string, pos int) (MatchExplanation, bool) {
+	return g.lexer().Explain(line, pos)
+}
+
+/*
+Explain how the input at a position of a line is
+tokenized by the default grammar.
+*/
+func Explain(line string, pos int) (MatchExplanation, bool) {
+	return defaultGrammar.Explain(line, pos)
+}
+
+/*
+Retrieve the kinds whose signatures start with
+the given byte, in the order they were added.
+*/
+func (g *Grammar) candidates(b byte) []TokenKind {
+	kinds := []TokenKind{}
+	for _, ik := range g.kinds.Starting(b) {
+		kinds = append(kinds, g.kinds.Get(ik.id))
+	}
+	for sig, ids := range g.kinds.keywords.ids {
+		if sig[0] != b {
+			continue
+		}
+		for _, id := range ids {
+			kinds = append(kinds, g.kinds.Get(id))
+		}
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i].Id < kinds[j].Id })
+	return kinds
+}
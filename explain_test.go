@@ -0,0 +1,61 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestExplain(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	for _, kind := range operatorKinds {
+		g.AddKind(kind[0], kind[1], "")
+	}
+	g.AddKind("ALSO_SHL", "<<", "")
+	g.AddKind("LABEL", "<label>", "")
+	if err := g.SetAnchor("LABEL", lexer.AnchorStart); err != nil {
+		t.Fatal(err)
+	}
+
+	me, ok := g.Explain("a <<= <label>", 3)
+	if !ok || me.Winner.Name != "SHLEQ" || me.Symbol != "<<=" || me.Rule != "longest match" {
+		t.Fatalf("unexpected explanation\n%s", me)
+	}
+	want := map[string]lexer.MatchReason{
+		"LT":       lexer.MatchShorter,
+		"SHL":      lexer.MatchShorter,
+		"SHLEQ":    lexer.MatchWon,
+		"LE":       lexer.MatchPrefixMismatch,
+		"ALSO_SHL": lexer.MatchShorter,
+		"LABEL":    lexer.MatchPrefixMismatch,
+	}
+	if len(me.Candidates) != len(want) {
+		t.Errorf("expected %d candidates\n%s", len(want), me)
+	}
+	for _, c := range me.Candidates {
+		if c.Reason != want[string(c.Kind.Name)] {
+			t.Errorf("%s: expected %s, got %s", c.Kind.Name, want[string(c.Kind.Name)], c.Reason)
+		}
+	}
+
+	me, _ = g.Explain("a << b", 3)
+	for _, c := range me.Candidates {
+		if c.Kind.Name == "ALSO_SHL" && c.Reason != lexer.MatchPriority {
+			t.Errorf("expected ALSO_SHL to lose on priority\n%s", me)
+		}
+	}
+
+	me, _ = g.Explain("a <<= <label>", 7)
+	for _, c := range me.Candidates {
+		if c.Kind.Name == "LABEL" && c.Reason != lexer.MatchAnchor {
+			t.Errorf("expected LABEL rejected by its anchor\n%s", me)
+		}
+	}
+
+	if me, _ := g.Explain("abc", 2); me.Winner.Name != "GENIDEN" || me.Symbol != "bc" || len(me.Candidates) != 0 {
+		t.Errorf("unexpected explanation\n%s", me)
+	}
+	if _, ok := g.Explain("abc", 4); ok {
+		t.Error("expected no explanation past the end of the line")
+	}
+}
@@ -0,0 +1,26 @@
+package lexer
+
+import "fmt"
+
+/* --- FALLBACK ---
+Input matching no kind, and not an identifier
+declared with `@ident`, falls back on a kind of
+the lexer's choosing: `GENIDEN` by default, the
+built in `ERROR` kind, or any kind of the
+grammar. A strict lexer has no fallback, and
+fails on such input instead. */
+
+/*
+ID of the kind input matching no kind falls back
+on. The caller must hold the grammar's read lock.
+*/
+func (lx *Lexer) fallback(g *Grammar) (tokenId, error) {
+	if lx.Fallback == "" {
+		return GenIdenId, nil
+	}
+	kind, ok := g.kinds.ByName(tokenName(lx.Fallback))
+	if !ok {
+		return 0, fmt.Errorf("unknown fallback kind %q", lx.Fallback)
+	}
+	return kind.Id, nil
+}
@@ -0,0 +1,37 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestFallbackKind(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	g.AddKind("WORD", "&WORD", "")
+	if err := g.SetIdentClasses("letter"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		fallback string
+		want     string // Kind of '?', after the identifier 'a'.
+	}{
+		{"", "GENIDEN"},
+		{"ERROR", "ERROR"},
+		{"WORD", "WORD"},
+	}
+	for _, c := range cases {
+		lx := &lexer.Lexer{Grammar: g, Fallback: c.fallback}
+		tokens := lx.TokenizeLine("a+?", 1)
+		if len(tokens) != 3 || tokens[0].Kind.Name != "GENIDEN" || string(tokens[2].Kind.Name) != c.want {
+			t.Errorf("%q: expected GENIDEN, PLUS, %s, got %v", c.fallback, c.want, tokens)
+		}
+	}
+
+	lx := &lexer.Lexer{Grammar: g, Fallback: "MISSING"}
+	if _, err := lx.TokenizeBytes([]byte("a")); err == nil {
+		t.Error("expected an error for an unknown fallback kind")
+	}
+}
@@ -0,0 +1,96 @@
+package lexer
+
+import (
+	"bytes"
+	"errors"
+)
+
+/* --- INCREMENTAL FEEDING ---
+A `Feeder` accepts input in chunks as it arrives,
+from a socket or REPL say, and delivers tokens to
+a sink as each line is completed, as
+`TokenizeFileTo` emits them. */
+
+// Returned when writing to a closed `Feeder`.
+var ErrFeederClosed = errors.New("lexer: write to closed feeder")
+
+/*
+Tokenizes input written to it in arbitrary
+chunks. Tokens are emitted to the sink once the
+line they are on is complete; a trailing
+incomplete line is held until more input arrives
+or the feeder is closed.
+*/
+type Feeder struct {
+	run     *sinkRun
+	pending []byte
+	closed  bool
+}
+
+/* Initialize a new `Feeder` emitting to the given sink. */
+func (lx *Lexer) NewFeeder(sink TokenSink) *Feeder {
+	return &Feeder{run: lx.newSinkRun(sink)}
+}
+
+/* Initialize a new `Feeder` emitting to the given sink. */
+func (g *Grammar) NewFeeder(sink TokenSink) *Feeder {
+	return g.lexer().NewFeeder(sink)
+}
+
+/*
+Initialize a new `Feeder` using the default
+grammar.
+*/
+func NewFeeder(sink TokenSink) *Feeder {
+	return defaultGrammar.NewFeeder(sink)
+}
+
+/* Tokenize a line, with its terminator if it has one. */
+func (f *Feeder) emitLine(line []byte) error {
+	return f.run.line(string(line))
+}
+
+/*
+Feed a chunk of input, emitting the tokens of
+any lines it completes.
+*/
+func (f *Feeder) Write(p []byte) (int, error) {
+	if f.closed {
+		return 0, ErrFeederClosed
+	}
+	f.pending = append(f.pending, p...)
+
+	for {
+		ind := bytes.IndexByte(f.pending, '\n')
+		if ind < 0 {
+			break
+		}
+		line := f.pending[:ind+1]
+		f.pending = f.pending[ind+1:]
+		if err := f.emitLine(line); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+/*
+Emit the tokens of any incomplete trailing line
+and flush the sink. The feeder accepts no input
+afterwards.
+*/
+func (f *Feeder) Close() error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+
+	if len(f.pending) > 0 {
+		line := f.pending
+		f.pending = nil
+		if err := f.emitLine(line); err != nil {
+			return err
+		}
+	}
+	return f.run.close()
+}
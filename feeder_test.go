@@ -0,0 +1,33 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestFeeder(t *testing.T) {
+	sink := &lexer.SliceSink{}
+	feeder := lexer.NewFeeder(sink)
+
+	feeder.Write([]byte(" \t"))
+	if len(sink.Tokens) != 0 {
+		t.Fatalf("expected incomplete line to be held, got %v", sink.Tokens)
+	}
+
+	feeder.Write([]byte("\r\n\t"))
+	if len(sink.Tokens) != 2 {
+		t.Fatalf("expected 2 tokens after first line, got %v", sink.Tokens)
+	}
+
+	if err := feeder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.Tokens) != 3 || sink.Tokens[2].LineNo != 2 {
+		t.Fatalf("expected trailing token on line 2, got %v", sink.Tokens)
+	}
+
+	if _, err := feeder.Write([]byte(" ")); err != lexer.ErrFeederClosed {
+		t.Errorf("expected ErrFeederClosed, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package lexer
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+/* --- FINGERPRINTS ---
+Sources alike at the lexical level, a file
+copied and its identifiers renamed say, give
+alike sequences of token kinds. Fingerprints
+hash every run of `K` kinds of the significant
+tokens of a source, spellings aside, and keep
+the least hash of each window of `Window` runs,
+per winnowing (Schleimer, Wilkerson and Aiken,
+2003). Any run of kinds shared by two sources at
+least `K+Window-1` tokens long is sure to give a
+hash shared by their fingerprints.
+
+The similarity of two fingerprints is the
+Jaccard index of their hashes: 1 for sources of
+the same kinds in the same order, 0 for sources
+sharing no run of kinds. */
+
+// Length of runs of kinds, and their windows, if not given.
+const (
+	DefaultFingerprintK      = 5
+	DefaultFingerprintWindow = 4
+)
+
+/* How fingerprints are taken; defaults apply to values of zero. */
+type FingerprintOptions struct {
+	K      int // Kinds per hashed run.
+	Window int // Runs per window, of which the least hash is kept.
+}
+
+/* Hashes selected from the runs of kinds of a source, in order. */
+type Fingerprint []uint64
+
+/* Hash a run of kinds. */
+func hashKinds(kinds []*TokenKind) uint64 {
+	h := fnv.New64a()
+	for _, kind := range kinds {
+		h.Write([]byte(kind.Name))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+/*
+Take the fingerprint of the kinds of the
+significant tokens of this series, ignoring
+their spellings and positions.
+*/
+func (tom TokenObjects) Fingerprint(opts FingerprintOptions) Fingerprint {
+	k, window := opts.K, opts.Window
+	if k <= 0 {
+		k = DefaultFingerprintK
+	}
+	if window <= 0 {
+		window = DefaultFingerprintWindow
+	}
+
+	kinds := []*TokenKind{}
+	for _, to := range tom {
+		if to.Kind != nil && !to.IsTrivia() && to.Kind.Id != CommentId {
+			kinds = append(kinds, to.Kind)
+		}
+	}
+	if len(kinds) == 0 {
+		return Fingerprint{}
+	}
+	if len(kinds) < k {
+		k = len(kinds)
+	}
+	hashes := make([]uint64, len(kinds)-k+1)
+	for i := range hashes {
+		hashes[i] = hashKinds(kinds[i : i+k])
+	}
+	if len(hashes) < window {
+		window = len(hashes)
+	}
+
+	// Keep the rightmost least hash of each window, once per
+	// position it was found at.
+	fp := Fingerprint{}
+	last := -1
+	for start := 0; start+window <= len(hashes); start++ {
+		least := start
+		for i := start; i < start+window; i++ {
+			if hashes[i] <= hashes[least] {
+				least = i
+			}
+		}
+		if least != last {
+			fp = append(fp, hashes[least])
+			last = least
+		}
+	}
+	return fp
+}
+
+/*
+Jaccard index of the distinct hashes of both
+fingerprints, from 0 to 1. Two empty
+fingerprints are alike.
+*/
+func (fp Fingerprint) Similarity(other Fingerprint) float64 {
+	a, b := fp.distinct(), other.distinct()
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			shared++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+/* The distinct hashes of the fingerprint, in ascending order. */
+func (fp Fingerprint) distinct() []uint64 {
+	sorted := append([]uint64(nil), fp...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := 0
+	for i, h := range sorted {
+		if i == 0 || h != sorted[n-1] {
+			sorted[n] = h
+			n++
+		}
+	}
+	return sorted[:n]
+}
+
+/*
+Similarity of two series of tokens at the
+lexical level, from 0 to 1, per their
+fingerprints of default options.
+*/
+func Similarity(a, b TokenObjects) float64 {
+	return a.Fingerprint(FingerprintOptions{}).Similarity(b.Fingerprint(FingerprintOptions{}))
+}
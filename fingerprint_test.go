@@ -0,0 +1,43 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestSimilarity(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	for _, kind := range [][2]string{{"LET", "let"}, {"ASSIGN", "="}, {"PLUS", "+"}, {"SEMI", ";"}, {"IF", "if"}, {"LBRACE", "{"}, {"RBRACE", "}"}} {
+		g.AddKind(kind[0], kind[1], "")
+	}
+	original := g.TokenizeLines([]string{
+		"let a = b + c;", "if a { let d = a + a; }", "let e = d + b;",
+	})
+	renamed := g.TokenizeLines([]string{
+		"let  x=y+z ;", "if x {", "  let w = x + x;", "}", "let v = w + y;",
+	})
+	edited := g.TokenizeLines([]string{
+		"let a = b + c;", "if a { let d = a + a; }", "if d { e; }",
+	})
+	other := g.TokenizeLines([]string{"{ { } } + + +"})
+
+	if s := lexer.Similarity(original, renamed); s != 1 {
+		t.Errorf("expected renamed source to be alike, got %v", s)
+	}
+	s := lexer.Similarity(original, edited)
+	if s <= 0.25 || s >= 1 {
+		t.Errorf("expected edited source to be partly alike, got %v", s)
+	}
+	if s := lexer.Similarity(original, other); s != 0 {
+		t.Errorf("expected other source not to be alike, got %v", s)
+	}
+	if s := lexer.Similarity(nil, lexer.TokenObjects{}); s != 1 {
+		t.Errorf("expected empty sources to be alike, got %v", s)
+	}
+
+	fp := original.Fingerprint(lexer.FingerprintOptions{K: 3, Window: 2})
+	if len(fp) == 0 || fp.Similarity(renamed.Fingerprint(lexer.FingerprintOptions{K: 3, Window: 2})) != 1 {
+		t.Errorf("unexpected fingerprint %v", fp)
+	}
+}
@@ -0,0 +1,126 @@
+package lexer
+
+import "sort"
+
+/* --- FOLDING RANGES ---
+Editors fold ranges of lines: bracketed blocks,
+and runs of comments. Both are known from the
+tokens alone, so editor integrations may take
+them from the lexer rather than a parser.
+
+Ranges are of lines counted from 1, as tokens
+are; the Language Server Protocol counts from 0.
+A block's range ends on the line before its close
+bracket if the bracket starts that line, so the
+bracket stays in view when folded, as most
+editors expect. Comments only span single lines;
+a run of lines holding only comments folds as
+one range. */
+
+// Kinds of folding ranges, as named by the Language Server Protocol.
+const (
+	FoldComment = "comment"
+	FoldRegion  = "region"
+)
+
+/* A range of lines an editor may fold. */
+type FoldingRange struct {
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Kind      string `json:"kind"` // `FoldComment` or `FoldRegion`.
+}
+
+/*
+Compute the ranges of lines of the tokens that
+fold: blocks between pairs of brackets spanning
+lines, and runs of comment lines. Ranges are in
+order of their start line, outermost first.
+*/
+func FoldingRanges(tokens []TokenObject, pairs ...BracketPair) []FoldingRange {
+	ranges := []FoldingRange{}
+	report := CheckBrackets(tokens, pairs...)
+	for i, partner := range report.Partners {
+		if partner <= i {
+			continue
+		}
+		start, end := int(tokens[i].LineNo), int(tokens[partner].LineNo)
+		if startsLine(tokens, partner) {
+			end--
+		}
+		if end > start {
+			ranges = append(ranges, FoldingRange{start, end, FoldRegion})
+		}
+	}
+	ranges = append(ranges, commentRuns(tokens)...)
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].StartLine != ranges[j].StartLine {
+			return ranges[i].StartLine < ranges[j].StartLine
+		}
+		return ranges[i].EndLine > ranges[j].EndLine
+	})
+	return ranges
+}
+
+/*
+Determine if the token at index `i` is the first
+of its line that is not whitespace.
+*/
+func startsLine(tokens []TokenObject, i int) bool {
+	for j := i - 1; j >= 0 && tokens[j].LineNo == tokens[i].LineNo; j-- {
+		if !tokens[j].IsTrivia() {
+			return false
+		}
+	}
+	return true
+}
+
+/* Ranges of runs of consecutive lines holding only comments. */
+func commentRuns(tokens []TokenObject) []FoldingRange {
+	runs := []FoldingRange{}
+	run := FoldingRange{Kind: FoldComment}
+	flush := func() {
+		if run.EndLine > run.StartLine {
+			runs = append(runs, run)
+		}
+		run.StartLine, run.EndLine = 0, 0
+	}
+
+	for start := 0; start < len(tokens); {
+		end := start
+		lineNo := int(tokens[start].LineNo)
+		comment := false
+		for end < len(tokens) && int(tokens[end].LineNo) == lineNo {
+			switch {
+			case tokens[end].Kind != nil && tokens[end].Kind.Id == CommentId:
+				comment = true
+			case !tokens[end].IsTrivia():
+				comment, end = false, skipLine(tokens, end)
+				continue
+			}
+			end++
+		}
+		start = end
+
+		switch {
+		case !comment:
+			flush()
+		case run.StartLine > 0 && lineNo == run.EndLine+1:
+			run.EndLine = lineNo
+		default:
+			flush()
+			run.StartLine, run.EndLine = lineNo, lineNo
+		}
+	}
+	flush()
+	return runs
+}
+
+/* Index of the first token past the line of that at `i`. */
+func skipLine(tokens []TokenObject, i int) int {
+	lineNo := tokens[i].LineNo
+	for i < len(tokens) && tokens[i].LineNo == lineNo {
+		i++
+	}
+	return i
+}
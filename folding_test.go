@@ -0,0 +1,41 @@
+package lexer_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestFoldingRanges(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("LBRACE", "{", "")
+	g.AddKind("RBRACE", "}", "")
+	if err := g.SetLineComments("//"); err != nil {
+		t.Fatal(err)
+	}
+	index, err := g.TokenizeText(`// one
+  // two
+fn {
+	if {
+		x } // three
+	// four
+}
+{ y
+}{ z }
+// five
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := lexer.FoldingRanges(index.Tokens(), lexer.BracketPair{Open: "LBRACE", Close: "RBRACE"})
+	want := []lexer.FoldingRange{
+		{StartLine: 1, EndLine: 2, Kind: lexer.FoldComment},
+		{StartLine: 3, EndLine: 6, Kind: lexer.FoldRegion},
+		{StartLine: 4, EndLine: 5, Kind: lexer.FoldRegion},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
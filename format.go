@@ -0,0 +1,120 @@
+package lexer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+/* --- TOKEN FORMATTING ---
+Formatters write out a series of tokens in some
+format for consumption elsewhere. Text, JSON and
+CSV are provided; others may be registered by name
+with `RegisterFormatter`. */
+
+/* Writes a series of tokens to a writer. */
+type TokenFormatter interface {
+	Format(w io.Writer, toks []TokenObject) error
+}
+
+/* Writes tokens as tab separated, human readable lines. */
+type TextFormatter struct{}
+
+func (TextFormatter) Format(w io.Writer, toks []TokenObject) error {
+	for _, to := range toks {
+		_, err := fmt.Fprintf(w, "%d:%d\t%s\t'%s'\n", to.LineNo, to.Position, to.Kind.Name, to.Symbol)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Writes tokens as a JSON array. If `Indent` is
+set, the output is indented with it.
+*/
+type JSONFormatter struct {
+	Indent string
+}
+
+func (f JSONFormatter) Format(w io.Writer, toks []TokenObject) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", f.Indent)
+	if toks == nil {
+		toks = []TokenObject{}
+	}
+	return enc.Encode(toks)
+}
+
+/* Writes tokens as CSV, preceded by a header row. */
+type CSVFormatter struct{}
+
+func (CSVFormatter) Format(w io.Writer, toks []TokenObject) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"line", "position", "kind", "symbol"}); err != nil {
+		return err
+	}
+	for _, to := range toks {
+		record := []string{
+			strconv.FormatUint(uint64(to.LineNo), 10),
+			strconv.FormatUint(uint64(to.Position), 10),
+			string(to.Kind.Name),
+			string(to.Symbol),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Formatters available by name.
+var tokenFormatters = map[string]TokenFormatter{
+	"text": TextFormatter{},
+	"json": JSONFormatter{},
+	"csv":  CSVFormatter{},
+}
+
+// Guards `tokenFormatters`.
+var tokenFormattersMu sync.RWMutex
+
+/*
+Register a `TokenFormatter` under the given name,
+replacing any formatter already registered with
+that name.
+*/
+func RegisterFormatter(name string, f TokenFormatter) {
+	tokenFormattersMu.Lock()
+	defer tokenFormattersMu.Unlock()
+
+	tokenFormatters[name] = f
+}
+
+/* Retrieve a registered `TokenFormatter` by name. */
+func Formatter(name string) (TokenFormatter, bool) {
+	tokenFormattersMu.RLock()
+	defer tokenFormattersMu.RUnlock()
+
+	f, ok := tokenFormatters[name]
+	return f, ok
+}
+
+/* List the names of registered formatters. */
+func FormatterNames() []string {
+	tokenFormattersMu.RLock()
+	defer tokenFormattersMu.RUnlock()
+
+	names := []string{}
+	for name := range tokenFormatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
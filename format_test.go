@@ -0,0 +1,38 @@
+package lexer_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func sampleTokens(t *testing.T) []lexer.TokenObject {
+	kind, ok := lexer.LookupKind("WHTSPACE")
+	if !ok {
+		t.Fatal("expected WHTSPACE to be loaded")
+	}
+	return []lexer.TokenObject{*kind.New(1, 1, []byte(" "))}
+}
+
+func TestFormatters(t *testing.T) {
+	cases := map[string]string{
+		"text": "1:1\tWHTSPACE\t' '\n",
+		"csv":  "line,position,kind,symbol\n1,1,WHTSPACE,\" \"\n",
+		"json": `[{"kind":{"id":0,"name":"WHTSPACE","signature":" ","description":"Whitespace."},"line":1,"position":1,"symbol":" "}]` + "\n",
+	}
+
+	for name, expected := range cases {
+		f, ok := lexer.Formatter(name)
+		if !ok {
+			t.Fatalf("expected formatter %q to be registered", name)
+		}
+		var buf bytes.Buffer
+		if err := f.Format(&buf, sampleTokens(t)); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package lexer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+/* --- GRAMMARS ---
+A grammar is a set of `TokenKind`s input is
+tokenized against. The default grammar is loaded
+from the tokens file at init; others may be built
+and registered so input can be matched to a
+grammar by its file extension or shebang.
+
+Any number of goroutines may tokenize input with
+the same grammar at once. Kinds may also be added
+or loaded while the grammar is in use, though
+input being tokenized at the time may see either
+set of kinds. */
+
+/*
+A set of `TokenKind`s and how to identify its
+input. The identifying fields should not be
+changed once the grammar is registered.
+*/
+type Grammar struct {
+	Name         string
+	Extensions   []string // File extensions, including the leading '.'
+	Interpreters []string // Interpreter names given on a `#!` line
+	Flags        []string // Flags enabling `@when` guarded kinds as tokens files load
+	Version      string   // Version of the grammar's definition, if declared, e.g. with `@version`
+
+	mu               sync.RWMutex // Guards the fields below.
+	kinds            tokenKindMap
+	nextId           tokenId                  // Holds what will be the next ID given to a `TokenKind`.
+	nameMaxSize      int                      // Tracks the last recorded largest `TokenKind` Name.
+	signatureMaxSize int                      // Tracks the last recorded largest `TokenKind` Signature.
+	ident            *identClass              // Characters of generic identifiers, if declared.
+	literals         map[tokenId]LiteralType  // Kinds whose symbols are parsed into values.
+	anchors          map[tokenId]Anchor       // Kinds matched only at the start or end of a line.
+	comments         []string                 // Markers starting line comments, if declared.
+	aliases          map[tokenName]tokenName  // Names of kinds of old versions, to current names.
+	trivia           map[tokenId]TriviaPolicy // What becomes of tokens of trivia kinds.
+}
+
+/*
+Initialize a new `Grammar` holding only the
+built in tokens.
+*/
+func NewGrammar(name string) *Grammar {
+	g := &Grammar{Name: name}
+	g.setTokens(tokenSpec{})
+	return g
+}
+
+/* Add a new `TokenKind` to this grammar. */
+func (g *Grammar) AddKind(name string, sig string, desc string) TokenKind {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.add(tokenName(name), tokenSignature(sig), tokenDescription(desc))
+}
+
+/*
+Declare the characters generic identifiers are
+made of, as with the `@ident` directive. Each
+class is `letter`, `digit`, or literal characters.
+No classes lets identifiers run up to the next
+token again.
+*/
+func (g *Grammar) SetIdentClasses(classes ...string) error {
+	var ident *identClass
+	if len(classes) > 0 {
+		parsed, err := parseIdentClass(strings.Join(classes, " "))
+		if err != nil {
+			return err
+		}
+		ident = &parsed
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.ident = ident
+	return nil
+}
+
+/*
+Determine if definitions guarded by the given
+flags are enabled. A flag starting with '!' is
+satisfied if the flag is not enabled.
+*/
+func (g *Grammar) enabled(when []string) bool {
+	for _, flag := range when {
+		if strings.HasPrefix(flag, "!") {
+			if containsString(g.Flags, flag[1:]) {
+				return false
+			}
+		} else if !containsString(g.Flags, flag) {
+			return false
+		}
+	}
+	return true
+}
+
+/* Characters generic identifiers are made of. */
+type identClass struct {
+	letters bool
+	digits  bool
+	chars   string
+}
+
+/* Parse a space separated list of identifier classes. */
+func parseIdentClass(s string) (identClass, error) {
+	ic := identClass{}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ic, fmt.Errorf("expected at least one identifier class")
+	}
+
+	for _, field := range fields {
+		switch field {
+		case "letter":
+			ic.letters = true
+		case "digit":
+			ic.digits = true
+		default:
+			ic.chars += field
+		}
+	}
+	return ic, nil
+}
+
+/* Determine if the rune belongs to these classes. */
+func (ic identClass) Contains(r rune) bool {
+	return (ic.letters && unicode.IsLetter(r)) ||
+		(ic.digits && unicode.IsDigit(r)) ||
+		strings.ContainsRune(ic.chars, r)
+}
+
+/*
+Find where a generic identifier at the start of
+the line must end, per the grammar's identifier
+classes. At least one character is always
+included so unknown characters still make
+progress.
+*/
+func (g *Grammar) identEnd(line string) int {
+	if g.ident == nil {
+		return len(line)
+	}
+	for i, r := range line {
+		if g.ident.Contains(r) {
+			continue
+		}
+		if i == 0 {
+			_, size := utf8.DecodeRuneInString(line)
+			return size
+		}
+		return i
+	}
+	return len(line)
+}
+
+// Grammar loaded from the tokens file at init.
+var defaultGrammar = &Grammar{
+	Name:         "panza",
+	Extensions:   []string{".pz"},
+	Interpreters: []string{"panza"},
+}
+
+/* Retrieve the grammar loaded from the tokens file. */
+func DefaultGrammar() *Grammar {
+	return defaultGrammar
+}
+
+// Grammars available for resolution, in order of
+// registration.
+var grammars = []*Grammar{defaultGrammar}
+
+// Guards `grammars`.
+var grammarsMu sync.RWMutex
+
+/*
+Make a grammar available to `ResolveGrammar`.
+Grammars registered earlier take precedence.
+*/
+func RegisterGrammar(g *Grammar) {
+	grammarsMu.Lock()
+	defer grammarsMu.Unlock()
+
+	grammars = append(grammars, g)
+}
+
+/*
+Identify the interpreter named on a `#!` line,
+looking through `env` if it is used.
+Returns an empty string if none found.
+*/
+func shebangInterpreter(line string) string {
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+	fields := strings.Fields(line[2:])
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interp := path.Base(fields[0])
+	if interp != "env" {
+		return interp
+	}
+	for _, field := range fields[1:] {
+		if !strings.HasPrefix(field, "-") {
+			return path.Base(field)
+		}
+	}
+	return ""
+}
+
+/* Determine if the given list contains `s`. */
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Pick a registered grammar for the named input.
+A `#!` first line takes precedence over the
+file extension.
+*/
+func ResolveGrammar(name string, firstLine string) (*Grammar, bool) {
+	grammarsMu.RLock()
+	defer grammarsMu.RUnlock()
+
+	if interp := shebangInterpreter(firstLine); interp != "" {
+		for _, g := range grammars {
+			if containsString(g.Interpreters, interp) {
+				return g, true
+			}
+		}
+	}
+
+	ext := filepath.Ext(name)
+	if ext == "" {
+		return nil, false
+	}
+	for _, g := range grammars {
+		if containsString(g.Extensions, ext) {
+			return g, true
+		}
+	}
+	return nil, false
+}
+
+/* Read the first line from `r`. */
+func readFirstLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err == io.EOF {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+/*
+Pick a registered grammar for the named file,
+falling back on the default grammar if none
+match.
+*/
+func ResolveGrammarFile(name string) (*Grammar, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	firstLine, err := readFirstLine(file)
+	if err != nil {
+		return nil, err
+	}
+	if g, ok := ResolveGrammar(name, firstLine); ok {
+		return g, nil
+	}
+	return defaultGrammar, nil
+}
@@ -0,0 +1,166 @@
+package lexer_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestResolveGrammar(t *testing.T) {
+	g := lexer.NewGrammar("calc")
+	g.Extensions = []string{".calc"}
+	g.Interpreters = []string{"calc"}
+	g.AddKind("PLUS", "+", "Addition.")
+	lexer.RegisterGrammar(g)
+
+	cases := []struct {
+		name      string
+		firstLine string
+		expected  *lexer.Grammar
+	}{
+		{"main.pz", "", lexer.DefaultGrammar()},
+		{"sum.calc", "", g},
+		{"script", "#!/usr/bin/env -S calc -q", g},
+		{"script.pz", "#!/usr/local/bin/calc", g},
+	}
+	for _, c := range cases {
+		found, ok := lexer.ResolveGrammar(c.name, c.firstLine)
+		if !ok || found != c.expected {
+			t.Errorf("%s: expected grammar %q, got %v", c.name, c.expected.Name, found)
+		}
+	}
+
+	if _, ok := lexer.ResolveGrammar("notes.txt", "#!/bin/sh"); ok {
+		t.Error("expected no grammar for notes.txt")
+	}
+
+	tokens := g.TokenizeLine("+", 1)
+	if len(tokens) != 1 || tokens[0].Kind.Name != "PLUS" {
+		t.Errorf("unexpected tokens %v", tokens)
+	}
+}
+
+func TestConcurrentTokenize(t *testing.T) {
+	g := lexer.NewGrammar("concurrent")
+	g.AddKind("PLUS", "+", "")
+	lines := []string{"+ +", "\t+", " \t "}
+	expected := g.TokenizeLines(lines)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				found := g.TokenizeLines(lines)
+				if len(found) != len(expected) {
+					t.Errorf("expected %d tokens, got %d", len(expected), len(found))
+					return
+				}
+			}
+		}()
+	}
+
+	// Lookups and additions may run alongside.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		g.AddKind("ARROW", "->", "")
+		g.LookupKind("PLUS")
+	}()
+	wg.Wait()
+}
+
+func symbolsOf(tokens []lexer.TokenObject) []string {
+	symbols := []string{}
+	for _, to := range tokens {
+		symbols = append(symbols, string(to.Symbol))
+	}
+	return symbols
+}
+
+func TestIdentClasses(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ident.tokens": {Data: []byte("@ident letter digit _\nPLUS +\n")},
+		"bad.tokens":   {Data: []byte("@bogus\n")},
+	}
+	g := lexer.NewGrammar("ident")
+	if err := g.LoadTokensFS(fsys, "ident.tokens"); err != nil {
+		t.Fatal(err)
+	}
+
+	found := fmt.Sprint(symbolsOf(g.TokenizeLine("a_1$b c", 1)))
+	if found != "[a_1 $ b   c]" {
+		t.Errorf("unexpected symbols %s", found)
+	}
+
+	g.SetIdentClasses()
+	found = fmt.Sprint(symbolsOf(g.TokenizeLine("a_1$b c", 1)))
+	if found != "[a_1$b   c]" {
+		t.Errorf("unexpected symbols without classes %s", found)
+	}
+
+	if err := g.LoadTokensFS(fsys, "bad.tokens"); err == nil {
+		t.Error("expected an error for an unknown directive")
+	}
+}
+
+func TestReservedIds(t *testing.T) {
+	g := lexer.NewGrammar("reserved")
+	first := g.AddKind("PLUS", "+", "")
+	second := g.AddKind("MINUS", "-", "")
+	if first.Id != lexer.FirstUserKindId || second.Id != lexer.FirstUserKindId+1 {
+		t.Errorf("expected user kinds from %d, got %d and %d", lexer.FirstUserKindId, first.Id, second.Id)
+	}
+
+	kind, _ := g.LookupKind("TABLINE")
+	if kind.Id != lexer.TablineId {
+		t.Errorf("expected TABLINE to have ID %d, got %d", lexer.TablineId, kind.Id)
+	}
+}
+
+func TestWhenFlags(t *testing.T) {
+	fsys := fstest.MapFS{
+		"flags.tokens": {Data: []byte(strings.Join([]string{
+			"PLUS +",
+			"@when experimental #: staged syntax",
+			"PIPE |>",
+			"@end",
+			"@when !experimental",
+			"PIPE |",
+			"@end",
+		}, "\n"))},
+		"open.tokens":   {Data: []byte("@when x\nPLUS +\n")},
+		"end.tokens":    {Data: []byte("@end\n")},
+		"nested.tokens": {Data: []byte("@when x\n@when y\n@end\n@end\n")},
+	}
+
+	g := lexer.NewGrammar("x")
+	if err := g.LoadTokensFS(fsys, "flags.tokens"); err != nil {
+		t.Fatal(err)
+	}
+	if pipe, ok := g.LookupKind("PIPE"); !ok || pipe.Signature.String() != "|" {
+		t.Errorf("expected stable PIPE without flags, got %v", pipe)
+	}
+
+	g.Flags = []string{"experimental"}
+	if err := g.LoadTokensFS(fsys, "flags.tokens"); err != nil {
+		t.Fatal(err)
+	}
+	if pipe, ok := g.LookupKind("PIPE"); !ok || pipe.Signature.String() != "|>" {
+		t.Errorf("expected experimental PIPE with flag, got %v", pipe)
+	}
+	if _, ok := g.LookupKind("PLUS"); !ok {
+		t.Error("expected unguarded PLUS")
+	}
+
+	for _, name := range []string{"open.tokens", "end.tokens", "nested.tokens"} {
+		if err := g.LoadTokensFS(fsys, name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
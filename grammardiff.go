@@ -0,0 +1,138 @@
+package lexer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+/* --- GRAMMAR DIFFS ---
+What changed between two versions of a grammar,
+kind by kind, for reviewing changes to tokens
+files. Kinds are paired up by name; kinds left
+over are paired up by signature as renamed, and
+the rest are added or removed. Kinds whose IDs
+changed are reported too, as tokens stored with
+their IDs no longer read back as the same kinds.
+Only kinds defined by tokens files are compared. */
+
+/* A kind of the old grammar, and what it became in the new. */
+type KindChange struct {
+	Old TokenKind
+	New TokenKind
+}
+
+/* What changed between two grammars. */
+type GrammarDiff struct {
+	Added        []TokenKind  // Kinds of only the new grammar.
+	Removed      []TokenKind  // Kinds of only the old grammar.
+	Renamed      []KindChange // Kinds of the same signature under a new name.
+	Resignatured []KindChange // Kinds of the same name with a new signature.
+	Renumbered   []KindChange // Kinds of both grammars whose IDs changed.
+}
+
+/* Kinds of the grammar defined by tokens files, in order of definition. */
+func (g *Grammar) userKinds() []TokenKind {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	kinds := []TokenKind{}
+	for _, id := range g.kinds.Ids() {
+		if id >= FirstUserKindId {
+			kinds = append(kinds, g.kinds.Get(id))
+		}
+	}
+	return kinds
+}
+
+/* Compare the kinds of two versions of a grammar. */
+func DiffGrammars(old, new *Grammar) GrammarDiff {
+	var diff GrammarDiff
+	oldKinds, newKinds := old.userKinds(), new.userKinds()
+
+	byName := map[tokenName]int{}
+	for i, kind := range newKinds {
+		if _, ok := byName[kind.Name]; !ok {
+			byName[kind.Name] = i
+		}
+	}
+	paired := make([]bool, len(newKinds))
+	var unpaired []TokenKind
+	for _, kind := range oldKinds {
+		i, ok := byName[kind.Name]
+		if !ok || paired[i] {
+			unpaired = append(unpaired, kind)
+			continue
+		}
+		paired[i] = true
+		change := KindChange{kind, newKinds[i]}
+		if change.Old.Signature.String() != change.New.Signature.String() {
+			diff.Resignatured = append(diff.Resignatured, change)
+		}
+		if change.Old.Id != change.New.Id {
+			diff.Renumbered = append(diff.Renumbered, change)
+		}
+	}
+
+	for _, kind := range unpaired {
+		i := unpairedBySignature(newKinds, paired, kind.Signature)
+		if i < 0 {
+			diff.Removed = append(diff.Removed, kind)
+			continue
+		}
+		paired[i] = true
+		change := KindChange{kind, newKinds[i]}
+		diff.Renamed = append(diff.Renamed, change)
+		if change.Old.Id != change.New.Id {
+			diff.Renumbered = append(diff.Renumbered, change)
+		}
+	}
+	for i, kind := range newKinds {
+		if !paired[i] {
+			diff.Added = append(diff.Added, kind)
+		}
+	}
+	return diff
+}
+
+/* Index of the first kind not yet paired with the signature; -1 if none. */
+func unpairedBySignature(kinds []TokenKind, paired []bool, sig tokenSignature) int {
+	for i, kind := range kinds {
+		if !paired[i] && kind.Signature.String() == sig.String() {
+			return i
+		}
+	}
+	return -1
+}
+
+/* Determine if the grammars define the same kinds. */
+func (gd GrammarDiff) Empty() bool {
+	return len(gd.Added) == 0 && len(gd.Removed) == 0 && len(gd.Renamed) == 0 &&
+		len(gd.Resignatured) == 0 && len(gd.Renumbered) == 0
+}
+
+/* Write the diff as text, a line per change. */
+func (gd GrammarDiff) Format(w io.Writer) error {
+	var b strings.Builder
+	for _, kind := range gd.Added {
+		fmt.Fprintf(&b, "+ %s %q [%d]\n", kind.Name, kind.Signature.String(), kind.Id)
+	}
+	for _, kind := range gd.Removed {
+		fmt.Fprintf(&b, "- %s %q [%d]\n", kind.Name, kind.Signature.String(), kind.Id)
+	}
+	for _, c := range gd.Renamed {
+		fmt.Fprintf(&b, "~ %s renamed %s %q\n", c.Old.Name, c.New.Name, c.New.Signature.String())
+	}
+	for _, c := range gd.Resignatured {
+		fmt.Fprintf(&b, "~ %s signature %q -> %q\n", c.New.Name, c.Old.Signature.String(), c.New.Signature.String())
+	}
+	for _, c := range gd.Renumbered {
+		fmt.Fprintf(&b, "! %s id %d -> %d\n", c.New.Name, c.Old.Id, c.New.Id)
+	}
+	if len(gd.Renumbered) > 0 {
+		fmt.Fprintf(&b, "ids changed: %d; tokens stored by id must be tokenized again\n", len(gd.Renumbered))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,42 @@
+package lexer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestDiffGrammars(t *testing.T) {
+	old := lexer.NewGrammar("old")
+	if err := old.LoadTokens(strings.NewReader("PLUS +\nMINUS -\nSTAR *\nEQ ==\n")); err != nil {
+		t.Fatal(err)
+	}
+	new := lexer.NewGrammar("new")
+	if err := new.LoadTokens(strings.NewReader("PLUS +\nSUB -\nEQ ===\nSLASH /\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	diff := lexer.DiffGrammars(old, new)
+	if diff.Empty() {
+		t.Fatal("expected the grammars to differ")
+	}
+	var buf bytes.Buffer
+	if err := diff.Format(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "+ SLASH \"/\" [259]\n" +
+		"- STAR \"*\" [258]\n" +
+		"~ MINUS renamed SUB \"-\"\n" +
+		"~ EQ signature \"==\" -> \"===\"\n" +
+		"! EQ id 259 -> 258\n" +
+		"ids changed: 1; tokens stored by id must be tokenized again\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	if diff := lexer.DiffGrammars(old, old); !diff.Empty() {
+		t.Errorf("expected no changes of a grammar against itself, got %+v", diff)
+	}
+}
@@ -0,0 +1,228 @@
+package lexer
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+/* --- COLUMN UNITS ---
+Token positions count columns in bytes, which is
+what slicing the input needs, but not what an
+editor shows: a caret after `é` spelled with a
+combining accent, or after a flag emoji, is one
+column on, not three or eight. Columns may be
+converted for display to other units:
+
+bytes: Bytes of UTF-8, as tokens have them.
+runes: Unicode code points.
+graphemes: User perceived characters, as of the
+extended grapheme clusters of Unicode text
+segmentation (UAX #29).
+
+Grapheme clusters are found with the rules that
+matter in source text: CR LF, combining marks,
+zero width joiner sequences, variation
+selectors, emoji modifiers, flags of regional
+indicator pairs and Hangul syllables. Prepended
+characters are not joined. */
+
+/* The unit columns are counted in. */
+type ColumnUnit uint8
+
+const (
+	ColumnBytes ColumnUnit = iota
+	ColumnRunes
+	ColumnGraphemes
+)
+
+var columnUnitNames = []string{"bytes", "runes", "graphemes"}
+
+func (cu ColumnUnit) String() string {
+	if int(cu) < len(columnUnitNames) {
+		return columnUnitNames[cu]
+	}
+	return fmt.Sprintf("ColumnUnit(%d)", uint8(cu))
+}
+
+/* Look up a column unit by its name, e.g. `graphemes`. */
+func ParseColumnUnit(name string) (ColumnUnit, error) {
+	for i, known := range columnUnitNames {
+		if name == known {
+			return ColumnUnit(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown column unit %q, expected bytes, runes or graphemes", name)
+}
+
+/*
+Convert a column of `line` counted in bytes, as
+tokens count them, to the given unit. Columns
+are counted from 1; a column past the end of the
+line counts the bytes past it as one column
+each.
+*/
+func ConvertColumn(line string, column int, unit ColumnUnit) int {
+	if column < 1 || unit == ColumnBytes {
+		return column
+	}
+	before, past := column-1, 0
+	if before > len(line) {
+		before, past = len(line), before-len(line)
+	}
+	if unit == ColumnRunes {
+		return utf8.RuneCountInString(line[:before]) + past + 1
+	}
+	return graphemeCount(line[:before]) + past + 1
+}
+
+/*
+Copy of the tokens with their positions counted
+in the given unit, per their lines of `src`, for
+display. Tokens whose lines `src` lacks are left
+as they are. Positions so converted no longer
+index into the input.
+*/
+func (tom TokenObjects) InColumns(src LineSource, unit ColumnUnit) TokenObjects {
+	converted := make(TokenObjects, len(tom))
+	copy(converted, tom)
+	if unit == ColumnBytes {
+		return converted
+	}
+	for i, to := range converted {
+		if line, ok := src.Text(to.LineNo); ok {
+			converted[i].Position = tokenPosition(ConvertColumn(line, int(to.Position), unit))
+		}
+	}
+	return converted
+}
+
+/* Number of grapheme clusters of `s`. */
+func graphemeCount(s string) int {
+	n := 0
+	for s != "" {
+		s = s[graphemeLen(s):]
+		n++
+	}
+	return n
+}
+
+/* Length in bytes of the grapheme cluster `s` starts with. */
+func graphemeLen(s string) int {
+	prev, end := utf8.DecodeRuneInString(s)
+	if prev == '\r' && end < len(s) && s[end] == '\n' {
+		return end + 1
+	}
+	if isControlRune(prev) {
+		return end
+	}
+	pictographic := isPictographic(prev)
+	regional := 0
+	if isRegionalIndicator(prev) {
+		regional = 1
+	}
+
+	for end < len(s) {
+		r, size := utf8.DecodeRuneInString(s[end:])
+		switch {
+		case isControlRune(r):
+			return end
+		case isExtendRune(r):
+		case prev == zeroWidthJoiner && pictographic && isPictographic(r):
+		case regional == 1 && isRegionalIndicator(r):
+			regional = 2
+		case joinsHangul(prev, r):
+		default:
+			return end
+		}
+		pictographic = pictographic || isPictographic(r)
+		prev = r
+		end += size
+	}
+	return end
+}
+
+const zeroWidthJoiner = '\u200d'
+
+/* Determine if a rune always stands alone: line breaks and other controls. */
+func isControlRune(r rune) bool {
+	return unicode.IsControl(r) || r == '\u2028' || r == '\u2029'
+}
+
+/*
+Determine if a rune extends the cluster before
+it: combining marks, joiners, variation
+selectors, emoji modifiers and tags.
+*/
+func isExtendRune(r rune) bool {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r == zeroWidthJoiner || r == '\u200c':
+		return true
+	case r >= 0xfe00 && r <= 0xfe0f, r >= 0xe0100 && r <= 0xe01ef:
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff, r >= 0xe0020 && r <= 0xe007f:
+		return true
+	}
+	return false
+}
+
+/* Determine if a rune is a flag letter. */
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+/* Determine if a rune is an emoji or other pictograph, joined by ZWJ. */
+func isPictographic(r rune) bool {
+	switch {
+	case r >= 0x1f000 && r <= 0x1faff:
+		return true
+	case r >= 0x2600 && r <= 0x27bf, r >= 0x2300 && r <= 0x23ff:
+		return true
+	case r == 0x00a9 || r == 0x00ae || r == 0x203c || r == 0x2049 || r == 0x2122:
+		return true
+	}
+	return false
+}
+
+/* Hangul syllable parts: leading consonants, vowels and trailing consonants. */
+const (
+	hangulNone = iota
+	hangulL
+	hangulV
+	hangulT
+	hangulLV
+	hangulLVT
+)
+
+/* The Hangul syllable part a rune is. */
+func hangulType(r rune) int {
+	switch {
+	case r >= 0x1100 && r <= 0x115f, r >= 0xa960 && r <= 0xa97c:
+		return hangulL
+	case r >= 0x1160 && r <= 0x11a7, r >= 0xd7b0 && r <= 0xd7c6:
+		return hangulV
+	case r >= 0x11a8 && r <= 0x11ff, r >= 0xd7cb && r <= 0xd7fb:
+		return hangulT
+	case r >= 0xac00 && r <= 0xd7a3:
+		if (r-0xac00)%28 == 0 {
+			return hangulLV
+		}
+		return hangulLVT
+	}
+	return hangulNone
+}
+
+/* Determine if two Hangul runes are parts of one syllable. */
+func joinsHangul(prev, r rune) bool {
+	switch p, n := hangulType(prev), hangulType(r); p {
+	case hangulL:
+		return n == hangulL || n == hangulV || n == hangulLV || n == hangulLVT
+	case hangulV, hangulLV:
+		return n == hangulV || n == hangulT
+	case hangulT, hangulLVT:
+		return n == hangulT
+	}
+	return false
+}
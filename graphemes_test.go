@@ -0,0 +1,51 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestConvertColumn(t *testing.T) {
+	cases := []struct {
+		line      string
+		column    int
+		runes     int
+		graphemes int
+	}{
+		{"abc", 3, 3, 3},
+		{"e\u0301x", 4, 3, 2},                                   // Combining accent.
+		{"\U0001F1EF\U0001F1F5x", 9, 3, 2},                      // Flag of a regional indicator pair.
+		{"\U0001F469\u200d\U0001F4BBx", 12, 4, 2},               // Zero width joiner sequence.
+		{"\U0001F44D\U0001F3FDx", 9, 3, 2},                      // Emoji modifier.
+		{"\u2764\ufe0fx", 7, 3, 2},                              // Variation selector.
+		{"\u1100\u1161\u11a8x", 10, 4, 2},                       // Hangul jamo.
+		{"\r\nx", 3, 3, 2},                                      // CR LF.
+		{"\U0001F1EF\U0001F1F5\U0001F1FA\U0001F1F8x", 17, 5, 3}, // Two flags.
+		{"ab", 5, 5, 5},                                         // Past the end.
+	}
+	for _, c := range cases {
+		if found := lexer.ConvertColumn(c.line, c.column, lexer.ColumnBytes); found != c.column {
+			t.Errorf("%q: expected bytes unchanged, got %d", c.line, found)
+		}
+		if found := lexer.ConvertColumn(c.line, c.column, lexer.ColumnRunes); found != c.runes {
+			t.Errorf("%q: expected rune column %d, got %d", c.line, c.runes, found)
+		}
+		if found := lexer.ConvertColumn(c.line, c.column, lexer.ColumnGraphemes); found != c.graphemes {
+			t.Errorf("%q: expected grapheme column %d, got %d", c.line, c.graphemes, found)
+		}
+	}
+
+	g := lexer.NewGrammar("columns")
+	g.AddKind("PLUS", "+", "")
+	text := "e\u0301+b\n"
+	tokens := g.TokenizeLine("e\u0301+b", 1)
+	converted := tokens.InColumns(lexer.NewSourceLines(text), lexer.ColumnGraphemes)
+	if converted[1].Position != 2 || converted[2].Position != 3 || tokens[1].Position != 4 {
+		t.Errorf("expected positions 2 and 3 in a copy, got %v", converted)
+	}
+
+	if unit, err := lexer.ParseColumnUnit("graphemes"); err != nil || unit != lexer.ColumnGraphemes {
+		t.Errorf("expected graphemes, got %s, %v", unit, err)
+	}
+}
@@ -0,0 +1,119 @@
+package lexer
+
+import "sort"
+
+/* --- GROUPING RESULTS ---
+Results of many files, as `TokenizeFiles` gives
+them, are kept by path in a map, which Go ranges
+over in no particular order. A `ResultSet` holds
+them in order of path instead, and groups their
+tokens by file, by kind, or by range of lines,
+always in the same order for the same results,
+so reports built from them are reproducible. */
+
+/* Results of many files, in order of source name. */
+type ResultSet []TokenResult
+
+/*
+Order results given by path. Results naming no
+source are named by their path.
+*/
+func NewResultSet(results map[string]TokenResult) ResultSet {
+	rs := make(ResultSet, 0, len(results))
+	for path, tr := range results {
+		if tr.Source == "" {
+			tr.Source = path
+		}
+		rs = append(rs, tr)
+	}
+	sort.SliceStable(rs, func(i, j int) bool { return rs[i].Source < rs[j].Source })
+	return rs
+}
+
+/* Tokens of a file. */
+type FileGroup struct {
+	Source string
+	Tokens TokenObjects
+}
+
+/* A token, and the file it is of. */
+type FileToken struct {
+	Source string
+	Token  TokenObject
+}
+
+/* Tokens of a kind, across files. */
+type KindGroup struct {
+	Kind   string
+	Tokens []FileToken // In order of file, then position.
+}
+
+/* Tokens of a range of lines of a file. */
+type LineGroup struct {
+	Source    string
+	StartLine int // First line of the range.
+	EndLine   int // Last line of the range, inclusive.
+	Tokens    TokenObjects
+}
+
+/* Group tokens by file, in order of source name. */
+func (rs ResultSet) ByFile() []FileGroup {
+	groups := make([]FileGroup, len(rs))
+	for i, tr := range rs {
+		groups[i] = FileGroup{tr.Source, tr.Tokens}
+	}
+	return groups
+}
+
+/*
+Group tokens by kind, in order of kind name.
+Kinds of no token are left out.
+*/
+func (rs ResultSet) ByKind() []KindGroup {
+	found := map[string]int{} // Index of the group by kind name.
+	groups := []KindGroup{}
+	for _, tr := range rs {
+		for _, to := range tr.Tokens {
+			if to.Kind == nil {
+				continue
+			}
+			name := string(to.Kind.Name)
+			i, ok := found[name]
+			if !ok {
+				i = len(groups)
+				found[name] = i
+				groups = append(groups, KindGroup{Kind: name})
+			}
+			groups[i].Tokens = append(groups[i].Tokens, FileToken{tr.Source, to})
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Kind < groups[j].Kind })
+	return groups
+}
+
+/*
+Group tokens by ranges of so many lines of each
+file, lines 1 to `lines` first, in order of
+source name, then line. Ranges of no token are
+left out.
+*/
+func (rs ResultSet) ByLineRange(lines int) []LineGroup {
+	if lines < 1 {
+		lines = 1
+	}
+	groups := []LineGroup{}
+	for _, tr := range rs {
+		for start := 0; start < len(tr.Tokens); {
+			first := (int(tr.Tokens[start].LineNo)-1)/lines*lines + 1
+			group := LineGroup{Source: tr.Source, StartLine: first, EndLine: first + lines - 1}
+			end := start
+			for end < len(tr.Tokens) && int(tr.Tokens[end].LineNo) <= group.EndLine {
+				end++
+			}
+			group.Tokens = tr.Tokens[start:end]
+			groups = append(groups, group)
+			start = end
+		}
+	}
+	return groups
+}
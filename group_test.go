@@ -0,0 +1,50 @@
+package lexer_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestResultSet(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	// Each in a directory named after the last, so in order of path.
+	a, b := writeSource(t, "c+d\n\n\ne\n"), writeSource(t, "a+b\n")
+	results, err := g.TokenizeFiles([]string{b, a}, lexer.BatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := lexer.NewResultSet(results)
+
+	var files []string
+	for _, group := range rs.ByFile() {
+		files = append(files, group.Source)
+	}
+	if !reflect.DeepEqual(files, []string{a, b}) {
+		t.Errorf("expected files in order of path, got %v", files)
+	}
+
+	kinds := rs.ByKind()
+	if len(kinds) < 2 || kinds[0].Kind > kinds[1].Kind {
+		t.Fatalf("expected kinds in order of name, got %v", kinds)
+	}
+	for _, group := range kinds {
+		if group.Kind != "PLUS" {
+			continue
+		}
+		if len(group.Tokens) != 2 || group.Tokens[0].Source != a || group.Tokens[1].Source != b {
+			t.Errorf("expected a PLUS of each file in order, got %v", group.Tokens)
+		}
+	}
+
+	var ranges [][3]interface{}
+	for _, group := range rs.ByLineRange(2) {
+		ranges = append(ranges, [3]interface{}{group.Source, group.StartLine, len(group.Tokens)})
+	}
+	want := [][3]interface{}{{a, 1, 3}, {a, 3, 1}, {b, 1, 3}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("expected %v, got %v", want, ranges)
+	}
+}
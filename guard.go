@@ -0,0 +1,126 @@
+package lexer
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+/* --- STEP GUARD ---
+Matching a generic identifier tries the kinds
+starting at each of its characters, to find
+where the next token begins, so pathological
+input or grammars can take a great many steps at
+a single position. A lexer given `MaxSteps`
+gives up matching at a position once so many
+kinds were tried there: what was scanned so far
+becomes a token of the fallback kind, with a
+diagnostic naming the input and the kinds tried,
+and tokenizing goes on after it. A strict lexer
+fails with a `StepLimitError` instead. */
+
+// Most characters of input quoted by a step limit diagnostic.
+const stepExcerptSize = 16
+
+/* Counts the kinds tried at a position, against the most allowed. */
+type stepGuard struct {
+	max   int // No limit if zero or less.
+	steps int
+}
+
+/* Count `n` more kinds tried. Returns false once past the limit. */
+func (sg *stepGuard) take(n int) bool {
+	sg.steps += n
+	return sg.max <= 0 || sg.steps <= sg.max
+}
+
+/* Matching that gave up at a position, found by a strict lexer. */
+type StepLimitError struct {
+	Filename   string // Name of the source, if known.
+	LineNo     tokenLineNo
+	Position   tokenPosition
+	Steps      int      // Kinds tried before giving up.
+	Excerpt    string   // Input at the position.
+	Candidates []string // Kinds starting at the position.
+}
+
+/* Where matching gave up. */
+func (se StepLimitError) Pos() Position {
+	return Position{Filename: se.Filename, Offset: -1, Line: int(se.LineNo), Column: int(se.Position)}
+}
+
+func (se StepLimitError) Error() string {
+	return fmt.Sprintf("%s: %s", se.Pos(), se.message())
+}
+
+func (se StepLimitError) message() string {
+	return fmt.Sprintf("matching gave up after %d steps at %q; candidates %s",
+		se.Steps, se.Excerpt, strings.Join(se.Candidates, ", "))
+}
+
+/*
+Describe matching giving up at the start of
+`line`: the input there, and the kinds that
+could start at it.
+*/
+func (g *Grammar) stepLimit(line string, lineNo tokenLineNo, pos tokenPosition, steps int) StepLimitError {
+	excerpt, runes := line, 0
+	for i := range line {
+		if runes == stepExcerptSize {
+			excerpt = line[:i]
+			break
+		}
+		runes += 1
+	}
+	candidates := []string{}
+	for _, ik := range g.kinds.Starting(line[0]) {
+		candidates = append(candidates, string(g.kinds.Ref(ik.id).Name))
+	}
+	var keywords []string
+	for word, ids := range g.kinds.keywords.ids {
+		if word[0] == line[0] {
+			for _, id := range ids {
+				keywords = append(keywords, string(g.kinds.Ref(id).Name))
+			}
+		}
+	}
+	sort.Strings(keywords)
+	candidates = append(candidates, keywords...)
+	candidates = append(candidates, string(g.kinds.Ref(GenIdenId).Name))
+	return StepLimitError{LineNo: lineNo, Position: pos, Steps: steps, Excerpt: excerpt, Candidates: candidates}
+}
+
+/*
+Number of kinds tried matching input starting
+with the byte: those of signatures starting with
+it, and a keyword length per length of keywords.
+*/
+func (g *Grammar) stepsAt(b byte) int {
+	return len(g.kinds.Starting(b)) + bits.OnesCount64(g.kinds.keywords.sizes[b])
+}
+
+/*
+Identify a generic token as `findIdenToken`
+does, counting the kinds tried against the
+guard. Returns false, and the token scanned so
+far, if the guard's limit is passed.
+*/
+func (g *Grammar) scanIdenToken(line string, guard *stepGuard) (string, bool) {
+	c := NewCursor(line).AdvanceRune()
+	if !guard.take(g.stepsAt(line[0])) {
+		return c.Passed(), false
+	}
+	limit := g.identEnd(line)
+
+	for c.Offset() < limit {
+		if !guard.take(g.stepsAt(c.Rest()[0])) {
+			return c.Passed(), false
+		}
+		if g.isToken(c.Rest()) {
+			break
+		}
+		c = c.AdvanceRune()
+	}
+	return c.Passed(), true
+}
@@ -0,0 +1,50 @@
+package lexer_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestStepGuard(t *testing.T) {
+	g := lexer.NewGrammar("guard")
+	g.AddKind("PLUS", "+", "")
+	g.AddKind("AND", "and", "")
+	g.AddKind("ASSERT", "assert", "")
+	text := strings.Repeat("a", 40) + "+b\n"
+
+	tokens, err := (&lexer.Lexer{Grammar: g, MaxSteps: 100}).TokenizeReader(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := renderKinds(tokens); got != "GENIDEN:"+strings.Repeat("a", 40)+" PLUS:+ GENIDEN:b" {
+		t.Errorf("expected a generous limit to change nothing, got %s", got)
+	}
+
+	diags := &lexer.Diagnostics{}
+	lx := &lexer.Lexer{Grammar: g, MaxSteps: 20, Fallback: "ERROR", Diagnostics: diags}
+	tokens, err = lx.TokenizeReader(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := renderKinds(tokens); got != "ERROR:"+strings.Repeat("a", 10)+" ERROR:"+strings.Repeat("a", 10)+
+		" ERROR:"+strings.Repeat("a", 10)+" ERROR:"+strings.Repeat("a", 10)+" PLUS:+ ERROR:b" {
+		t.Errorf("expected input split where matching gave up, got %s", got)
+	}
+	if len(diags.List) != 5 || diags.List[0].Code != lexer.CodeStepLimit || diags.List[4].Code != lexer.CodeUnknownInput {
+		t.Fatalf("expected a diagnostic per give up, got %v", diags.List)
+	}
+	expected := `matching gave up after 22 steps at "aaaaaaaaaaaaaaaa"; candidates AND, ASSERT, GENIDEN`
+	if diags.List[0].Message != expected {
+		t.Errorf("expected %q, got %q", expected, diags.List[0].Message)
+	}
+
+	lx = &lexer.Lexer{Grammar: g, MaxSteps: 20, Strict: true}
+	_, err = lx.TokenizeReader(strings.NewReader(text))
+	var se lexer.StepLimitError
+	if !errors.As(err, &se) || se.Position != 1 || se.Steps != 22 {
+		t.Errorf("expected strict lexing to fail giving up at 1:1, got %v", err)
+	}
+}
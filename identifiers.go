@@ -0,0 +1,71 @@
+package lexer
+
+import "sort"
+
+/* --- IDENTIFIER DICTIONARIES ---
+Editors complete and spell check identifiers from
+those already used. The dictionary of a series
+of tokens holds each distinct identifier, how
+often it is used and where it is first used,
+most used first. Words expected anywhere, such
+as those of a stop-list, may be left out. */
+
+/* An identifier of a dictionary. */
+type IdentifierEntry struct {
+	Symbol string
+	Count  int      // Uses of the identifier.
+	First  Position // Of the first use.
+}
+
+/*
+Build the dictionary of generic identifiers of
+this series, leaving out those of the stop-list.
+Literal values tokenized as identifiers, such as
+numbers, are not identifiers. Entries are in
+order of count, most used first, then symbol.
+*/
+func (tom TokenObjects) Identifiers(stop ...string) []IdentifierEntry {
+	return identifiers(tom, TokenObject.Pos, stop)
+}
+
+/*
+Build the dictionary of generic identifiers of
+the result, leaving out those of the stop-list.
+First uses are positioned within the source.
+*/
+func (tr TokenResult) Identifiers(stop ...string) []IdentifierEntry {
+	return identifiers(tr.Tokens, tr.Position, stop)
+}
+
+func identifiers(tokens TokenObjects, position func(to TokenObject) Position, stop []string) []IdentifierEntry {
+	stopped := map[string]bool{}
+	for _, word := range stop {
+		stopped[word] = true
+	}
+
+	found := map[string]int{} // Index of the entry by symbol.
+	entries := []IdentifierEntry{}
+	for _, to := range tokens {
+		if to.Kind == nil || to.Kind.Id != GenIdenId || to.Value != nil {
+			continue
+		}
+		symbol := string(to.Symbol)
+		if stopped[symbol] {
+			continue
+		}
+		if i, ok := found[symbol]; ok {
+			entries[i].Count++
+			continue
+		}
+		found[symbol] = len(entries)
+		entries = append(entries, IdentifierEntry{symbol, 1, position(to)})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Symbol < entries[j].Symbol
+	})
+	return entries
+}
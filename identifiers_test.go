@@ -0,0 +1,39 @@
+package lexer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestIdentifiers(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	if err := g.SetLiteral("GENIDEN", lexer.LiteralInt); err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.TokenizeSource("a.x", strings.NewReader("b + a + 1\na + self + c\nself + b + a\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := result.Identifiers("self")
+	want := []lexer.IdentifierEntry{
+		{Symbol: "a", Count: 3, First: lexer.Position{Filename: "a.x", Offset: 4, Line: 1, Column: 5}},
+		{Symbol: "b", Count: 2, First: lexer.Position{Filename: "a.x", Offset: 0, Line: 1, Column: 1}},
+		{Symbol: "c", Count: 1, First: lexer.Position{Filename: "a.x", Offset: 21, Line: 2, Column: 12}},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %v, got %v", want, entries)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("expected %v, got %v", want[i], entries[i])
+		}
+	}
+
+	if entries := result.Tokens.Identifiers(); len(entries) != 4 || entries[2].Symbol != "self" || entries[2].First.String() != "2:5" {
+		t.Errorf("unexpected entries %v", entries)
+	}
+}
@@ -0,0 +1,120 @@
+package lexer
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+/* --- INPUT ---
+Input is checked before it is tokenized. Binary
+input, an image or an object file say, would
+otherwise produce thousands of garbage tokens;
+it is rejected with `ErrBinaryInput` instead.
+Lexers serving untrusted callers may cap the
+size of input with `MaxInput` as well.
+
+Input is considered binary if the start of it
+holds a NUL byte, or too much of it is not valid
+UTF-8. */
+
+// Returned when input appears to be binary rather than text.
+var ErrBinaryInput = errors.New("lexer: binary input")
+
+// Returned when input is longer than `Lexer.MaxInput`.
+var ErrInputTooLarge = errors.New("lexer: input too large")
+
+const (
+	binarySniffSize    = 8000 // Bytes looked at to detect binary input.
+	binaryInvalidRatio = 30   // Percentage of invalid UTF-8 runes deemed binary.
+)
+
+/*
+Determine if the given start of input appears to
+be binary. A rune cut off at the end of `head`
+is not counted as invalid.
+*/
+func isBinary(head []byte) bool {
+	var runes, invalid int
+
+	for len(head) > 0 {
+		if head[0] == 0 {
+			return true
+		}
+		r, size := utf8.DecodeRune(head)
+		if r == utf8.RuneError && size == 1 {
+			if !utf8.FullRune(head) {
+				break
+			}
+			invalid += 1
+		}
+		runes += 1
+		head = head[size:]
+	}
+	return invalid*100 > runes*binaryInvalidRatio
+}
+
+/*
+Reads at most `n` bytes of the underlying
+reader, failing with `ErrInputTooLarge` past
+them rather than ending the input early.
+*/
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if lr.n < 0 {
+		return 0, ErrInputTooLarge
+	}
+	if int64(len(p)) > lr.n+1 {
+		p = p[:lr.n+1]
+	}
+	n, err := lr.r.Read(p)
+	if lr.n -= int64(n); lr.n < 0 {
+		return n + int(lr.n), ErrInputTooLarge
+	}
+	return n, err
+}
+
+/*
+Decoder of input read by this lexer: its own,
+after limiting input to `MaxInput` if set.
+*/
+func (lx *Lexer) decoder() Decoder {
+	if lx.MaxInput <= 0 {
+		return lx.Decode
+	}
+	return func(r io.Reader) (io.Reader, error) {
+		r = &limitedReader{r, lx.MaxInput}
+		if lx.Decode != nil {
+			return lx.Decode(r)
+		}
+		return r, nil
+	}
+}
+
+/*
+Wrap `r` in a reader of the same input,
+transcoded with `decode` if given. Returns
+`ErrBinaryInput` if it appears to be binary.
+*/
+func newTextReader(r io.Reader, decode Decoder) (io.Reader, error) {
+	if decode != nil {
+		var err error
+		if r, err = decode(r); err != nil {
+			return nil, err
+		}
+	}
+	br := bufio.NewReaderSize(r, binarySniffSize)
+	head, err := br.Peek(binarySniffSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if isBinary(head) {
+		return nil, ErrBinaryInput
+	}
+	return br, nil
+}
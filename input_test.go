@@ -0,0 +1,82 @@
+package lexer_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestBinaryInput(t *testing.T) {
+	lx := &lexer.Lexer{Grammar: lexer.NewGrammar("x")}
+
+	cases := []struct {
+		name   string
+		input  string
+		binary bool
+	}{
+		{"text", "let x = 1\n", false},
+		{"utf8", "let π = \"héllo\"\n", false},
+		{"nul", "ELF\x00\x01\x02", true},
+		{"invalid", strings.Repeat("\xff\xfe\x80", 100), true},
+		{"sparse invalid", "caf\xe9 au lait\n", false},
+		{"empty", "", false},
+	}
+	for _, c := range cases {
+		_, err := lx.TokenizeReader(strings.NewReader(c.input))
+		if binary := errors.Is(err, lexer.ErrBinaryInput); binary != c.binary {
+			t.Errorf("%s: expected binary %v, got error %v", c.name, c.binary, err)
+		}
+	}
+}
+
+func TestBinaryFile(t *testing.T) {
+	name := writeSource(t, "\x00\x00\x00")
+	lx := &lexer.Lexer{}
+	if _, err := lx.TokenizeFile(name); err != lexer.ErrBinaryInput {
+		t.Errorf("expected ErrBinaryInput, got %v", err)
+	}
+	if _, err := lexer.TokenizeFile(name); err != lexer.ErrBinaryInput {
+		t.Errorf("expected ErrBinaryInput of the default grammar, got %v", err)
+	}
+	if _, err := lexer.NewGrammar("x").TokenizeFile(name); err != lexer.ErrBinaryInput {
+		t.Errorf("expected ErrBinaryInput of a grammar, got %v", err)
+	}
+	if _, err := lexer.TokenizeIndexed(bytes.NewReader([]byte{0x7f, 0x00})); err != lexer.ErrBinaryInput {
+		t.Errorf("expected ErrBinaryInput, got %v", err)
+	}
+
+	// NUL bytes past the start of input are not looked at.
+	text := strings.Repeat("x\n", 8000) + "\x00"
+	if _, err := lx.TokenizeReader(strings.NewReader(text)); err != nil {
+		t.Errorf("expected text input, got %v", err)
+	}
+}
+
+func TestMaxInput(t *testing.T) {
+	lx := &lexer.Lexer{Grammar: lexer.NewGrammar("x"), MaxInput: 16}
+
+	for _, input := range []string{"", "a b\n", strings.Repeat("a", 16)} {
+		if _, err := lx.TokenizeReader(strings.NewReader(input)); err != nil {
+			t.Errorf("expected %d bytes to be read, got %v", len(input), err)
+		}
+	}
+	// Past the sniffed start of input as well.
+	for _, input := range []string{strings.Repeat("a", 17), strings.Repeat("a b\n", 4096)} {
+		if _, err := lx.TokenizeReader(strings.NewReader(input)); !errors.Is(err, lexer.ErrInputTooLarge) {
+			t.Errorf("expected %d bytes to be refused, got %v", len(input), err)
+		}
+	}
+	lx.MaxInput = 1 << 20
+	if _, err := lx.TokenizeSource("big", strings.NewReader(strings.Repeat("a b\n", 1<<19))); !errors.Is(err, lexer.ErrInputTooLarge) {
+		t.Errorf("expected a large source to be refused, got %v", err)
+	}
+
+	name := writeSource(t, strings.Repeat("a", 64))
+	lx = &lexer.Lexer{Grammar: lexer.NewGrammar("x"), MaxInput: 32, Cache: lexer.NewMemoryCache()}
+	if _, err := lx.TokenizeFile(name); !errors.Is(err, lexer.ErrInputTooLarge) {
+		t.Errorf("expected a large file to be refused, got %v", err)
+	}
+}
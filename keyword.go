@@ -0,0 +1,120 @@
+package lexer
+
+import "math/bits"
+
+/* --- KEYWORDS ---
+Kinds whose signatures are words, e.g. `fn` or
+`return`, are kept apart from other kinds, in a
+table keyed by signature. Grammars may declare
+hundreds of keywords, many sharing a first byte;
+rather than comparing input against each of
+them, matching looks up the prefixes of the
+input in the table, only of the lengths of
+keywords starting with its first byte, so the
+cost of finding a keyword does not grow with
+their number. */
+
+// Longest signature kept as a keyword; longer
+// ones are indexed by their first byte.
+const maxKeywordSize = 64
+
+/* Kinds with word signatures, by signature. */
+type keywordIndex struct {
+	ids map[string][]tokenId // In order of ID, and so of when kinds were added.
+
+	// Per first byte, bit n-1 is set if a keyword of n bytes
+	// starts with it. Bits are not cleared on removal.
+	sizes [256]uint64
+}
+
+/*
+Determine if a signature is a word: ASCII
+letters, digits and underscores only.
+*/
+func isKeyword(sig string) bool {
+	if sig == "" || len(sig) > maxKeywordSize {
+		return false
+	}
+	for i := 0; i < len(sig); i++ {
+		if !isWordByte(sig[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+/* Determine if a byte is an ASCII letter, digit or underscore. */
+func isWordByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+/* Index a kind by its signature. */
+func (ki *keywordIndex) add(kind TokenKind) {
+	sig := string(kind.Signature)
+	if ki.ids == nil {
+		ki.ids = map[string][]tokenId{}
+	}
+
+	list := ki.ids[sig]
+	i := len(list)
+	for i > 0 && list[i-1] > kind.Id {
+		i--
+	}
+	list = append(list, 0)
+	copy(list[i+1:], list[i:])
+	list[i] = kind.Id
+	ki.ids[sig] = list
+	ki.sizes[sig[0]] |= 1 << (len(sig) - 1)
+}
+
+/* Drop a kind from the index. */
+func (ki *keywordIndex) remove(kind TokenKind) {
+	sig := string(kind.Signature)
+	list := ki.ids[sig]
+	for i, id := range list {
+		if id == kind.Id {
+			list = append(list[:i:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(ki.ids, sig)
+		return
+	}
+	ki.ids[sig] = list
+}
+
+/*
+Find the kind whose keyword is the longest
+prefix of `line` longer than `size` bytes; of
+kinds sharing it, that added first. Kinds whose
+anchors do not hold are passed over.
+*/
+func (g *Grammar) matchKeyword(line string, size int, atStart bool) (tokenId, int, bool) {
+	if line == "" {
+		return 0, 0, false
+	}
+	ki := g.kinds.keywords
+	sizes := ki.sizes[line[0]]
+	if sizes == 0 {
+		return 0, 0, false
+	}
+
+	// Only the word a keyword could be a prefix
+	// of, up to the longest that might match.
+	end, longest := 1, bits.Len64(sizes)
+	for end < len(line) && end < longest && isWordByte(line[end]) {
+		end++
+	}
+	for n := end; n > size; n-- {
+		if sizes&(1<<(n-1)) == 0 {
+			continue
+		}
+		for _, id := range ki.ids[line[:n]] {
+			if g.anchorHolds(id, line, n, atStart) {
+				return id, n, true
+			}
+		}
+	}
+	return 0, 0, false
+}
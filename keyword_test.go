@@ -0,0 +1,59 @@
+package lexer_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestKeywordMatching(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("IN", "in", "")
+	g.AddKind("INT", "int", "")
+	g.AddKind("INTO", "into", "")
+	g.AddKind("ALSO_IN", "in", "")
+	g.AddKind("INC", "in+", "")
+
+	cases := []struct {
+		line string
+		want string
+	}{
+		{"into", "INTO:into"},
+		{"int x", "INT:int WHTSPACE:  GENIDEN:x"},
+		{"in", "IN:in"},
+		{"in+", "INC:in+"},
+		{"intox", "INTO:into GENIDEN:x"},
+		{"x in", "GENIDEN:x WHTSPACE:  IN:in"},
+	}
+	for _, c := range cases {
+		if got := renderKinds(g.TokenizeLine(c.line, 1)); got != c.want {
+			t.Errorf("%q: expected %s, got %s", c.line, c.want, got)
+		}
+	}
+}
+
+func TestKeywordAnchors(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("LABEL", "label", "")
+	if err := g.SetAnchor("LABEL", lexer.AnchorStart); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := renderKinds(g.TokenizeLine("label label", 1)); got != "LABEL:label WHTSPACE:  GENIDEN:label" {
+		t.Errorf("unexpected tokens %s", got)
+	}
+}
+
+func BenchmarkTokenizeManyKeywords(b *testing.B) {
+	g := lexer.NewGrammar("x")
+	for i := 0; i < 500; i++ {
+		g.AddKind(fmt.Sprintf("KW%d", i), fmt.Sprintf("kw%d", i), "")
+	}
+	line := strings.Repeat("kw499 kw0 ident ", 8)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		g.TokenizeLine(line, 1)
+	}
+}
@@ -0,0 +1,202 @@
+package lexer
+
+import (
+	"fmt"
+	"time"
+)
+
+/* --- LEXERS ---
+A lexer tokenizes input against a grammar with a
+set of options. The tokenizing methods of
+`Grammar` and the package level functions use a
+lexer with no options set. */
+
+/*
+Tokenizes input against `Grammar`, or the
+default grammar if nil.
+*/
+type Lexer struct {
+	Grammar *Grammar
+	Profile *Profile // Accumulates time spent per stage, if set.
+	Decode  Decoder  // Transcodes input to UTF-8, if set.
+
+	// Accumulates time spent matching per kind, if set.
+	Timings *KindTimings
+
+	// Records unknown input, if set, as tokenizing recovers from it.
+	Diagnostics *Diagnostics
+
+	// What becomes of comments; emitted as tokens by default.
+	Comments CommentMode
+
+	// What becomes of line breaks; left out by default.
+	Newlines NewlineMode
+
+	// Classes of whitespace left out of the tokens; none by default.
+	SkipWhitespace WhitespaceClass
+
+	// Keeps tokens of trivia kinds, ignoring the grammar's trivia
+	// policies. See `TriviaPolicy`.
+	KeepTrivia bool
+
+	// Fails on input matching no kind, rather than falling back.
+	Strict bool
+
+	// Most kinds tried matching at any one position; no limit if
+	// zero or less. See `StepLimitError`.
+	MaxSteps int
+
+	// Marker silencing diagnostics on its line, if set;
+	// e.g. `lex-ignore`.
+	Suppress string
+
+	// Name of the kind input matching no kind falls back on;
+	// GENIDEN if empty.
+	Fallback string
+
+	// Normalizes the symbols of generic identifiers, if set;
+	// `NFC` normalizes them to Unicode Normalization Form C.
+	Normalize func(symbol string) string
+
+	// Looked up before tokenizing files, if set.
+	Cache TokenCache
+
+	// Most bytes of input read per source, before decoding;
+	// no limit if zero or less. Larger input fails with
+	// `ErrInputTooLarge`.
+	MaxInput int64
+
+	actions map[tokenName][]TokenAction // Registered with `OnToken`.
+}
+
+/* Lexer with no options set, tokenizing against this grammar. */
+func (g *Grammar) lexer() *Lexer {
+	return &Lexer{Grammar: g}
+}
+
+/* The grammar this lexer tokenizes against. */
+func (lx *Lexer) grammar() *Grammar {
+	if lx.Grammar == nil {
+		return defaultGrammar
+	}
+	return lx.Grammar
+}
+
+/*
+The symbol of a token of the given kind matched
+at `pos` of a line. Generic identifiers are
+normalized, if the lexer is set to. Positions
+still refer to the input as is. The symbol is a
+slice of `src`, the bytes of the line, if given.
+*/
+func (lx *Lexer) symbol(id tokenId, sig string, src []byte, pos tokenPosition) tokenSignature {
+	if id == GenIdenId && lx.Normalize != nil {
+		return tokenSignature(lx.Normalize(sig))
+	}
+	if src != nil {
+		end := int(pos) + len(sig)
+		return src[pos:end:end]
+	}
+	return tokenSignature(sig)
+}
+
+/* Start timing stages into this lexer's profile. */
+func (lx *Lexer) stopwatch() stopwatch {
+	sw := stopwatch{prof: lx.Profile}
+	if sw.prof != nil {
+		sw.last = time.Now()
+	}
+	return sw
+}
+
+/* --- PROFILING ---
+Time spent tokenizing is split into stages:
+reading lines from input, matching kinds against
+the input, resolving the full symbol of generic
+identifiers and building the resulting tokens.
+Timing is opt-in, so lexers without a profile do
+not pay for the clock. */
+
+/*
+Time spent per stage of tokenizing. A profile is
+not safe for use by more than one goroutine at
+a time.
+*/
+type Profile struct {
+	Scanning    time.Duration // Reading lines from input.
+	Matching    time.Duration // Matching kinds against input.
+	Identifiers time.Duration // Resolving generic identifiers.
+	Output      time.Duration // Building the resulting tokens.
+
+	Lines  int // Lines tokenized.
+	Tokens int // Tokens produced.
+}
+
+/* Time spent over all stages. */
+func (p Profile) Total() time.Duration {
+	return p.Scanning + p.Matching + p.Identifiers + p.Output
+}
+
+/* Add the time and counts of another profile to this one. */
+func (p *Profile) add(o Profile) {
+	p.Scanning += o.Scanning
+	p.Matching += o.Matching
+	p.Identifiers += o.Identifiers
+	p.Output += o.Output
+	p.Lines += o.Lines
+	p.Tokens += o.Tokens
+}
+
+func (p Profile) String() string {
+	return fmt.Sprintf(
+		"scanning %s, matching %s, identifiers %s, output %s (total %s, %d lines, %d tokens)",
+		p.Scanning, p.Matching, p.Identifiers, p.Output, p.Total(), p.Lines, p.Tokens)
+}
+
+type stage int
+
+const (
+	stageScanning stage = iota
+	stageMatching
+	stageIdentifiers
+	stageOutput
+)
+
+/*
+Charges the time since the last lap to a stage
+of a profile. Does nothing without a profile.
+*/
+type stopwatch struct {
+	prof *Profile
+	last time.Time
+}
+
+/* Charge the time since the last lap to `s`. */
+func (sw *stopwatch) lap(s stage) {
+	if sw.prof == nil {
+		return
+	}
+	now := time.Now()
+	elapsed := now.Sub(sw.last)
+	sw.last = now
+
+	switch s {
+	case stageScanning:
+		sw.prof.Scanning += elapsed
+	case stageMatching:
+		sw.prof.Matching += elapsed
+	case stageIdentifiers:
+		sw.prof.Identifiers += elapsed
+	case stageOutput:
+		sw.prof.Output += elapsed
+	}
+}
+
+/* Count a tokenized line and its `n` tokens. */
+func (sw *stopwatch) count(n int) {
+	if sw.prof == nil {
+		return
+	}
+	sw.prof.Lines += 1
+	sw.prof.Tokens += n
+}
@@ -0,0 +1,67 @@
+package lexer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestLexerProfile(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "plus")
+	name := writeSource(t, "ab+c\n+\n")
+
+	prof := &lexer.Profile{}
+	lx := &lexer.Lexer{Grammar: g, Profile: prof}
+	tokens, err := lx.TokenizeFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prof.Lines != 2 || prof.Tokens != len(tokens) || prof.Tokens != 4 {
+		t.Errorf("expected 2 lines and 4 tokens, got %+v", *prof)
+	}
+	if prof.Scanning < 0 || prof.Matching < 0 || prof.Identifiers < 0 || prof.Output < 0 {
+		t.Errorf("expected non-negative stage times, got %+v", *prof)
+	}
+	if prof.Total() != prof.Scanning+prof.Matching+prof.Identifiers+prof.Output {
+		t.Errorf("expected total to sum the stages, got %s", prof)
+	}
+}
+
+func TestLexerWithoutProfile(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "plus")
+
+	lx := &lexer.Lexer{Grammar: g}
+	got := symbolsOf(lx.TokenizeLine("a+b", 1))
+	want := symbolsOf(g.TokenizeLine("a+b", 1))
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestLexerNormalize(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "plus")
+
+	// Composes 'e' followed by a combining acute accent.
+	compose := func(symbol string) string {
+		return strings.ReplaceAll(symbol, "e\u0301", "\u00e9")
+	}
+	lx := &lexer.Lexer{Grammar: g, Normalize: compose}
+	tokens := lx.TokenizeLine("cafe\u0301+caf\u00e9", 1)
+
+	if len(tokens) != 3 || tokens[0].Symbol.String() != tokens[2].Symbol.String() {
+		t.Fatalf("expected equal identifiers, got %v", symbolsOf(tokens))
+	}
+	if tokens[1].Position != 7 || tokens[2].Position != 8 {
+		t.Errorf("expected positions of the input as is, got %v", tokens)
+	}
+}
@@ -0,0 +1,103 @@
+package lexer
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+/* --- GRAMMAR LIMITS ---
+Grammars compiled per request, as in the
+playground, come from untrusted callers. An
+enormous definition, or one of a great many
+kinds or very long signatures, would otherwise
+hold up memory and time of every request after
+it. A `GrammarCache` may refuse such grammars
+per its `Limits`, with a `LimitError` naming the
+limit exceeded. */
+
+/* Most a grammar compiled from a definition may hold; no limit if zero. */
+type GrammarLimits struct {
+	MaxDefinition int           // Bytes of the definition.
+	MaxKinds      int           // Kinds defined, guarded or not.
+	MaxSignature  int           // Bytes of any one signature.
+	MaxCompile    time.Duration // Time spent compiling.
+}
+
+/* Returned when a grammar exceeds one of its limits. */
+type LimitError struct {
+	Limit string // Name of the limit, e.g. `MaxKinds`.
+	Max   int64  // Value of the limit.
+}
+
+func (le LimitError) Error() string {
+	return fmt.Sprintf("lexer: grammar exceeds %s of %d", le.Limit, le.Max)
+}
+
+/*
+Reads a definition, failing past its size or
+the deadline of compiling it.
+*/
+type definitionReader struct {
+	r        io.Reader
+	limits   GrammarLimits
+	read     int
+	deadline time.Time
+}
+
+func (dr *definitionReader) Read(p []byte) (int, error) {
+	if err := dr.limits.checkTime(dr.deadline); err != nil {
+		return 0, err
+	}
+	n, err := dr.r.Read(p)
+	if dr.read += n; dr.limits.MaxDefinition > 0 && dr.read > dr.limits.MaxDefinition {
+		return n, LimitError{"MaxDefinition", int64(dr.limits.MaxDefinition)}
+	}
+	return n, err
+}
+
+/* Fail if compiling has run past the deadline, if any. */
+func (gl GrammarLimits) checkTime(deadline time.Time) error {
+	if gl.MaxCompile > 0 && time.Now().After(deadline) {
+		return LimitError{"MaxCompile", int64(gl.MaxCompile)}
+	}
+	return nil
+}
+
+/* Fail if the tokens defined exceed the limits. */
+func (gl GrammarLimits) checkSpec(spec tokenSpec) error {
+	if gl.MaxKinds > 0 && len(spec.defs) > gl.MaxKinds {
+		return LimitError{"MaxKinds", int64(gl.MaxKinds)}
+	}
+	if gl.MaxSignature > 0 {
+		for _, def := range spec.defs {
+			if len(def.sig) > gl.MaxSignature {
+				return LimitError{"MaxSignature", int64(gl.MaxSignature)}
+			}
+		}
+	}
+	return nil
+}
+
+/*
+Replace the loaded tokens with those defined in
+the tokens read from `r`, failing with a
+`LimitError` if they exceed the given limits.
+Loaded tokens are left untouched if they cannot
+be read or exceed the limits.
+*/
+func (g *Grammar) LoadTokensLimited(r io.Reader, limits GrammarLimits) error {
+	deadline := time.Now().Add(limits.MaxCompile)
+	spec, err := readTokenSpec(tokenFile{scanner: newLineScanner(&definitionReader{r: r, limits: limits, deadline: deadline})})
+	if err != nil {
+		return err
+	}
+	if err := limits.checkSpec(spec); err != nil {
+		return err
+	}
+	if err := limits.checkTime(deadline); err != nil {
+		return err
+	}
+	g.setTokens(spec)
+	return nil
+}
@@ -0,0 +1,46 @@
+package lexer_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestGrammarLimits(t *testing.T) {
+	limits := lexer.GrammarLimits{MaxDefinition: 64, MaxKinds: 2, MaxSignature: 4, MaxCompile: time.Minute}
+	cases := []struct {
+		definition string
+		limit      string
+	}{
+		{"PLUS +\nMINUS -\n", ""},
+		{"PLUS +\nMINUS -\nSTAR *\n", "MaxKinds"},
+		{"ARROW ---->\n", "MaxSignature"},
+		{strings.Repeat("#: padding\n", 8), "MaxDefinition"},
+	}
+	for _, c := range cases {
+		g := lexer.NewGrammar("x")
+		err := g.LoadTokensLimited(strings.NewReader(c.definition), limits)
+		var le lexer.LimitError
+		if c.limit == "" && err != nil {
+			t.Errorf("expected %q to compile, got %v", c.definition, err)
+		}
+		if c.limit != "" && (!errors.As(err, &le) || le.Limit != c.limit) {
+			t.Errorf("expected %q to exceed %s, got %v", c.definition, c.limit, err)
+		}
+	}
+
+	limits.MaxCompile = time.Nanosecond
+	err := lexer.NewGrammar("x").LoadTokensLimited(strings.NewReader("PLUS +\n"), limits)
+	if le, ok := err.(lexer.LimitError); !ok || le.Limit != "MaxCompile" {
+		t.Errorf("expected compiling to time out, got %v", err)
+	}
+
+	cache := lexer.NewGrammarCache(0)
+	cache.Limits = lexer.GrammarLimits{MaxKinds: 1}
+	if _, err := cache.Compile("PLUS +\nMINUS -\n"); !errors.As(err, new(lexer.LimitError)) || cache.Len() != 0 {
+		t.Errorf("expected the cache to refuse the grammar, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package lexer
+
+import "io"
+
+/* --- LINE STREAMS ---
+Input too large to hold, such as logs of many
+gigabytes, is tokenized a line at a time: each
+line's tokens are handed over, then let go before
+the next line is read. Memory stays bounded by
+the longest line, whatever the size of the input.
+
+The tokens handed over are only valid until the
+callback returns; their slice is reused for the
+next line. Copy any that must be kept. */
+
+/*
+Break down each line read from `r` into tokens,
+calling `fn` with the number of each line,
+counting from 1, and its tokens. Stops at the
+first error `fn` returns, returning it. Line
+breaks are given per the lexer's newline mode,
+but never collapsed across lines, as each line is
+let go before the next is read. In strict mode,
+the tokens before unknown input are handed over,
+then the error returned. Returns `ErrBinaryInput`
+if `r` appears to be binary.
+*/
+func (lx *Lexer) TokenizeLinesStream(r io.Reader, fn func(lineNo int, toks []TokenObject) error) error {
+	r, err := newTextReader(r, lx.decoder())
+	if err != nil {
+		return err
+	}
+	scanner := newLineScanner(r)
+	scanner.Split(scanLinesKeep)
+
+	tokens := TokenObjects{}
+	lineNo := tokenLineNo(0)
+	run := lx.newRun()
+	for scanner.Scan() {
+		lineNo += 1
+		line := splitTerminator(scanner.Text(), 0)
+		run.lap(stageScanning)
+
+		tokens, err = lx.tokenizeLineLocked(tokens[:0], line.Text, nil, lineNo, &run)
+		if err == nil {
+			tokens = lx.endLine(tokens, 0, len(line.Text), line.Terminator, lineNo, &run)
+		}
+		if fnErr := fn(int(lineNo), tokens); fnErr != nil {
+			return fnErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+/*
+Break down each line read from `r` into tokens,
+calling `fn` with each line's tokens in turn.
+*/
+func (g *Grammar) TokenizeLinesStream(r io.Reader, fn func(lineNo int, toks []TokenObject) error) error {
+	return g.lexer().TokenizeLinesStream(r, fn)
+}
+
+/*
+Break down each line read from `r` into tokens
+using the default grammar, calling `fn` with each
+line's tokens in turn.
+*/
+func TokenizeLinesStream(r io.Reader, fn func(lineNo int, toks []TokenObject) error) error {
+	return defaultGrammar.TokenizeLinesStream(r, fn)
+}
@@ -0,0 +1,80 @@
+package lexer_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+/* Reads the same line over and over, `n` times. */
+type repeatReader struct {
+	line string
+	n    int
+	rest string
+}
+
+func (rr *repeatReader) Read(p []byte) (int, error) {
+	if rr.rest == "" {
+		if rr.n == 0 {
+			return 0, io.EOF
+		}
+		rr.rest, rr.n = rr.line, rr.n-1
+	}
+	n := copy(p, rr.rest)
+	rr.rest = rr.rest[n:]
+	return n, nil
+}
+
+func TestTokenizeLinesStream(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+
+	var got []string
+	err := g.TokenizeLinesStream(strings.NewReader("a+b\n\nc"), func(lineNo int, toks []lexer.TokenObject) error {
+		got = append(got, renderKinds(toks))
+		if len(toks) > 0 && int(toks[0].LineNo) != lineNo {
+			t.Errorf("line %d: unexpected line number %d", lineNo, toks[0].LineNo)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"GENIDEN:a PLUS:+ GENIDEN:b", "", "GENIDEN:c"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// Tokens of every line share one buffer, however many lines.
+	lines, most := 0, 0
+	rr := &repeatReader{line: "a + b + c\n", n: 100000}
+	err = g.TokenizeLinesStream(rr, func(lineNo int, toks []lexer.TokenObject) error {
+		lines++
+		if cap(toks) > most {
+			most = cap(toks)
+		}
+		return nil
+	})
+	if err != nil || lines != 100000 {
+		t.Fatalf("expected 100000 lines, got %d, %v", lines, err)
+	}
+	if most > 32 {
+		t.Errorf("expected the buffer to stay bounded by a line, grew to %d", most)
+	}
+
+	stop := errors.New("stop")
+	lines = 0
+	err = g.TokenizeLinesStream(strings.NewReader("a\nb\nc\n"), func(lineNo int, toks []lexer.TokenObject) error {
+		lines++
+		if lineNo == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || lines != 2 {
+		t.Errorf("expected to stop at line 2 with its error, got %d lines, %v", lines, err)
+	}
+}
@@ -0,0 +1,404 @@
+package lexer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/* --- LINTING ---
+Whitespace conventions are checked from the token
+stream alone: every byte of a line is in some
+token, whitespace included. Each rule reports
+diagnostics about the tokens breaking it, named
+by the rule:
+
+trailing-whitespace: Whitespace ending a line.
+indent: Lines indented with tabs where the
+project wants spaces, or the other way around;
+if it wants neither in particular, indentation
+mixing both.
+operator-spacing: Binary operators without a
+space on either side, e.g. `a+b`. Operators not
+after an operand, an identifier, literal or
+close bracket, as the `-` of `a = -b`, are taken
+as unary and left alone.
+line-length: Lines longer than the most allowed,
+counting tabs to the next tab stop.
+
+Other rules may be registered by name with
+`RegisterLintRule`, and are run after these.
+
+Projects configure rules in a lint file of a
+setting per line, e.g.:
+
+	max-line-length 120
+	tab-width 8
+	indent spaces
+	disable operator-spacing
+
+Lines starting with `#` are comments. Rules
+not configured keep their defaults. */
+
+// Name of the lint file projects configure rules in.
+const LintFileName = ".panza-lint"
+
+/* Settings of the lint rules. */
+type LintConfig struct {
+	MaxLineLength int      // Most columns per line; no limit if 0.
+	TabWidth      int      // Columns between tab stops; 4 if 0.
+	Indent        string   // `tabs` or `spaces`; if empty, either, but not both on one line.
+	Disabled      []string // Names of rules not run.
+}
+
+/* Settings of the lint rules if not configured. */
+func DefaultLintConfig() LintConfig {
+	return LintConfig{MaxLineLength: 100, TabWidth: 4}
+}
+
+/*
+Read lint settings, a setting per line, over the
+defaults.
+*/
+func ParseLintConfig(r io.Reader) (LintConfig, error) {
+	lc := DefaultLintConfig()
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := lc.apply(line); err != nil {
+			return lc, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	return lc, scanner.Err()
+}
+
+/* Apply a single setting. */
+func (lc *LintConfig) apply(line string) error {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "max-line-length", "tab-width":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return fmt.Errorf("expected a number of columns for %s, got %q", name, arg)
+		}
+		if name == "tab-width" {
+			lc.TabWidth = n
+		} else {
+			lc.MaxLineLength = n
+		}
+	case "indent":
+		if arg != "tabs" && arg != "spaces" && arg != "any" {
+			return fmt.Errorf("unknown indent %q, expected tabs, spaces or any", arg)
+		}
+		if lc.Indent = arg; arg == "any" {
+			lc.Indent = ""
+		}
+	case "disable":
+		if !isLintRule(arg) {
+			return fmt.Errorf("unknown rule %q", arg)
+		}
+		lc.Disabled = append(lc.Disabled, arg)
+	default:
+		return fmt.Errorf("unknown setting %q", name)
+	}
+	return nil
+}
+
+/* Read lint settings from the named lint file. */
+func LoadLintConfig(name string) (LintConfig, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return DefaultLintConfig(), err
+	}
+	defer file.Close()
+
+	return ParseLintConfig(file)
+}
+
+/*
+A lint rule, checking the tokens of a line at a
+time. Rules report diagnostics about the tokens
+breaking them; the name of the rule is set on
+each by the linter.
+*/
+type Rule interface {
+	Check(ctx *RuleContext) []Diagnostic
+}
+
+/* Adapts a function to a `Rule`. */
+type RuleFunc func(ctx *RuleContext) []Diagnostic
+
+func (rf RuleFunc) Check(ctx *RuleContext) []Diagnostic {
+	return rf(ctx)
+}
+
+/* The line a rule is checking, and its settings. */
+type RuleContext struct {
+	Config LintConfig
+	Tokens TokenObjects // Every token linted.
+	Line   TokenObjects // Tokens of the line checked; never empty.
+	start  int          // Index of the line's first token in `Tokens`.
+}
+
+/*
+Cursor at the token at index `i` of the line.
+The cursor moves over every token linted, so may
+be moved to neighboring lines.
+*/
+func (ctx *RuleContext) Cursor(i int) TokenCursor {
+	return ctx.Tokens.CursorAt(ctx.start + i)
+}
+
+/* A rule registered by name. */
+type namedRule struct {
+	name string
+	code string // Given diagnostics of the rule without a code.
+	rule Rule
+}
+
+// Rules run by the linter, in order of registration.
+var lintRules = []namedRule{
+	{"trailing-whitespace", CodeTrailingWhitespace, RuleFunc(lintTrailingWhitespace)},
+	{"indent", CodeIndent, RuleFunc(lintIndent)},
+	{"operator-spacing", CodeOperatorSpacing, RuleFunc(lintOperatorSpacing)},
+	{"line-length", CodeLineLength, RuleFunc(lintLineLength)},
+}
+
+// Guards `lintRules`.
+var lintRulesMu sync.RWMutex
+
+/*
+Register a lint rule under the given name, run
+by every linter after the rules registered before
+it, including by the `lint` subcommand of the
+command line tool. Replaces any rule already
+registered with that name, in its place.
+Diagnostics of the rule without a code are
+given its name as their code; their severity is
+the rule's own to set.
+*/
+func RegisterLintRule(name string, r Rule) {
+	lintRulesMu.Lock()
+	defer lintRulesMu.Unlock()
+
+	for i := range lintRules {
+		if lintRules[i].name == name {
+			lintRules[i].code, lintRules[i].rule = name, r
+			return
+		}
+	}
+	lintRules = append(lintRules, namedRule{name, name, r})
+}
+
+/* Retrieve a registered lint rule by name. */
+func LintRule(name string) (Rule, bool) {
+	lintRulesMu.RLock()
+	defer lintRulesMu.RUnlock()
+
+	for _, nr := range lintRules {
+		if nr.name == name {
+			return nr.rule, true
+		}
+	}
+	return nil, false
+}
+
+/* Determine if a rule of the given name is registered. */
+func isLintRule(name string) bool {
+	_, ok := LintRule(name)
+	return ok
+}
+
+/* Names of the registered lint rules, in the order they are run. */
+func LintRules() []string {
+	lintRulesMu.RLock()
+	defer lintRulesMu.RUnlock()
+
+	names := make([]string, len(lintRules))
+	for i, nr := range lintRules {
+		names[i] = nr.name
+	}
+	return names
+}
+
+/*
+Check the tokens against every registered rule
+not disabled. Diagnostics are in order of
+position, then rule. The tokens are expected in
+order, as the tokenizer produces them.
+*/
+func (lc LintConfig) Lint(tokens TokenObjects) []Diagnostic {
+	lintRulesMu.RLock()
+	rules := append([]namedRule{}, lintRules...)
+	lintRulesMu.RUnlock()
+
+	diags := []Diagnostic{}
+	for start := 0; start < len(tokens); {
+		end := skipLine(tokens, start)
+		ctx := &RuleContext{lc, tokens, tokens[start:end], start}
+		for _, nr := range rules {
+			if containsString(lc.Disabled, nr.name) {
+				continue
+			}
+			for _, d := range nr.rule.Check(ctx) {
+				d.Rule = nr.name
+				if d.Code == "" {
+					d.Code = nr.code
+				}
+				diags = append(diags, d)
+			}
+		}
+		start = end
+	}
+
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Token, diags[j].Token
+		if a.LineNo != b.LineNo {
+			return a.LineNo < b.LineNo
+		}
+		return a.Position < b.Position
+	})
+	return diags
+}
+
+/* A warning about a token, as the built in rules give. */
+func lintWarning(to TokenObject, msg string) Diagnostic {
+	return Diagnostic{Token: to, Message: msg, Severity: SeverityWarning}
+}
+
+/* Report whitespace ending the line. */
+func lintTrailingWhitespace(ctx *RuleContext) []Diagnostic {
+	last := ctx.Line[len(ctx.Line)-1]
+	if !last.IsTrivia() {
+		return nil
+	}
+	return []Diagnostic{lintWarning(last, "trailing whitespace")}
+}
+
+/* Report indentation of the wrong, or mixed, characters. */
+func lintIndent(ctx *RuleContext) []Diagnostic {
+	lc, line := ctx.Config, ctx.Line
+	if allTrivia(line) {
+		// Blank lines are left to trailing-whitespace.
+		return nil
+	}
+	var diags []Diagnostic
+	tabs, spaces := false, false
+	for _, to := range line {
+		if !to.IsTrivia() {
+			break
+		}
+		hasTab := strings.ContainsRune(string(to.Symbol), '\t')
+		hasSpace := strings.ContainsRune(string(to.Symbol), ' ')
+		switch {
+		case lc.Indent == "spaces" && hasTab:
+			diags = append(diags, lintWarning(to, "indented with tabs, expected spaces"))
+		case lc.Indent == "tabs" && hasSpace:
+			diags = append(diags, lintWarning(to, "indented with spaces, expected tabs"))
+		case lc.Indent == "" && (hasTab && (spaces || hasSpace) || hasSpace && tabs):
+			diags = append(diags, lintWarning(to, "indented with both tabs and spaces"))
+		}
+		tabs, spaces = tabs || hasTab, spaces || hasSpace
+	}
+	return diags
+}
+
+/* Determine if every token is whitespace. */
+func allTrivia(tokens TokenObjects) bool {
+	for _, to := range tokens {
+		if !to.IsTrivia() {
+			return false
+		}
+	}
+	return true
+}
+
+/* Determine if a signature is of an operator, e.g. `+` or `<=`. */
+func isOperatorSig(sig tokenSignature) bool {
+	if len(sig) == 0 {
+		return false
+	}
+	for _, b := range sig {
+		if !strings.ContainsRune("=<>!+-*/%&|^", rune(b)) {
+			return false
+		}
+	}
+	return true
+}
+
+/* Determine if a token may end an operand, e.g. `x`, `1` or `)`. */
+func endsOperand(to TokenObject) bool {
+	if to.Kind == nil || isOperatorSig(to.Kind.Signature) {
+		return false
+	}
+	if to.Kind.Id == GenIdenId || to.Kind.Id == GenTypeId || to.Kind.Id == GenObjId || to.Value != nil {
+		return true
+	}
+	sig := to.Kind.Signature
+	last := sig[len(sig)-1]
+	return last == ')' || last == ']' || last == '}'
+}
+
+/* Report binary operators without a space on either side. */
+func lintOperatorSpacing(ctx *RuleContext) []Diagnostic {
+	line := ctx.Line
+	var diags []Diagnostic
+	for i, to := range line {
+		if to.Kind == nil || to.Kind.Id < FirstUserKindId || !isOperatorSig(to.Kind.Signature) {
+			continue
+		}
+		prev := ctx.Cursor(i).PrevSignificant()
+		if !prev.Valid() || !endsOperand(prev.Token()) {
+			continue
+		}
+		if i > 0 && !line[i-1].IsTrivia() {
+			diags = append(diags, lintWarning(to, fmt.Sprintf("missing space before '%s'", to.Symbol)))
+		}
+		if i+1 < len(line) && !line[i+1].IsTrivia() && line[i+1].Kind.Id != CommentId {
+			diags = append(diags, lintWarning(to, fmt.Sprintf("missing space after '%s'", to.Symbol)))
+		}
+	}
+	return diags
+}
+
+/* Report the token crossing the most columns allowed. */
+func lintLineLength(ctx *RuleContext) []Diagnostic {
+	lc, line := ctx.Config, ctx.Line
+	if lc.MaxLineLength <= 0 {
+		return nil
+	}
+	tabWidth := lc.TabWidth
+	if tabWidth <= 0 {
+		tabWidth = 4
+	}
+
+	width := 0
+	var crossing *TokenObject
+	for i, to := range line {
+		for _, r := range string(to.Symbol) {
+			if r == '\t' {
+				width += tabWidth - width%tabWidth
+			} else {
+				width++
+			}
+		}
+		if crossing == nil && width > lc.MaxLineLength {
+			crossing = &line[i]
+		}
+	}
+	if crossing == nil {
+		return nil
+	}
+	return []Diagnostic{lintWarning(*crossing, fmt.Sprintf("line is %d columns long, more than %d", width, lc.MaxLineLength))}
+}
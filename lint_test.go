@@ -0,0 +1,135 @@
+package lexer_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+/* Rule and message of each diagnostic, with its line and column. */
+func lintHits(t *testing.T, lc lexer.LintConfig, text string) []string {
+	t.Helper()
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	g.AddKind("MINUS", "-", "")
+	g.AddKind("ASSIGN", "=", "")
+	g.AddKind("LPAREN", "(", "")
+	g.AddKind("RPAREN", ")", "")
+	if err := g.SetLineComments("//"); err != nil {
+		t.Fatal(err)
+	}
+	index, err := g.TokenizeText(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hits := []string{}
+	for _, d := range lc.Lint(index.Tokens()) {
+		pos := d.Token.Pos()
+		hits = append(hits, fmt.Sprintf("%s: %s @%d:%d", d.Rule, d.Message, pos.Line, pos.Column))
+	}
+	return hits
+}
+
+// Registered by `TestRegisterLintRule`, so left out of other tests.
+var builtinRulesOnly = []string{"test-adjacent-identifiers"}
+
+func TestLintRules(t *testing.T) {
+	lc := lexer.DefaultLintConfig()
+	lc.Disabled = builtinRulesOnly
+	got := lintHits(t, lc, "x = a+b \n\t y = (-a) - b\nz = a +b //c\n")
+	want := []string{
+		"operator-spacing: missing space before '+' @1:6",
+		"operator-spacing: missing space after '+' @1:6",
+		"trailing-whitespace: trailing whitespace @1:8",
+		"indent: indented with both tabs and spaces @2:2",
+		"operator-spacing: missing space after '+' @3:7",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestLintIndentAndLength(t *testing.T) {
+	lc := lexer.DefaultLintConfig()
+	lc.Disabled = builtinRulesOnly
+	lc.Indent = "spaces"
+	lc.MaxLineLength = 10
+	lc.TabWidth = 8
+	got := lintHits(t, lc, "\tx = 1\n    y = 2\nzzzz = yyyy\n")
+	want := []string{
+		"indent: indented with tabs, expected spaces @1:1",
+		"line-length: line is 13 columns long, more than 10 @1:4",
+		"line-length: line is 11 columns long, more than 10 @3:8",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestParseLintConfig(t *testing.T) {
+	lc, err := lexer.ParseLintConfig(strings.NewReader(`
+# Project settings.
+max-line-length 120
+tab-width 8
+indent tabs
+disable operator-spacing
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := lexer.LintConfig{MaxLineLength: 120, TabWidth: 8, Indent: "tabs", Disabled: []string{"operator-spacing"}}
+	if !reflect.DeepEqual(lc, want) {
+		t.Errorf("expected %+v, got %+v", want, lc)
+	}
+	if got := lintHits(t, lc, "x=1\n"); len(got) != 0 {
+		t.Errorf("expected disabled rule to be skipped, got %q", got)
+	}
+
+	for _, bad := range []string{"indent both", "disable nothing", "tab-width -1", "colour red"} {
+		if _, err := lexer.ParseLintConfig(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestRegisterLintRule(t *testing.T) {
+	// Reports identifiers following another identifier, on any line.
+	lexer.RegisterLintRule("test-adjacent-identifiers", lexer.RuleFunc(func(ctx *lexer.RuleContext) []lexer.Diagnostic {
+		var diags []lexer.Diagnostic
+		for i, to := range ctx.Line {
+			if to.Kind.Id != lexer.GenIdenId {
+				continue
+			}
+			if prev := ctx.Cursor(i).PrevSignificant(); prev.Valid() && prev.Token().Kind.Id == lexer.GenIdenId {
+				diags = append(diags, lexer.Diagnostic{Token: to, Message: "adjacent identifiers"})
+			}
+		}
+		return diags
+	}))
+	if _, ok := lexer.LintRule("test-adjacent-identifiers"); !ok {
+		t.Fatal("expected the rule to be registered")
+	}
+	names := lexer.LintRules()
+	if names[len(names)-1] != "test-adjacent-identifiers" {
+		t.Errorf("expected the rule to run last, got %q", names)
+	}
+
+	lc := lexer.DefaultLintConfig()
+	got := lintHits(t, lc, "a = b\nc\n")
+	want := []string{"test-adjacent-identifiers: adjacent identifiers @2:1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	lc, err := lexer.ParseLintConfig(strings.NewReader("disable test-adjacent-identifiers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lintHits(t, lc, "a b\n"); len(got) != 0 {
+		t.Errorf("expected the disabled rule to be skipped, got %q", got)
+	}
+}
@@ -0,0 +1,163 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/* --- LITERALS ---
+Kinds may be declared literals of a type, with
+the `@literal` directive or `SetLiteral`. The
+symbol of each token of such a kind is parsed
+into its `Value` as it is tokenized, so every
+parser shares the same rules:
+
+int: Go integer literals; `0x`, `0o` and `0b`
+prefixes and `_` separators are allowed.
+float: Go floating-point literals.
+string: Go quoted strings, with escapes decoded;
+"...", `...`, or '...' for a single character.
+bool: `true` or `false`.
+
+A symbol that does not parse leaves `Value` nil,
+so `GENIDEN` may be declared an int literal to
+give values to numbers only. */
+
+/* The type a literal kind's symbols parse to. */
+type LiteralType uint8
+
+const (
+	LiteralInt    LiteralType = iota + 1 // Parses to int64.
+	LiteralFloat                         // Parses to float64.
+	LiteralString                        // Parses to string.
+	LiteralBool                          // Parses to bool.
+)
+
+var literalTypeNames = map[string]LiteralType{
+	"int":    LiteralInt,
+	"float":  LiteralFloat,
+	"string": LiteralString,
+	"bool":   LiteralBool,
+}
+
+func (lt LiteralType) String() string {
+	for name, typ := range literalTypeNames {
+		if typ == lt {
+			return name
+		}
+	}
+	return fmt.Sprintf("LiteralType(%d)", uint8(lt))
+}
+
+/* Look up a literal type by its name, e.g. `int`. */
+func ParseLiteralType(name string) (LiteralType, error) {
+	if typ, ok := literalTypeNames[name]; ok {
+		return typ, nil
+	}
+	return 0, fmt.Errorf("unknown literal type %q, expected int, float, string or bool", name)
+}
+
+/*
+Parse a symbol per the literal type. Returns nil
+if it does not parse.
+*/
+func parseLiteral(typ LiteralType, symbol string) any {
+	switch typ {
+	case LiteralInt:
+		if v, err := strconv.ParseInt(symbol, 0, 64); err == nil {
+			return v
+		}
+	case LiteralFloat:
+		// Spelled out "Inf" or "NaN" are identifiers.
+		if v, err := strconv.ParseFloat(symbol, 64); err == nil && startsNumeric(symbol) {
+			return v
+		}
+	case LiteralString:
+		if v, err := strconv.Unquote(symbol); err == nil {
+			return v
+		}
+	case LiteralBool:
+		switch symbol {
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+	}
+	return nil
+}
+
+/* Determine if a symbol starts like a number. */
+func startsNumeric(symbol string) bool {
+	symbol = strings.TrimLeft(symbol, "+-")
+	return symbol != "" && (symbol[0] == '.' || '0' <= symbol[0] && symbol[0] <= '9')
+}
+
+/*
+Declare the named kind a literal of the given
+type. Tokens of the kind tokenized from then on
+carry a `Value`.
+*/
+func (g *Grammar) SetLiteral(kind string, typ LiteralType) error {
+	if typ < LiteralInt || typ > LiteralBool {
+		return fmt.Errorf("unknown literal type %s", typ)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	found, ok := g.kinds.ByName(tokenName(kind))
+	if !ok {
+		return fmt.Errorf("unknown kind %q", kind)
+	}
+	if g.literals == nil {
+		g.literals = map[tokenId]LiteralType{}
+	}
+	g.literals[found.Id] = typ
+	return nil
+}
+
+/*
+The value of a symbol of the given kind, or nil
+if the kind is not a literal.
+*/
+func (g *Grammar) literalValue(id tokenId, symbol tokenSignature) any {
+	typ, ok := g.literals[id]
+	if !ok {
+		return nil
+	}
+	return parseLiteral(typ, string(symbol))
+}
+
+/* The value of this token if it is an int literal. */
+func (to TokenObject) IntValue() (int64, bool) {
+	v, ok := to.Value.(int64)
+	return v, ok
+}
+
+/*
+The value of this token if it is a float
+literal. Int literals are converted.
+*/
+func (to TokenObject) FloatValue() (float64, bool) {
+	switch v := to.Value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+/* The value of this token if it is a string literal. */
+func (to TokenObject) StringValue() (string, bool) {
+	v, ok := to.Value.(string)
+	return v, ok
+}
+
+/* The value of this token if it is a bool literal. */
+func (to TokenObject) BoolValue() (bool, bool) {
+	v, ok := to.Value.(bool)
+	return v, ok
+}
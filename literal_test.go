@@ -0,0 +1,81 @@
+package lexer_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestLiteralValues(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("TRUE", "true", "")
+	g.AddKind("FALSE", "false", "")
+	if err := g.SetLiteral("TRUE", lexer.LiteralBool); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetLiteral("FALSE", lexer.LiteralBool); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetLiteral("GENIDEN", lexer.LiteralInt); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetLiteral("MISSING", lexer.LiteralInt); err == nil {
+		t.Error("expected an error for an unknown kind")
+	}
+
+	tokens := g.TokenizeLine("0x1F true 1_000 x false", 1)
+	if v, ok := tokens[0].IntValue(); !ok || v != 31 {
+		t.Errorf("expected 31, got %v", tokens[0].Value)
+	}
+	if v, ok := tokens[2].BoolValue(); !ok || !v {
+		t.Errorf("expected true, got %v", tokens[2].Value)
+	}
+	if v, ok := tokens[4].FloatValue(); !ok || v != 1000 {
+		t.Errorf("expected 1000, got %v", tokens[4].Value)
+	}
+	if tokens[6].Value != nil || tokens[1].Value != nil {
+		t.Errorf("expected no value for %v and %v", tokens[6], tokens[1])
+	}
+	if v, ok := tokens[8].BoolValue(); !ok || v {
+		t.Errorf("expected false, got %v", tokens[8].Value)
+	}
+}
+
+func TestLiteralDirective(t *testing.T) {
+	defer reloadTokens(t)
+
+	fsys := fstest.MapFS{
+		"lit.tokens":    {Data: []byte("QUOTE \"\n@literal GENIDEN float\n")},
+		"bad.tokens":    {Data: []byte("@literal NUMBER int\nNUMBER 0\n")},
+		"badtyp.tokens": {Data: []byte("@literal GENIDEN complex\n")},
+	}
+	if err := lexer.LoadTokensFS(fsys, "lit.tokens"); err != nil {
+		t.Fatal(err)
+	}
+	tokens := lexer.TokenizeLine("1.5e3 Inf", 1)
+	if v, ok := tokens[0].FloatValue(); !ok || v != 1500 {
+		t.Errorf("expected 1500, got %v", tokens[0].Value)
+	}
+	if tokens[2].Value != nil {
+		t.Errorf("expected Inf to be an identifier, got %v", tokens[2].Value)
+	}
+
+	if err := lexer.LoadTokensFS(fsys, "bad.tokens"); err == nil {
+		t.Error("expected an error for a literal of a kind not yet defined")
+	}
+	if err := lexer.LoadTokensFS(fsys, "badtyp.tokens"); err == nil {
+		t.Error("expected an error for an unknown literal type")
+	}
+}
+
+func TestStringLiteral(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	if err := g.SetLiteral("GENIDEN", lexer.LiteralString); err != nil {
+		t.Fatal(err)
+	}
+	tokens := g.TokenizeLine(`"a\tb"`, 1)
+	if v, ok := tokens[0].StringValue(); !ok || v != "a\tb" {
+		t.Errorf("expected decoded escapes, got %v", tokens[0].Value)
+	}
+}
@@ -0,0 +1,177 @@
+package lexer
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+/* --- MERGING TOKENS ---
+Tokens may come from more than one source; kinds
+built into a program, say from an embedded tokens
+file, along with a tokens file found on disk. How
+a tokens file combines with the kinds a grammar
+already has is chosen by the caller:
+
+replace: The file's kinds replace every kind, as
+with `LoadTokensFS`.
+extend: The file's kinds are added; kinds whose
+names are already defined are ignored.
+override: The file's kinds are added; kinds whose
+names are already defined take the file's
+signature and description, keeping their IDs.
+
+Directives follow suit: under extend, `@ident`,
+`@literal`, `@anchor`, `@trivia` and `@comment` only apply
+where the grammar has not declared them
+already. */
+
+/* How a tokens file combines with a grammar's kinds. */
+type MergeMode uint8
+
+const (
+	MergeReplace MergeMode = iota
+	MergeExtend
+	MergeOverride
+)
+
+var mergeModeNames = []string{"replace", "extend", "override"}
+
+func (mm MergeMode) String() string {
+	if int(mm) < len(mergeModeNames) {
+		return mergeModeNames[mm]
+	}
+	return fmt.Sprintf("MergeMode(%d)", uint8(mm))
+}
+
+/* Look up a merge mode by its name, e.g. `extend`. */
+func ParseMergeMode(name string) (MergeMode, error) {
+	for i, known := range mergeModeNames {
+		if name == known {
+			return MergeMode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown merge mode %q, expected replace, extend or override", name)
+}
+
+/*
+Combine the kinds defined in the named tokens
+file of the given file system with this
+grammar's, per the merge mode. Loaded tokens are
+left untouched if the file cannot be read.
+*/
+func (g *Grammar) MergeTokensFS(fsys fs.FS, name string, mode MergeMode) error {
+	file, err := openTokenFileFS(fsys, name, nil)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	spec, err := readTokenSpec(file)
+	if err != nil {
+		return err
+	}
+	return g.mergeTokens(spec, mode)
+}
+
+/*
+Combine the kinds defined in the named tokens
+file with this grammar's, per the merge mode.
+*/
+func (g *Grammar) MergeTokensFile(name string, mode MergeMode) error {
+	file, err := openTokenFile(name, nil)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	spec, err := readTokenSpec(file)
+	if err != nil {
+		return err
+	}
+	return g.mergeTokens(spec, mode)
+}
+
+/*
+Combine the kinds defined in the named tokens
+file with the default grammar's, per the merge
+mode.
+*/
+func MergeTokensFile(name string, mode MergeMode) error {
+	return defaultGrammar.MergeTokensFile(name, mode)
+}
+
+/* Combine the spec with this grammar's kinds per the mode. */
+func (g *Grammar) mergeTokens(spec tokenSpec, mode MergeMode) error {
+	switch mode {
+	case MergeReplace:
+		g.setTokens(spec)
+		return nil
+	case MergeExtend, MergeOverride:
+	default:
+		return fmt.Errorf("unknown merge mode %s", mode)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	override := mode == MergeOverride
+	for _, def := range spec.defs {
+		if !g.enabled(def.when) {
+			continue
+		}
+		kind, ok := g.kinds.ByName(def.name)
+		switch {
+		case !ok:
+			g.add(def.name, def.sig, def.desc)
+		case override:
+			g.replaceKind(kind, def.sig, def.desc)
+		}
+	}
+
+	if spec.ident != nil && (override || g.ident == nil) {
+		g.ident = spec.ident
+	}
+	g.applyLiterals(spec.literals, override)
+	g.applyAnchors(spec.anchors, override)
+	g.applyAliases(spec.aliases, override)
+	g.applyTrivia(spec.trivia, override)
+	if spec.version != "" && (override || g.Version == "") {
+		g.Version = spec.version
+	}
+	if spec.comments != nil && (override || g.comments == nil) {
+		g.comments = spec.comments
+	}
+	return nil
+}
+
+/*
+Replace the signature and description of a kind,
+keeping its ID and name.
+*/
+func (g *Grammar) replaceKind(kind TokenKind, sig tokenSignature, desc tokenDescription) {
+	if len(sig) > g.signatureMaxSize {
+		g.signatureMaxSize = len(sig)
+	}
+	g.kinds.put(TokenKind{kind.Id, kind.Name, sig, desc})
+}
+
+/*
+Declare kinds, by name, literals of a type.
+Kinds already declared literals keep their type
+unless `overwrite` is set.
+*/
+func (g *Grammar) applyLiterals(literals map[tokenName]LiteralType, overwrite bool) {
+	for name, typ := range literals {
+		kind, ok := g.kinds.ByName(name)
+		if !ok {
+			continue
+		}
+		if _, declared := g.literals[kind.Id]; declared && !overwrite {
+			continue
+		}
+		if g.literals == nil {
+			g.literals = map[tokenId]LiteralType{}
+		}
+		g.literals[kind.Id] = typ
+	}
+}
@@ -0,0 +1,73 @@
+package lexer_test
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestMergeTokens(t *testing.T) {
+	fsys := fstest.MapFS{
+		"extra.tokens": {Data: []byte("PLUS ++ #: increment\nARROW ->\n@ident letter\n")},
+	}
+	newGrammar := func() *lexer.Grammar {
+		g := lexer.NewGrammar("x")
+		g.AddKind("PLUS", "+", "plus")
+		return g
+	}
+
+	cases := []struct {
+		mode  lexer.MergeMode
+		plus  string // Signature of PLUS after merging, if kept.
+		arrow bool
+	}{
+		{lexer.MergeReplace, "++", true},
+		{lexer.MergeExtend, "+", true},
+		{lexer.MergeOverride, "++", true},
+	}
+	for _, c := range cases {
+		g := newGrammar()
+		before, _ := g.LookupKind("PLUS")
+		if err := g.MergeTokensFS(fsys, "extra.tokens", c.mode); err != nil {
+			t.Fatalf("%s: %v", c.mode, err)
+		}
+
+		plus, ok := g.LookupKind("PLUS")
+		if !ok || plus.Signature.String() != c.plus {
+			t.Errorf("%s: expected PLUS %q, got %v", c.mode, c.plus, plus)
+		}
+		if c.mode == lexer.MergeOverride && (plus.Id != before.Id || plus.Description != "increment") {
+			t.Errorf("%s: expected PLUS to keep its ID and take the description, got %v", c.mode, plus)
+		}
+		if _, ok := g.LookupKind("ARROW"); ok != c.arrow {
+			t.Errorf("%s: expected ARROW %v", c.mode, c.arrow)
+		}
+		if got := strings.Join(symbolsOf(g.TokenizeLine("a->b", 1)), " "); got != "a -> b" {
+			t.Errorf("%s: unexpected symbols %q", c.mode, got)
+		}
+	}
+
+	if _, err := lexer.ParseMergeMode("extend"); err != nil {
+		t.Error(err)
+	}
+	if _, err := lexer.ParseMergeMode("merge"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestMergeExtendKeepsIdent(t *testing.T) {
+	fsys := fstest.MapFS{"ident.tokens": {Data: []byte("@ident letter\n")}}
+
+	g := lexer.NewGrammar("x")
+	if err := g.SetIdentClasses("digit"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MergeTokensFS(fsys, "ident.tokens", lexer.MergeExtend); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(symbolsOf(g.TokenizeLine("12ab", 1)), " "); got != "12 a b" {
+		t.Errorf("expected digit identifiers to be kept, got %q", got)
+	}
+}
@@ -0,0 +1,87 @@
+package lexer
+
+import "strings"
+
+/* --- MINIFYING ---
+Source is minified by dropping its comments and
+as much whitespace as can go without changing how
+it tokenizes. Only the grammar knows what that
+is: `a` and `b` merge into `ab` written
+together, where `a` and `(` do not, and `<` and
+`=` may merge into `<=`. Each pair of adjacent
+tokens is re-lexed written together, then with a
+space between, and is kept apart by the least
+that tokenizes back into the same pair. Tokens
+anchored to the start or end of a line keep a
+line break before or after them. */
+
+/*
+Reconstruct the text of the tokens with comments
+dropped and whitespace collapsed to the least
+keeping tokens from merging, so the text
+tokenizes back into the same tokens, whitespace
+and comments aside. The tokens are expected in
+order, as the tokenizer produces them.
+*/
+func (lx *Lexer) Minify(tokens TokenObjects) string {
+	// Re-lexing pairs of tokens records nothing.
+	probe := *lx
+	probe.Diagnostics, probe.Profile, probe.Cache = nil, nil, nil
+
+	var out strings.Builder
+	prev := -1 // Index of the last token written.
+	for i, to := range tokens {
+		if to.Kind == nil || to.IsTrivia() || to.Kind.Id == CommentId {
+			continue
+		}
+		if prev >= 0 {
+			out.WriteString(probe.separator(tokens[prev], to))
+		}
+		out.Write(to.Symbol)
+		prev = i
+	}
+	return out.String()
+}
+
+/* Reconstruct the minified text of tokens of this grammar. */
+func (g *Grammar) Minify(tokens TokenObjects) string {
+	return g.lexer().Minify(tokens)
+}
+
+/* Reconstruct the minified text of tokens of the default grammar. */
+func Minify(tokens TokenObjects) string {
+	return defaultGrammar.Minify(tokens)
+}
+
+/* The least whitespace keeping two adjacent tokens apart. */
+func (lx *Lexer) separator(a, b TokenObject) string {
+	g := lx.grammar()
+	if g.anchorOf(a.Kind.Id) == AnchorEnd || g.anchorOf(b.Kind.Id) == AnchorStart {
+		return "\n"
+	}
+	for _, sep := range []string{"", " "} {
+		if lx.relexes(a, sep, b) {
+			return sep
+		}
+	}
+	return "\n"
+}
+
+/*
+Determine if two tokens written with `sep`
+between them tokenize back into the same two.
+*/
+func (lx *Lexer) relexes(a TokenObject, sep string, b TokenObject) bool {
+	got := TokenObjects{}
+	for _, to := range lx.TokenizeLine(string(a.Symbol)+sep+string(b.Symbol), 1) {
+		if !to.IsTrivia() {
+			got = append(got, to)
+		}
+	}
+	return len(got) == 2 && sameToken(got[0], a) && sameToken(got[1], b)
+}
+
+/* Determine if two tokens are of the same kind and symbol. */
+func sameToken(a, b TokenObject) bool {
+	return a.Kind != nil && b.Kind != nil && a.Kind.Id == b.Kind.Id && string(a.Symbol) == string(b.Symbol)
+}
@@ -0,0 +1,57 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestMinify(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("LT", "<", "")
+	g.AddKind("LE", "<=", "")
+	g.AddKind("ASSIGN", "=", "")
+	g.AddKind("LPAREN", "(", "")
+	g.AddKind("RPAREN", ")", "")
+	g.AddKind("SEMI", ";", "")
+	g.AddKind("HASH", "#", "")
+	if err := g.SetLineComments("//"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetAnchor("HASH", lexer.AnchorStart); err != nil {
+		t.Fatal(err)
+	}
+
+	src := "if ( a <= b ) // c\n\tx = y ;\n# z < = w\n"
+	index, err := g.TokenizeText(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := g.Minify(index.Tokens())
+	want := "if(a<=b)x=y;\n#z< =w"
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	again, err := g.TokenizeText(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Minify(again.Tokens()) != got {
+		t.Errorf("expected minified text to minify to itself")
+	}
+	if a, b := renderKinds(significant(again.Tokens())), renderKinds(significant(index.Tokens())); a != b {
+		t.Errorf("expected the same tokens, got %s, want %s", a, b)
+	}
+}
+
+/* Tokens that are neither whitespace nor comments. */
+func significant(tokens []lexer.TokenObject) []lexer.TokenObject {
+	kept := []lexer.TokenObject{}
+	for _, to := range tokens {
+		if !to.IsTrivia() && to.Kind.Id != lexer.CommentId {
+			kept = append(kept, to)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,616 @@
+package lexer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"unicode/utf8"
+)
+
+/* --- MESSAGEPACK ---
+Tokens may be encoded as MessagePack, a compact
+binary format with libraries for most languages,
+for passing tokens to consumers outside of Go.
+Each token is a map, as with JSON:
+
+kind: Map of the kind's `id` and `name`.
+line, position: Where the token is.
+symbol: Text of the token; binary if not UTF-8.
+value: Parsed symbol of literal kinds, if any.
+count: Line breaks a collapsed `NEWLINE` stands
+for, if any.
+origin: Map of what produced the token, if
+anything: `by`, `source` and `kind` if known,
+`line`, `position`, `symbol`, and the `origin`
+of the token it was produced from, if produced.
+comments: Array of comments attached, if any.
+
+A stream is a series of token maps, one after
+another, optionally preceded by a map holding
+their provenance under `provenance`. Kinds are
+written without signatures or descriptions;
+decoding against a grammar gives tokens the
+grammar's kinds back. */
+
+/* Appends MessagePack encoded values to a buffer. */
+type msgpackBuffer []byte
+
+func (mb *msgpackBuffer) put(b ...byte) {
+	*mb = append(*mb, b...)
+}
+
+func (mb *msgpackBuffer) putUint(n uint64) {
+	switch {
+	case n < 0x80:
+		mb.put(byte(n))
+	case n <= math.MaxUint8:
+		mb.put(0xcc, byte(n))
+	case n <= math.MaxUint16:
+		mb.put(0xcd, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		mb.put(0xce, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32((*mb)[len(*mb)-4:], uint32(n))
+	default:
+		mb.put(0xcf, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64((*mb)[len(*mb)-8:], n)
+	}
+}
+
+func (mb *msgpackBuffer) putInt(n int64) {
+	switch {
+	case n >= 0:
+		mb.putUint(uint64(n))
+	case n >= -32:
+		mb.put(byte(n))
+	default:
+		mb.put(0xd3, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64((*mb)[len(*mb)-8:], uint64(n))
+	}
+}
+
+/*
+Put the header of a string, binary, array or map
+of `n` items, given the codes of its forms; 0 for
+those it lacks.
+*/
+func (mb *msgpackBuffer) putHeader(n int, fix byte, fixMax int, code8, code16, code32 byte) {
+	switch {
+	case n <= fixMax:
+		mb.put(fix | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		mb.put(code8, byte(n))
+	case n <= math.MaxUint16:
+		mb.put(code16, byte(n>>8), byte(n))
+	default:
+		mb.put(code32, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32((*mb)[len(*mb)-4:], uint32(n))
+	}
+}
+
+func (mb *msgpackBuffer) putString(s string) {
+	mb.putHeader(len(s), 0xa0, 31, 0xd9, 0xda, 0xdb)
+	mb.put([]byte(s)...)
+}
+
+func (mb *msgpackBuffer) putBinary(b []byte) {
+	mb.putHeader(len(b), 0, -1, 0xc4, 0xc5, 0xc6)
+	mb.put(b...)
+}
+
+func (mb *msgpackBuffer) putArray(n int) {
+	mb.putHeader(n, 0x90, 15, 0, 0xdc, 0xdd)
+}
+
+func (mb *msgpackBuffer) putMap(n int) {
+	mb.putHeader(n, 0x80, 15, 0, 0xde, 0xdf)
+}
+
+/* Put a literal value, as held by `TokenObject.Value`. */
+func (mb *msgpackBuffer) putValue(v any) error {
+	switch v := v.(type) {
+	case nil:
+		mb.put(0xc0)
+	case bool:
+		if v {
+			mb.put(0xc3)
+		} else {
+			mb.put(0xc2)
+		}
+	case int64:
+		mb.putInt(v)
+	case float64:
+		mb.put(0xcb, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64((*mb)[len(*mb)-8:], math.Float64bits(v))
+	case string:
+		mb.putString(v)
+	default:
+		return fmt.Errorf("cannot encode value of type %T", v)
+	}
+	return nil
+}
+
+/* Put a token as a map. */
+func (mb *msgpackBuffer) putToken(to TokenObject) error {
+	fields := 4
+	if to.Value != nil {
+		fields += 1
+	}
+	if len(to.Comments) > 0 {
+		fields += 1
+	}
+	if to.Count != 0 {
+		fields += 1
+	}
+	if to.Origin != nil {
+		fields += 1
+	}
+	mb.putMap(fields)
+
+	mb.putString("kind")
+	mb.putMap(2)
+	mb.putString("id")
+	mb.putUint(uint64(to.Kind.Id))
+	mb.putString("name")
+	mb.putString(string(to.Kind.Name))
+
+	mb.putString("line")
+	mb.putUint(uint64(to.LineNo))
+	mb.putString("position")
+	mb.putUint(uint64(to.Position))
+	mb.putString("symbol")
+	if utf8.Valid(to.Symbol) {
+		mb.putString(string(to.Symbol))
+	} else {
+		mb.putBinary(to.Symbol)
+	}
+
+	if to.Value != nil {
+		mb.putString("value")
+		if err := mb.putValue(to.Value); err != nil {
+			return err
+		}
+	}
+	if to.Count != 0 {
+		mb.putString("count")
+		mb.putUint(uint64(to.Count))
+	}
+	if to.Origin != nil {
+		mb.putString("origin")
+		mb.putOrigin(to.Origin)
+	}
+	if len(to.Comments) > 0 {
+		mb.putString("comments")
+		mb.putArray(len(to.Comments))
+		for _, comment := range to.Comments {
+			if err := mb.putToken(comment); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/* Put the origin of a token as a map, and those it was produced from. */
+func (mb *msgpackBuffer) putOrigin(o *Origin) {
+	fields := 4
+	if o.Source != "" {
+		fields += 1
+	}
+	if o.Kind != "" {
+		fields += 1
+	}
+	if o.Origin != nil {
+		fields += 1
+	}
+	mb.putMap(fields)
+
+	mb.putString("by")
+	mb.putString(o.By)
+	if o.Source != "" {
+		mb.putString("source")
+		mb.putString(o.Source)
+	}
+	if o.Kind != "" {
+		mb.putString("kind")
+		mb.putString(string(o.Kind))
+	}
+	mb.putString("line")
+	mb.putUint(uint64(o.LineNo))
+	mb.putString("position")
+	mb.putUint(uint64(o.Position))
+	mb.putString("symbol")
+	if utf8.Valid(o.Symbol) {
+		mb.putString(string(o.Symbol))
+	} else {
+		mb.putBinary(o.Symbol)
+	}
+	if o.Origin != nil {
+		mb.putString("origin")
+		mb.putOrigin(o.Origin)
+	}
+}
+
+/*
+Writes tokens as a MessagePack stream, buffered
+until `Flush`. It is a token sink.
+*/
+type MsgpackEncoder struct {
+	w   *bufio.Writer
+	buf msgpackBuffer
+}
+
+/* Initialize a new `MsgpackEncoder`. */
+func NewMsgpackEncoder(w io.Writer) *MsgpackEncoder {
+	return &MsgpackEncoder{w: bufio.NewWriter(w)}
+}
+
+/* Write a token to the stream. */
+func (me *MsgpackEncoder) Encode(to TokenObject) error {
+	me.buf = me.buf[:0]
+	if err := me.buf.putToken(to); err != nil {
+		return err
+	}
+	_, err := me.w.Write(me.buf)
+	return err
+}
+
+/* Write the provenance of the tokens to follow. */
+func (me *MsgpackEncoder) EncodeProvenance(p Provenance) error {
+	fields := [][2]string{
+		{"lexer", p.Lexer},
+		{"grammar", p.Grammar},
+		{"grammar_checksum", p.GrammarChecksum},
+		{"source", p.Source},
+		{"source_checksum", p.SourceChecksum},
+	}
+	if p.GrammarVersion != "" {
+		fields = append(fields, [2]string{"grammar_version", p.GrammarVersion})
+	}
+
+	me.buf = me.buf[:0]
+	me.buf.putMap(1)
+	me.buf.putString("provenance")
+	me.buf.putMap(len(fields))
+	for _, field := range fields {
+		me.buf.putString(field[0])
+		me.buf.putString(field[1])
+	}
+	_, err := me.w.Write(me.buf)
+	return err
+}
+
+func (me *MsgpackEncoder) Emit(to TokenObject) error { return me.Encode(to) }
+
+func (me *MsgpackEncoder) Flush() error { return me.w.Flush() }
+
+/* Writes tokens as a MessagePack stream. */
+type MsgpackFormatter struct{}
+
+func (MsgpackFormatter) Format(w io.Writer, toks []TokenObject) error {
+	me := NewMsgpackEncoder(w)
+	for _, to := range toks {
+		if err := me.Encode(to); err != nil {
+			return err
+		}
+	}
+	return me.Flush()
+}
+
+func init() {
+	RegisterFormatter("msgpack", MsgpackFormatter{})
+}
+
+/* --- MESSAGEPACK DECODING --- */
+
+var errMsgpackFormat = errors.New("malformed MessagePack token")
+
+/*
+Reads tokens from a MessagePack stream. If a
+grammar is given, tokens get its kinds by ID;
+otherwise, or for IDs it does not have, kinds
+are made up of the IDs and names read, shared by
+the tokens of each.
+*/
+type MsgpackDecoder struct {
+	Provenance *Provenance // Read from the stream, if it has it.
+
+	r     *bufio.Reader
+	g     *Grammar
+	kinds map[tokenId]*TokenKind
+}
+
+/* Initialize a new `MsgpackDecoder`; `g` may be nil. */
+func NewMsgpackDecoder(r io.Reader, g *Grammar) *MsgpackDecoder {
+	return &MsgpackDecoder{r: bufio.NewReader(r), g: g, kinds: map[tokenId]*TokenKind{}}
+}
+
+/*
+Read the next token of the stream. Returns
+`io.EOF` once the stream ends between tokens.
+*/
+func (md *MsgpackDecoder) Decode() (TokenObject, error) {
+	for {
+		if _, err := md.r.Peek(1); err != nil {
+			return TokenObject{}, err
+		}
+		v, err := md.value()
+		if err != nil {
+			return TokenObject{}, unexpectedEOF(err)
+		}
+		if fields, ok := v.(map[string]any); ok && fields["provenance"] != nil {
+			if err := md.provenance(fields["provenance"]); err != nil {
+				return TokenObject{}, err
+			}
+			continue
+		}
+		return md.token(v)
+	}
+}
+
+/* Record the provenance read from the stream. */
+func (md *MsgpackDecoder) provenance(v any) error {
+	fields, ok := v.(map[string]any)
+	if !ok {
+		return errMsgpackFormat
+	}
+	get := func(name string) string {
+		s, _ := fields[name].(string)
+		return s
+	}
+	md.Provenance = &Provenance{
+		Lexer:           get("lexer"),
+		Grammar:         get("grammar"),
+		GrammarChecksum: get("grammar_checksum"),
+		Source:          get("source"),
+		SourceChecksum:  get("source_checksum"),
+		GrammarVersion:  get("grammar_version"),
+	}
+	return nil
+}
+
+/* Treat the stream ending mid token as an error. */
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+/* Build a token from its decoded map. */
+func (md *MsgpackDecoder) token(v any) (TokenObject, error) {
+	fields, ok := v.(map[string]any)
+	if !ok {
+		return TokenObject{}, errMsgpackFormat
+	}
+	kind, ok := fields["kind"].(map[string]any)
+	if !ok {
+		return TokenObject{}, errMsgpackFormat
+	}
+	id, okId := kind["id"].(uint64)
+	name, okName := kind["name"].(string)
+	line, okLine := fields["line"].(uint64)
+	pos, okPos := fields["position"].(uint64)
+	if !okId || !okName || !okLine || !okPos {
+		return TokenObject{}, errMsgpackFormat
+	}
+
+	to := TokenObject{Kind: md.kind(tokenId(id), tokenName(name)), LineNo: tokenLineNo(line), Position: tokenPosition(pos)}
+	switch sym := fields["symbol"].(type) {
+	case string:
+		to.Symbol = tokenSignature(sym)
+	case []byte:
+		to.Symbol = sym
+	default:
+		return TokenObject{}, errMsgpackFormat
+	}
+
+	switch value := fields["value"].(type) {
+	case uint64:
+		to.Value = int64(value)
+	case []any, map[string]any, []byte:
+		return TokenObject{}, errMsgpackFormat
+	default:
+		to.Value = value
+	}
+
+	if count, ok := fields["count"].(uint64); ok {
+		to.Count = int(count)
+	}
+	if origin, ok := fields["origin"]; ok {
+		var err error
+		if to.Origin, err = msgpackOrigin(origin); err != nil {
+			return TokenObject{}, err
+		}
+	}
+	if comments, ok := fields["comments"].([]any); ok {
+		for _, c := range comments {
+			comment, err := md.token(c)
+			if err != nil {
+				return TokenObject{}, err
+			}
+			to.Comments = append(to.Comments, comment)
+		}
+	}
+	return to, nil
+}
+
+/* Build the origin of a token from its decoded map. */
+func msgpackOrigin(v any) (*Origin, error) {
+	fields, ok := v.(map[string]any)
+	if !ok {
+		return nil, errMsgpackFormat
+	}
+	by, okBy := fields["by"].(string)
+	line, okLine := fields["line"].(uint64)
+	pos, okPos := fields["position"].(uint64)
+	if !okBy || !okLine || !okPos {
+		return nil, errMsgpackFormat
+	}
+
+	o := &Origin{By: by, LineNo: tokenLineNo(line), Position: tokenPosition(pos)}
+	o.Source, _ = fields["source"].(string)
+	kind, _ := fields["kind"].(string)
+	o.Kind = tokenName(kind)
+	switch sym := fields["symbol"].(type) {
+	case string:
+		o.Symbol = tokenSignature(sym)
+	case []byte:
+		o.Symbol = sym
+	default:
+		return nil, errMsgpackFormat
+	}
+	if parent, ok := fields["origin"]; ok {
+		var err error
+		if o.Origin, err = msgpackOrigin(parent); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+/* Retrieve the kind a token is of, by ID. */
+func (md *MsgpackDecoder) kind(id tokenId, name tokenName) *TokenKind {
+	if ref, ok := md.kinds[id]; ok && ref.Name == name {
+		return ref
+	}
+	var ref *TokenKind
+	if md.g != nil {
+		md.g.mu.RLock()
+		ref = md.g.kinds.Ref(id)
+		md.g.mu.RUnlock()
+	}
+	if ref == nil || ref.Name != name {
+		ref = &TokenKind{Id: id, Name: name}
+	}
+	md.kinds[id] = ref
+	return ref
+}
+
+/* Read `n` bytes. */
+func (md *MsgpackDecoder) bytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(md.r, b)
+	return b, err
+}
+
+/* Read a big endian unsigned integer of `n` bytes. */
+func (md *MsgpackDecoder) uint(n int) (uint64, error) {
+	b, err := md.bytes(n)
+	if err != nil {
+		return 0, err
+	}
+	v := uint64(0)
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+/*
+Read a value: nil, bool, uint64 for positive
+integers, int64, float64, string, []byte, []any
+or map[string]any.
+*/
+func (md *MsgpackDecoder) value() (any, error) {
+	c, err := md.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c < 0x80:
+		return uint64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return md.fields(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return md.items(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		b, err := md.bytes(int(c & 0x1f))
+		return string(b), err
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := md.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return md.bytes(int(n))
+	case 0xca:
+		n, err := md.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := md.uint(8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return md.uint(1 << (c - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		n, err := md.uint(size)
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, err
+	case 0xd9, 0xda, 0xdb:
+		n, err := md.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		b, err := md.bytes(int(n))
+		return string(b), err
+	case 0xdc, 0xdd:
+		n, err := md.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return md.items(int(n))
+	case 0xde, 0xdf:
+		n, err := md.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return md.fields(int(n))
+	}
+	return nil, fmt.Errorf("unsupported MessagePack type 0x%02x", c)
+}
+
+/* Read the items of an array. */
+func (md *MsgpackDecoder) items(n int) ([]any, error) {
+	items := []any{}
+	for i := 0; i < n; i++ {
+		v, err := md.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
+
+/* Read the fields of a map keyed by strings. */
+func (md *MsgpackDecoder) fields(n int) (map[string]any, error) {
+	fields := map[string]any{}
+	for i := 0; i < n; i++ {
+		key, err := md.value()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, errMsgpackFormat
+		}
+		if fields[name], err = md.value(); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,89 @@
+package lexer_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	if err := g.SetLiteral("GENIDEN", lexer.LiteralInt); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetLineComments("#"); err != nil {
+		t.Fatal(err)
+	}
+	lx := &lexer.Lexer{Grammar: g, Comments: lexer.CommentAttach}
+	tokens := lx.TokenizeLine("-7 + 300000 + \xff"+strings.Repeat("y", 40)+" # sum", 1)
+
+	var out bytes.Buffer
+	if err := (lexer.MsgpackFormatter{}).Format(&out, tokens); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := lexer.NewMsgpackDecoder(&out, g)
+	for i, want := range tokens {
+		got, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("token %d: %s", i, err)
+		}
+		if got.Kind != want.Kind || !got.EqualIgnoringPosition(want) || got.LineNo != want.LineNo || got.Position != want.Position || got.Value != want.Value || len(got.Comments) != len(want.Comments) {
+			t.Errorf("token %d: expected %#v, got %#v", i, want, got)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestMsgpackWithoutGrammar(t *testing.T) {
+	var out bytes.Buffer
+	enc := lexer.NewMsgpackEncoder(&out)
+	for _, to := range lexer.TokenizeLine("fn main", 1) {
+		if err := enc.Emit(to); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := lexer.NewMsgpackDecoder(bytes.NewReader(out.Bytes()[:out.Len()-1]), nil)
+	first, err := dec.Decode()
+	if err != nil || first.Kind.Name != "FN" || string(first.Symbol) != "fn" {
+		t.Errorf("unexpected token %v, %v", first, err)
+	}
+	dec.Decode()
+	if _, err := dec.Decode(); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected a truncated token, got %v", err)
+	}
+}
+
+func TestMsgpackOrigins(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	tokens := g.TokenizeLine("a b", 1)
+	tokens[0] = tokens[0].DeriveFrom("macro", "defs.pz").Derive("remap")
+	tokens[2] = tokens[2].Derive("action")
+	tokens[2].Origin.Symbol = []byte{0xff}
+
+	var out bytes.Buffer
+	if err := (lexer.MsgpackFormatter{}).Format(&out, tokens); err != nil {
+		t.Fatal(err)
+	}
+	read, err := g.ReadTokensMsgpack(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 3 || !reflect.DeepEqual(read[0].Origin, tokens[0].Origin) || read[1].Origin != nil || !reflect.DeepEqual(read[2].Origin, tokens[2].Origin) {
+		t.Errorf("expected origins read back, got %+v", read)
+	}
+	if chain := read[0].Origins(); len(chain) != 2 || chain[1].Source != "defs.pz" {
+		t.Errorf("expected the origin chain read back, got %v", chain)
+	}
+}
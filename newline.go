@@ -0,0 +1,90 @@
+package lexer
+
+import "fmt"
+
+/* --- LINE BREAKS ---
+Lines are read without their terminators, so
+line breaks are not tokens unless a lexer is set
+to emit them. Grammars whose statements end at a
+line break want them; most of all once collapsed,
+so parsers need not wade through a token per
+blank line.
+
+omit: Line breaks are left out.
+emit: A `NEWLINE` token ends each line read
+with a terminator.
+collapse: As with emit, but a run of line breaks,
+along with the whitespace of blank lines between
+them, is a single `NEWLINE` token, its `Count`
+the line breaks it stands for.
+
+Only input of many lines, read from files,
+readers or bytes, gives line breaks; a single
+line has none. */
+
+/* What a lexer does with line breaks. */
+type NewlineMode uint8
+
+const (
+	NewlineOmit NewlineMode = iota
+	NewlineEmit
+	NewlineCollapse
+)
+
+var newlineModeNames = []string{"omit", "emit", "collapse"}
+
+func (nm NewlineMode) String() string {
+	if int(nm) < len(newlineModeNames) {
+		return newlineModeNames[nm]
+	}
+	return fmt.Sprintf("NewlineMode(%d)", uint8(nm))
+}
+
+/* Look up a newline mode by its name, e.g. `collapse`. */
+func ParseNewlineMode(name string) (NewlineMode, error) {
+	for i, known := range newlineModeNames {
+		if name == known {
+			return NewlineMode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown newline mode %q, expected omit, emit or collapse", name)
+}
+
+/*
+End the line whose tokens start at index `start`
+of `tokens`, per the lexer's newline mode. The
+line is `width` bytes, ended by `terminator`; it
+has none if empty, as the last line of input may
+not.
+*/
+func (lx *Lexer) endLine(tokens TokenObjects, start int, width int, terminator string, lineNo tokenLineNo, run *lexRun) TokenObjects {
+	if lx.Newlines == NewlineOmit || terminator == "" {
+		return tokens
+	}
+	before := len(tokens)
+	defer func() {
+		if run.prof != nil {
+			run.prof.Tokens += len(tokens) - before
+		}
+	}()
+
+	collapse := lx.Newlines == NewlineCollapse
+	if collapse && allTrivia(tokens[start:]) {
+		tokens = tokens[:start]
+		if n := len(tokens); n > 0 && tokens[n-1].Kind != nil && tokens[n-1].Kind.Id == NewlineId {
+			tokens[n-1].Count++
+			return tokens
+		}
+	}
+
+	g := run.grammar(lx)
+	g.mu.RLock()
+	kind := g.kinds.Ref(NewlineId)
+	g.mu.RUnlock()
+
+	newline := TokenObject{Kind: kind, LineNo: lineNo, Position: tokenPosition(width + 1), Symbol: tokenSignature("\n")}
+	if collapse {
+		newline.Count = 1
+	}
+	return append(tokens, newline)
+}
@@ -0,0 +1,66 @@
+package lexer_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+/* Kind, line and count of each token, e.g. `NEWLINE@1x3`. */
+func renderNewlines(tokens []lexer.TokenObject) []string {
+	got := []string{}
+	for _, to := range tokens {
+		s := string(to.Kind.Name)
+		if to.Kind.Id == lexer.NewlineId {
+			s = fmt.Sprintf("%s@%dx%d", s, to.LineNo, to.Count)
+		}
+		got = append(got, s)
+	}
+	return got
+}
+
+func TestNewlineModes(t *testing.T) {
+	src := "a\n\n \t\nb\r\nc"
+	cases := []struct {
+		mode lexer.NewlineMode
+		want []string
+	}{
+		{lexer.NewlineOmit, []string{"GENIDEN", "WHTSPACE", "TABLINE", "GENIDEN", "GENIDEN"}},
+		{lexer.NewlineEmit, []string{"GENIDEN", "NEWLINE@1x0", "NEWLINE@2x0", "WHTSPACE", "TABLINE", "NEWLINE@3x0", "GENIDEN", "NEWLINE@4x0", "GENIDEN"}},
+		{lexer.NewlineCollapse, []string{"GENIDEN", "NEWLINE@1x3", "GENIDEN", "NEWLINE@4x1", "GENIDEN"}},
+	}
+	for _, c := range cases {
+		lx := &lexer.Lexer{Grammar: lexer.NewGrammar("x"), Newlines: c.mode}
+
+		tokens, err := lx.TokenizeBytes([]byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := renderNewlines(tokens); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s bytes: expected %v, got %v", c.mode, c.want, got)
+		}
+
+		index, err := lx.TokenizeText(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := renderNewlines(index.Tokens()); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s text: expected %v, got %v", c.mode, c.want, got)
+		}
+
+		name := writeSource(t, src)
+		tokens, err = lx.TokenizeFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := renderNewlines(tokens); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s file: expected %v, got %v", c.mode, c.want, got)
+		}
+	}
+
+	if _, err := lexer.ParseNewlineMode("fold"); err == nil {
+		t.Error("expected an error for an unknown newline mode")
+	}
+}
@@ -0,0 +1,22 @@
+package lexer
+
+import "golang.org/x/text/unicode/norm"
+
+/* --- NORMALIZATION ---
+The same identifier may be spelled with
+different code points, as `é` is either a single
+code point or `e` followed by a combining accent,
+which look alike but compare unequal. Lexers
+given a `Normalize` function normalize the
+symbols of generic identifiers with it, so such
+identifiers compare equal downstream; `NFC`
+normalizes them to Unicode Normalization Form C,
+composing characters where Unicode can. */
+
+/*
+Normalize a symbol to Unicode Normalization Form
+C, for use as a lexer's `Normalize`.
+*/
+func NFC(symbol string) string {
+	return norm.NFC.String(symbol)
+}
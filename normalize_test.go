@@ -0,0 +1,24 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestNFC(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "plus")
+
+	lx := &lexer.Lexer{Grammar: g, Normalize: lexer.NFC}
+	tokens := lx.TokenizeLine("cafe\u0301+caf\u00e9+\u1100\u1161", 1)
+	if len(tokens) != 5 || tokens[0].Symbol.String() != "caf\u00e9" || tokens[2].Symbol.String() != "caf\u00e9" {
+		t.Fatalf("expected composed identifiers, got %v", symbolsOf(tokens))
+	}
+	if tokens[4].Symbol.String() != "\uac00" {
+		t.Errorf("expected a composed Hangul syllable, got %q", tokens[4].Symbol)
+	}
+	if lexer.NFC("A\u030a") != "\u00c5" || lexer.NFC("plain") != "plain" {
+		t.Errorf("unexpected normalization")
+	}
+}
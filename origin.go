@@ -0,0 +1,95 @@
+package lexer
+
+import (
+	"fmt"
+	"io"
+)
+
+/* --- ORIGINS ---
+Not every token is found as is in the source;
+remapping and actions change the kinds of
+tokens, and tools built on the lexer expand
+macros, include other files or coalesce tokens.
+Tokens so produced carry an `Origin`, naming what
+produced them and the token they were produced
+from, itself perhaps produced from another.
+Diagnostics about them can then explain where
+they came from. Tokens found as is have none. */
+
+/* What produced a token, and from what. */
+type Origin struct {
+	By       string         `json:"by"`               // What produced the token, e.g. `remap`.
+	Source   string         `json:"source,omitempty"` // File of the token produced from, if another.
+	Kind     tokenName      `json:"kind,omitempty"`
+	LineNo   tokenLineNo    `json:"line"`
+	Position tokenPosition  `json:"position"`
+	Symbol   tokenSignature `json:"symbol"`
+	Origin   *Origin        `json:"origin,omitempty"` // Of the token produced from, if it was produced too.
+}
+
+/*
+Copy of the token, recording it as produced by
+`by` from this token, as found in the named
+source file; empty if the same source. The copy
+may then be changed as the producer sees fit.
+*/
+func (to TokenObject) DeriveFrom(by string, source string) TokenObject {
+	origin := &Origin{
+		By:       by,
+		Source:   source,
+		LineNo:   to.LineNo,
+		Position: to.Position,
+		Symbol:   to.Symbol,
+		Origin:   to.Origin,
+	}
+	if to.Kind != nil {
+		origin.Kind = to.Kind.Name
+	}
+	to.Origin = origin
+	return to
+}
+
+/* Copy of the token, recording it as produced by `by` from this token. */
+func (to TokenObject) Derive(by string) TokenObject {
+	return to.DeriveFrom(by, "")
+}
+
+/* Determine if the token was produced, rather than found as is. */
+func (to TokenObject) IsSynthetic() bool {
+	return to.Origin != nil
+}
+
+/*
+The origins of the token, from the token it was
+last produced from back to the one found in the
+source.
+*/
+func (to TokenObject) Origins() []Origin {
+	var origins []Origin
+	for o := to.Origin; o != nil; o = o.Origin {
+		origins = append(origins, *o)
+	}
+	return origins
+}
+
+/* Where the token produced from is. */
+func (o Origin) Pos() Position {
+	return Position{Filename: o.Source, Offset: -1, Line: int(o.LineNo), Column: int(o.Position)}
+}
+
+func (o Origin) String() string {
+	if o.Kind == "" {
+		return fmt.Sprintf("produced by %s from '%s' at %s", o.By, o.Symbol, o.Pos())
+	}
+	return fmt.Sprintf("produced by %s from %s '%s' at %s", o.By, o.Kind, o.Symbol, o.Pos())
+}
+
+/* Write a note for each origin of the token, as `FormatDiagnostic` does. */
+func formatOrigins(w io.Writer, tok TokenObject) error {
+	for o := tok.Origin; o != nil; o = o.Origin {
+		if _, err := fmt.Fprintf(w, "  = note: %s\n", o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package lexer_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestTokenOrigins(t *testing.T) {
+	g := lexer.NewGrammar("origin")
+	g.AddKind("PLUS", "+", "")
+	g.AddKind("ADD", "add", "")
+	tokens := g.TokenizeLine("a+b", 1)
+	if tokens[1].IsSynthetic() {
+		t.Fatalf("expected tokens found as is to have no origin")
+	}
+
+	remapped, err := g.RemapKinds(tokens, map[string]string{"PLUS": "ADD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded := remapped[1].DeriveFrom("macro", "lib.x")
+	expanded.LineNo, expanded.Position = 7, 3
+	if remapped[0].IsSynthetic() || !expanded.IsSynthetic() {
+		t.Errorf("expected only the remapped token to have an origin")
+	}
+	origins := expanded.Origins()
+	if len(origins) != 2 || origins[0].By != "macro" || origins[1].By != "remap" {
+		t.Fatalf("unexpected origins %v", origins)
+	}
+	if found := origins[1].String(); found != "produced by remap from PLUS '+' at 1:2" {
+		t.Errorf("unexpected origin %q", found)
+	}
+
+	var buf bytes.Buffer
+	lexer.FormatDiagnostic(&buf, lexer.NewSourceLines("a+b\n"), expanded, "odd")
+	expected := "7:3: odd\n" +
+		"  = note: produced by macro from ADD '+' at lib.x:1:2\n" +
+		"  = note: produced by remap from PLUS '+' at 1:2\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	lx := &lexer.Lexer{Grammar: g}
+	lx.OnToken("PLUS", func(ctx *lexer.TokenContext) { ctx.SetKind("ADD") })
+	acted := lx.TokenizeLine("a+b", 1)
+	if o := acted[1].Origins(); len(o) != 1 || o[0].By != "action" || o[0].Kind != "PLUS" {
+		t.Errorf("expected the action recorded, got %v", o)
+	}
+	buf.Reset()
+	if err := (lexer.JSONFormatter{}).Format(&buf, acted); err != nil {
+		t.Fatal(err)
+	}
+	read, err := g.ReadTokensJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := read[1].Origins(); len(o) != 1 || string(o[0].Symbol) != "+" || o[0].Position != 2 {
+		t.Errorf("expected origins read back, got %v", o)
+	}
+	if lexer.Anonymize(acted)[1].IsSynthetic() {
+		t.Errorf("expected anonymized tokens to drop their origins")
+	}
+}
@@ -0,0 +1,109 @@
+package lexer
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+/* --- PAGES ---
+A very large source gives a series of tokens
+too large to hand over in one piece. A result
+may be paged through instead, a page of tokens
+at a time. Each page carries an opaque cursor
+to the next, empty on the last page.
+
+Cursors are bound to the source they were taken
+from, by its checksum, so a cursor from one
+result is refused by another rather than
+silently giving tokens of the wrong source. */
+
+// Number of tokens per page if not given.
+const DefaultPageSize = 1000
+
+var ErrInvalidCursor = errors.New("lexer: invalid page cursor")
+
+/* A page of the tokens of a result. */
+type TokenPage struct {
+	Tokens TokenObjects `json:"tokens"`
+	Next   string       `json:"next,omitempty"` // Cursor of the next page; empty if last.
+	Total  int          `json:"total"`          // Number of tokens in the whole result.
+}
+
+/*
+Retrieve the page of up to `limit` tokens at
+`cursor`. An empty cursor is the first page; a
+limit of zero or less is `DefaultPageSize`.
+Returns `ErrInvalidCursor` if the cursor was not
+taken from a page of this result.
+*/
+func (tr TokenResult) Page(cursor string, limit int) (TokenPage, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	start := 0
+	if cursor != "" {
+		var err error
+		if start, err = tr.parseCursor(cursor); err != nil {
+			return TokenPage{}, err
+		}
+	}
+
+	end := start + limit
+	if end > len(tr.Tokens) {
+		end = len(tr.Tokens)
+	}
+	page := TokenPage{Tokens: tr.Tokens[start:end], Total: len(tr.Tokens)}
+	if end < len(tr.Tokens) {
+		page.Next = tr.cursorAt(end)
+	}
+	return page, nil
+}
+
+/*
+Call `fn` with each page of up to `limit` tokens
+in order, until it returns false.
+*/
+func (tr TokenResult) Pages(limit int, fn func(page TokenPage) bool) {
+	cursor := ""
+	for {
+		page, _ := tr.Page(cursor, limit)
+		if !fn(page) || page.Next == "" {
+			return
+		}
+		cursor = page.Next
+	}
+}
+
+/* Checksum binding cursors to the result's source. */
+func (tr TokenResult) cursorKey() string {
+	sum := tr.Provenance.SourceChecksum
+	if len(sum) > 16 {
+		sum = sum[:16]
+	}
+	return sum
+}
+
+/* Encode a cursor to the token at index `i`. */
+func (tr TokenResult) cursorAt(i int) string {
+	raw := strconv.Itoa(i) + ":" + tr.cursorKey()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+/* Decode a cursor to the index of a token. */
+func (tr TokenResult) parseCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	index, key, ok := strings.Cut(string(raw), ":")
+	if !ok || key != tr.cursorKey() {
+		return 0, ErrInvalidCursor
+	}
+	i, err := strconv.Atoi(index)
+	if err != nil || i <= 0 || i >= len(tr.Tokens) {
+		return 0, ErrInvalidCursor
+	}
+	return i, nil
+}
@@ -0,0 +1,56 @@
+package lexer_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestResultPages(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	result, err := g.TokenizeSource("sum.x", strings.NewReader("a+b+c\nd+e\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got lexer.TokenObjects
+	pages := 0
+	cursor := ""
+	for {
+		page, err := result.Page(cursor, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != result.Len() || len(page.Tokens) > 3 {
+			t.Fatalf("unexpected page %v", page)
+		}
+		got = append(got, page.Tokens...)
+		pages++
+		if cursor = page.Next; cursor == "" {
+			break
+		}
+	}
+	if pages != 3 || renderKinds(got) != renderKinds(result.Tokens) {
+		t.Errorf("expected every token over 3 pages, got %d pages of %s", pages, renderKinds(got))
+	}
+
+	counted := 0
+	result.Pages(4, func(page lexer.TokenPage) bool {
+		counted++
+		return false
+	})
+	if counted != 1 {
+		t.Errorf("expected paging to stop, got %d pages", counted)
+	}
+
+	first, _ := result.Page("", 4)
+	other, _ := g.TokenizeSource("other.x", strings.NewReader("a+b+c+d+e\n"))
+	for _, cursor := range []string{first.Next, "not a cursor", "OTk5OTk"} {
+		if _, err := other.Page(cursor, 4); !errors.Is(err, lexer.ErrInvalidCursor) {
+			t.Errorf("expected cursor %q to be refused, got %v", cursor, err)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package lexer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+/* --- PARQUET EXPORT ---
+Tokens may be written as Parquet, a columnar
+format read by Spark, DuckDB and the like, for
+corpora too large for JSON or CSV. Each token is
+a row of five columns:
+
+file: Name of the source, as set on the writer.
+line, col: Line and position of the token.
+kind: Name of the token's kind.
+symbol: Text of the token.
+
+Rows are written in row groups of
+`RowGroupSize` rows, so memory use stays bounded
+however many tokens are written. Values are
+written plainly, without compression; the
+footer is only written on `Close`. */
+
+// Rows per row group if `ParquetWriter.RowGroupSize` is 0.
+const DefaultRowGroupSize = 1 << 16
+
+// Parquet physical types, encodings and such
+// used below; see parquet.thrift.
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+	parquetUTF8      = 0 // Converted type of strings.
+	parquetRequired  = 0
+	parquetPlain     = 0
+	parquetRLE       = 3
+	parquetDataPage  = 0
+)
+
+var parquetMagic = []byte("PAR1")
+
+var errParquetClosed = errors.New("parquet writer is closed")
+
+/* A column of a row group being gathered. */
+type parquetColumn struct {
+	name  string
+	typ   int32
+	data  []byte // Values, plainly encoded.
+	count int
+}
+
+/* Where a column chunk was written. */
+type parquetChunk struct {
+	offset int64 // Of the page header.
+	size   int64 // Of the page header and values.
+	values int64
+}
+
+/* A row group written, for the footer. */
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	size   int64
+	rows   int64
+}
+
+/*
+Writes tokens as a Parquet file. It is a token
+sink; `Flush` ends the current row group, and
+`Close` writes the footer.
+*/
+type ParquetWriter struct {
+	Source       string // Name of the file tokens emitted next are from.
+	RowGroupSize int    // Rows per row group; `DefaultRowGroupSize` if 0.
+
+	w       *bufio.Writer
+	offset  int64
+	columns [5]parquetColumn
+	rows    int
+	groups  []parquetRowGroup
+	total   int64
+	started bool
+	closed  bool
+}
+
+/* Initialize a new `ParquetWriter`. */
+func NewParquetWriter(w io.Writer) *ParquetWriter {
+	return &ParquetWriter{
+		w: bufio.NewWriter(w),
+		columns: [5]parquetColumn{
+			{name: "file", typ: parquetByteArray},
+			{name: "line", typ: parquetInt64},
+			{name: "col", typ: parquetInt64},
+			{name: "kind", typ: parquetByteArray},
+			{name: "symbol", typ: parquetByteArray},
+		},
+	}
+}
+
+/* Append plainly encoded values to a column. */
+func (pc *parquetColumn) putString(s string) {
+	pc.data = appendUint32(pc.data, uint32(len(s)))
+	pc.data = append(pc.data, s...)
+	pc.count += 1
+}
+
+func (pc *parquetColumn) putInt(n int64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(n))
+	pc.data = append(pc.data, b[:]...)
+	pc.count += 1
+}
+
+/* Append a little endian `uint32`. */
+func appendUint32(b []byte, n uint32) []byte {
+	var le [4]byte
+	binary.LittleEndian.PutUint32(le[:], n)
+	return append(b, le[:]...)
+}
+
+func (pw *ParquetWriter) Emit(to TokenObject) error {
+	if pw.closed {
+		return errParquetClosed
+	}
+	pw.columns[0].putString(pw.Source)
+	pw.columns[1].putInt(int64(to.LineNo))
+	pw.columns[2].putInt(int64(to.Position))
+	pw.columns[3].putString(string(to.Kind.Name))
+	pw.columns[4].putString(string(to.Symbol))
+	pw.rows += 1
+
+	size := pw.RowGroupSize
+	if size <= 0 {
+		size = DefaultRowGroupSize
+	}
+	if pw.rows >= size {
+		return pw.writeRowGroup()
+	}
+	return nil
+}
+
+/* Write the tokens of a result, under its source name. */
+func (pw *ParquetWriter) WriteResult(tr TokenResult) error {
+	pw.Source = tr.Source
+	for _, to := range tr.Tokens {
+		if err := pw.Emit(to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* End the current row group, writing it out. */
+func (pw *ParquetWriter) Flush() error {
+	if pw.closed {
+		return errParquetClosed
+	}
+	if err := pw.writeRowGroup(); err != nil {
+		return err
+	}
+	return pw.w.Flush()
+}
+
+/*
+Write any rows left and the footer, completing
+the file. The underlying writer is not closed.
+*/
+func (pw *ParquetWriter) Close() error {
+	if pw.closed {
+		return nil
+	}
+	if err := pw.writeRowGroup(); err != nil {
+		return err
+	}
+	if err := pw.start(); err != nil {
+		return err
+	}
+	pw.closed = true
+
+	footer := pw.footer()
+	if _, err := pw.w.Write(footer); err != nil {
+		return err
+	}
+	size := appendUint32(nil, uint32(len(footer)))
+	if _, err := pw.w.Write(append(size, parquetMagic...)); err != nil {
+		return err
+	}
+	return pw.w.Flush()
+}
+
+/* Write the magic bytes opening the file, once. */
+func (pw *ParquetWriter) start() error {
+	if pw.started {
+		return nil
+	}
+	pw.started = true
+	return pw.write(parquetMagic)
+}
+
+func (pw *ParquetWriter) write(b []byte) error {
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	return err
+}
+
+/* Write the rows gathered as a row group of one page per column. */
+func (pw *ParquetWriter) writeRowGroup() error {
+	if pw.rows == 0 {
+		return nil
+	}
+	if err := pw.start(); err != nil {
+		return err
+	}
+
+	group := parquetRowGroup{rows: int64(pw.rows)}
+	for i := range pw.columns {
+		col := &pw.columns[i]
+		header := parquetPageHeader(col)
+		chunk := parquetChunk{offset: pw.offset, size: int64(len(header) + len(col.data)), values: int64(col.count)}
+
+		if err := pw.write(header); err != nil {
+			return err
+		}
+		if err := pw.write(col.data); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.size += chunk.size
+		col.data, col.count = col.data[:0], 0
+	}
+	pw.groups = append(pw.groups, group)
+	pw.total += group.rows
+	pw.rows = 0
+	return nil
+}
+
+/* Encode the header of a page holding a column's values. */
+func parquetPageHeader(col *parquetColumn) []byte {
+	var e thriftEncoder
+	e.i32(1, parquetDataPage)
+	e.i32(2, int32(len(col.data)))
+	e.i32(3, int32(len(col.data)))
+	e.begin(5)
+	e.i32(1, int32(col.count))
+	e.i32(2, parquetPlain)
+	e.i32(3, parquetRLE)
+	e.i32(4, parquetRLE)
+	e.end()
+	e.stop()
+	return e.buf
+}
+
+/* Encode the file's metadata. */
+func (pw *ParquetWriter) footer() []byte {
+	var e thriftEncoder
+	e.i32(1, 1)
+
+	e.list(2, thriftStruct, len(pw.columns)+1)
+	e.begin(0)
+	e.binary(4, "schema")
+	e.i32(5, int32(len(pw.columns)))
+	e.end()
+	for _, col := range pw.columns {
+		e.begin(0)
+		e.i32(1, col.typ)
+		e.i32(3, parquetRequired)
+		e.binary(4, col.name)
+		if col.typ == parquetByteArray {
+			e.i32(6, parquetUTF8)
+		}
+		e.end()
+	}
+
+	e.i64(3, pw.total)
+	e.list(4, thriftStruct, len(pw.groups))
+	for _, group := range pw.groups {
+		e.begin(0)
+		e.list(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			col := pw.columns[i]
+			e.begin(0)
+			e.i64(2, chunk.offset)
+			e.begin(3)
+			e.i32(1, col.typ)
+			e.list(2, thriftI32, 1)
+			e.varint(parquetPlain)
+			e.list(3, thriftBinary, 1)
+			e.str(col.name)
+			e.i32(4, 0) // Uncompressed.
+			e.i64(5, chunk.values)
+			e.i64(6, chunk.size)
+			e.i64(7, chunk.size)
+			e.i64(9, chunk.offset)
+			e.end()
+			e.end()
+		}
+		e.i64(2, group.size)
+		e.i64(3, group.rows)
+		e.end()
+	}
+	e.binary(6, "panza-lexer")
+	e.stop()
+	return e.buf
+}
+
+/* --- THRIFT ---
+Parquet metadata is encoded with Thrift's compact
+protocol; only what the writer above needs of it
+is implemented. */
+
+// Compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+/* Encodes a struct, with those nested in it. */
+type thriftEncoder struct {
+	buf  []byte
+	last []int16 // ID of the last field written, per struct nested.
+}
+
+/* ID of the last field written to the struct being written. */
+func (e *thriftEncoder) lastId() *int16 {
+	if len(e.last) == 0 {
+		e.last = []int16{0}
+	}
+	return &e.last[len(e.last)-1]
+}
+
+/* Write the header of a field. */
+func (e *thriftEncoder) field(id int16, typ byte) {
+	last := e.lastId()
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		e.buf = append(e.buf, byte(delta)<<4|typ)
+	} else {
+		e.buf = append(e.buf, typ)
+		e.varint(int64(id))
+	}
+	*last = id
+}
+
+/* Write a zigzag encoded varint. */
+func (e *thriftEncoder) varint(n int64) {
+	e.uvarint(uint64(n<<1 ^ n>>63))
+}
+
+/* Write a varint, as sizes are. */
+func (e *thriftEncoder) uvarint(n uint64) {
+	var b [binary.MaxVarintLen64]byte
+	e.buf = append(e.buf, b[:binary.PutUvarint(b[:], n)]...)
+}
+
+func (e *thriftEncoder) str(s string) {
+	e.uvarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *thriftEncoder) i32(id int16, n int32) {
+	e.field(id, thriftI32)
+	e.varint(int64(n))
+}
+
+func (e *thriftEncoder) i64(id int16, n int64) {
+	e.field(id, thriftI64)
+	e.varint(n)
+}
+
+func (e *thriftEncoder) binary(id int16, s string) {
+	e.field(id, thriftBinary)
+	e.str(s)
+}
+
+/*
+Write the header of a list field; its elements
+are written after.
+*/
+func (e *thriftEncoder) list(id int16, elem byte, size int) {
+	e.field(id, thriftList)
+	if size < 15 {
+		e.buf = append(e.buf, byte(size)<<4|elem)
+		return
+	}
+	e.buf = append(e.buf, 0xf0|elem)
+	e.uvarint(uint64(size))
+}
+
+/*
+Begin a struct; that of a field, or an element
+of a list if `id` is 0.
+*/
+func (e *thriftEncoder) begin(id int16) {
+	if id != 0 {
+		e.field(id, thriftStruct)
+	}
+	e.lastId()
+	e.last = append(e.last, 0)
+}
+
+/* End a struct begun with `begin`. */
+func (e *thriftEncoder) end() {
+	e.stop()
+	e.last = e.last[:len(e.last)-1]
+}
+
+/* Write the stop byte ending a struct. */
+func (e *thriftEncoder) stop() {
+	e.buf = append(e.buf, 0)
+}
@@ -0,0 +1,209 @@
+package lexer_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestParquetWriter(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	result, err := g.TokenizeSource("sum.x", strings.NewReader("ab+c\nd\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	pw := lexer.NewParquetWriter(&out)
+	pw.RowGroupSize = 2
+	if err := pw.WriteResult(result); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file := out.Bytes()
+	if !bytes.HasPrefix(file, []byte("PAR1")) || !bytes.HasSuffix(file, []byte("PAR1")) {
+		t.Fatalf("expected Parquet magic at both ends, got %q", file)
+	}
+	footer := binary.LittleEndian.Uint32(file[len(file)-8:])
+	if int(footer) >= len(file)-12 {
+		t.Errorf("footer of %d bytes overruns the file", footer)
+	}
+	// The first row group holds the symbols 'ab' and
+	// '+', each with a little endian length.
+	if !bytes.Contains(file, []byte("\x02\x00\x00\x00ab\x01\x00\x00\x00+")) {
+		t.Errorf("expected plainly encoded symbols, got %q", file)
+	}
+
+	if err := pw.Emit(result.Tokens[0]); err == nil {
+		t.Error("expected an error emitting to a closed writer")
+	}
+}
+
+// Decodes Thrift compact structs, as maps of
+// field ID to value, to check Parquet metadata.
+type thriftDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *thriftDecoder) byte() byte {
+	if len(d.buf) == 0 {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b
+}
+
+func (d *thriftDecoder) uvarint() uint64 {
+	n, size := binary.Uvarint(d.buf)
+	if size <= 0 {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	d.buf = d.buf[size:]
+	return n
+}
+
+func (d *thriftDecoder) varint() int64 {
+	n := d.uvarint()
+	return int64(n>>1) ^ -int64(n&1)
+}
+
+func (d *thriftDecoder) value(typ byte) any {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case 5, 6:
+		return d.varint()
+	case 8:
+		size := int(d.uvarint())
+		if size > len(d.buf) {
+			d.err = io.ErrUnexpectedEOF
+			return ""
+		}
+		s := string(d.buf[:size])
+		d.buf = d.buf[size:]
+		return s
+	case 9:
+		header := d.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(d.uvarint())
+		}
+		list := []any{}
+		for i := 0; i < size && d.err == nil; i++ {
+			list = append(list, d.value(header&0x0f))
+		}
+		return list
+	case 12:
+		return d.structure()
+	}
+	d.err = fmt.Errorf("unexpected thrift type %d", typ)
+	return nil
+}
+
+func (d *thriftDecoder) structure() map[int16]any {
+	fields := map[int16]any{}
+	var id int16
+	for d.err == nil {
+		header := d.byte()
+		if header == 0 {
+			break
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(d.varint())
+		}
+		fields[id] = d.value(header & 0x0f)
+	}
+	return fields
+}
+
+func TestParquetFooter(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	result, err := g.TokenizeSource("sum.x", strings.NewReader("ab+c\nd\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	pw := lexer.NewParquetWriter(&out)
+	pw.RowGroupSize = 2
+	if err := pw.WriteResult(result); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file := out.Bytes()
+	size := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	d := &thriftDecoder{buf: file[len(file)-8-size : len(file)-8]}
+	meta := d.structure()
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+
+	rows := len(result.Tokens)
+	if meta[3] != int64(rows) {
+		t.Errorf("expected %d rows, got %v", rows, meta[3])
+	}
+	columns := []string{}
+	for _, elem := range meta[2].([]any)[1:] {
+		columns = append(columns, elem.(map[int16]any)[4].(string))
+	}
+	if strings.Join(columns, ",") != "file,line,col,kind,symbol" {
+		t.Errorf("expected the token columns, got %v", columns)
+	}
+
+	// Read each row group's pages back by the
+	// offsets of its column chunks.
+	groups := meta[4].([]any)
+	if len(groups) != (rows+1)/2 {
+		t.Errorf("expected %d row groups, got %d", (rows+1)/2, len(groups))
+	}
+	var symbols, lines []string
+	for _, group := range groups {
+		chunks := group.(map[int16]any)[1].([]any)
+		for i, chunk := range chunks {
+			offset := chunk.(map[int16]any)[2].(int64)
+			page := &thriftDecoder{buf: file[offset:]}
+			header := page.structure()
+			data := page.buf[:header[2].(int64)]
+			count := int(header[5].(map[int16]any)[1].(int64))
+			for n := 0; n < count; n++ {
+				switch i {
+				case 1:
+					lines = append(lines, fmt.Sprint(binary.LittleEndian.Uint64(data)))
+					data = data[8:]
+				case 4:
+					length := binary.LittleEndian.Uint32(data)
+					symbols = append(symbols, string(data[4:4+length]))
+					data = data[4+length:]
+				}
+			}
+		}
+	}
+	var wantSymbols, wantLines []string
+	for _, to := range result.Tokens {
+		wantSymbols = append(wantSymbols, string(to.Symbol))
+		wantLines = append(wantLines, fmt.Sprint(to.LineNo))
+	}
+	if !reflect.DeepEqual(symbols, wantSymbols) || !reflect.DeepEqual(lines, wantLines) {
+		t.Errorf("expected symbols %q on lines %v, got %q on %v", wantSymbols, wantLines, symbols, lines)
+	}
+}
@@ -0,0 +1,126 @@
+package lexer
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+/* --- PLAYGROUND ---
+A grammar playground tokenizes source against a
+grammar given alongside it, so grammar authors
+may see the effect of each change live. Most
+requests give a grammar seen moments before, so
+compiled grammars are kept in a `GrammarCache`
+by the checksum of their definition rather than
+loaded again per request. Grammars exceeding the
+cache's limits are refused. */
+
+// Number of grammars a cache keeps if not given.
+const DefaultGrammarCacheSize = 64
+
+/*
+Keeps grammars compiled from their definitions,
+in the tokens file format, evicting those least
+recently used past its size. Safe for concurrent
+use.
+*/
+type GrammarCache struct {
+	size int
+
+	// Most grammars compiled by the cache may hold; set before
+	// first use.
+	Limits GrammarLimits
+
+	mu      sync.Mutex
+	order   *list.List               // Of `*cachedGrammar`, most recently used first.
+	entries map[string]*list.Element // By checksum of the definition.
+}
+
+type cachedGrammar struct {
+	key     string
+	grammar *Grammar
+}
+
+/*
+Initialize a new, empty `GrammarCache` of the
+given size; `DefaultGrammarCacheSize` if zero or
+less.
+*/
+func NewGrammarCache(size int) *GrammarCache {
+	if size <= 0 {
+		size = DefaultGrammarCacheSize
+	}
+	return &GrammarCache{size: size, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+/* Number of grammars in the cache. */
+func (gc *GrammarCache) Len() int {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	return gc.order.Len()
+}
+
+/*
+Retrieve the grammar of the given definition,
+compiling it if not in the cache. Definitions
+failing to compile, or exceeding the cache's
+limits, are not kept. The grammar
+returned is shared; it must not be modified.
+*/
+func (gc *GrammarCache) Compile(definition string) (*Grammar, error) {
+	sum := sha256.Sum256([]byte(definition))
+	key := hex.EncodeToString(sum[:])
+
+	gc.mu.Lock()
+	if el, ok := gc.entries[key]; ok {
+		gc.order.MoveToFront(el)
+		gc.mu.Unlock()
+		return el.Value.(*cachedGrammar).grammar, nil
+	}
+	gc.mu.Unlock()
+
+	// Compiled unlocked, so a large definition does not hold up
+	// requests for others.
+	g := NewGrammar("playground-" + key[:12])
+	if err := g.LoadTokensLimited(strings.NewReader(definition), gc.Limits); err != nil {
+		return nil, err
+	}
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if el, ok := gc.entries[key]; ok {
+		gc.order.MoveToFront(el)
+		return el.Value.(*cachedGrammar).grammar, nil
+	}
+	gc.entries[key] = gc.order.PushFront(&cachedGrammar{key, g})
+	for gc.order.Len() > gc.size {
+		oldest := gc.order.Remove(gc.order.Back()).(*cachedGrammar)
+		delete(gc.entries, oldest.key)
+	}
+	return g, nil
+}
+
+/*
+Break down `source` into tokens against the
+grammar of the given definition, gathering
+everything tokenizing gives into a result. The
+lexer's options apply, if given, save its
+grammar.
+*/
+func (gc *GrammarCache) TokenizeSource(lx *Lexer, definition string, name string, source string) (TokenResult, error) {
+	g, err := gc.Compile(definition)
+	if err != nil {
+		return TokenResult{Source: name}, err
+	}
+	run := Lexer{}
+	if lx != nil {
+		run = *lx
+	}
+	run.Grammar = g
+	return run.TokenizeSource(name, strings.NewReader(source))
+}
@@ -0,0 +1,63 @@
+package lexer_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestGrammarCache(t *testing.T) {
+	cache := lexer.NewGrammarCache(2)
+	plus, err := cache.Compile("PLUS +\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := cache.Compile("PLUS +\n"); again != plus {
+		t.Error("expected the compiled grammar to be reused")
+	}
+	if _, err := cache.Compile("EMPTY \"\"\n"); err == nil || cache.Len() != 1 {
+		t.Errorf("expected a failing definition not to be kept, got %v", err)
+	}
+
+	cache.Compile("MINUS -\n")
+	cache.Compile("PLUS +\n")
+	cache.Compile("STAR *\n")
+	if cache.Len() != 2 {
+		t.Errorf("expected 2 grammars, got %d", cache.Len())
+	}
+	if again, _ := cache.Compile("PLUS +\n"); again != plus {
+		t.Error("expected the recently used grammar to be kept")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Compile("SLASH /\n")
+		}()
+	}
+	wg.Wait()
+}
+
+func TestGrammarCacheTokenize(t *testing.T) {
+	cache := lexer.NewGrammarCache(0)
+	lx := &lexer.Lexer{Fallback: "PLUS"}
+
+	result, err := cache.TokenizeSource(lx, "PLUS +\n", "a.x", "a + b\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := renderKinds(result.Significant()); got != "PLUS:a PLUS:+ PLUS:b" {
+		t.Errorf("unexpected tokens %s", got)
+	}
+	if lx.Grammar != nil {
+		t.Error("expected the lexer to be left untouched")
+	}
+
+	if _, err := cache.TokenizeSource(nil, "PLUS +\n@bogus\n", "a.x", "a"); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected the definition's error, got %v", err)
+	}
+}
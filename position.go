@@ -0,0 +1,112 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+)
+
+/* --- POSITIONS ---
+Where a token or error is, in the manner of
+`go/token`, so messages read `file.pz:3:14` and
+editors and CI logs can link to them. Tokens
+only know their line and column; a line index or
+result locates them in their source as well. */
+
+/*
+A place in source text. Lines and columns are
+counted from 1, columns in bytes; offsets from
+0, or -1 if not known.
+*/
+type Position struct {
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
+}
+
+/* Determine if the position has a line. */
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+/*
+Render the position as `file:line:column`,
+leaving out what is not known; `-` if nothing is.
+*/
+func (p Position) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += strconv.Itoa(p.Line)
+		if p.Column > 0 {
+			s += fmt.Sprintf(":%d", p.Column)
+		}
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
+/* Position of this token within its line. */
+func (to TokenObject) Pos() Position {
+	return Position{Offset: -1, Line: int(to.LineNo), Column: int(to.Position)}
+}
+
+/*
+Position of a token of the indexed input, with
+its offset if the line was recorded.
+*/
+func (li LineIndex) Position(to TokenObject) Position {
+	pos := to.Pos()
+	if line, ok := li.Input(to.LineNo); ok && pos.Column > 0 {
+		pos.Offset = line.Offset + pos.Column - 1
+	}
+	return pos
+}
+
+/*
+Position of a token of the result, within its
+source.
+*/
+func (tr TokenResult) Position(to TokenObject) Position {
+	pos := tr.Index.Position(to)
+	pos.Filename = tr.Source
+	return pos
+}
+
+/* The original text of a line of the result's source. */
+func (tr TokenResult) Text(lineNo tokenLineNo) (string, bool) {
+	return tr.Index.Text(lineNo)
+}
+
+/*
+Locates tokens in their source; line sources
+may also, for messages to name their file.
+*/
+type positionSource interface {
+	Position(to TokenObject) Position
+}
+
+/* Name the file of an error about unknown input, or matching giving up. */
+func withFilename(err error, name string) error {
+	switch e := err.(type) {
+	case UnknownInputError:
+		e.Filename = name
+		return e
+	case StepLimitError:
+		e.Filename = name
+		return e
+	}
+	return err
+}
+
+/* Position of a token per the line source, if it knows it. */
+func sourcePosition(src LineSource, tok TokenObject) Position {
+	if ps, ok := src.(positionSource); ok {
+		return ps.Position(tok)
+	}
+	return tok.Pos()
+}
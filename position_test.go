@@ -0,0 +1,51 @@
+package lexer_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestPositionString(t *testing.T) {
+	cases := []struct {
+		pos  lexer.Position
+		want string
+	}{
+		{lexer.Position{Filename: "a.pz", Line: 3, Column: 14}, "a.pz:3:14"},
+		{lexer.Position{Filename: "a.pz", Line: 3}, "a.pz:3"},
+		{lexer.Position{Line: 3, Column: 14}, "3:14"},
+		{lexer.Position{Filename: "a.pz"}, "a.pz"},
+		{lexer.Position{}, "-"},
+	}
+	for _, c := range cases {
+		if got := c.pos.String(); got != c.want {
+			t.Errorf("expected %s, got %s", c.want, got)
+		}
+	}
+}
+
+func TestResultPositions(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	result, err := g.TokenizeSource("sum.x", strings.NewReader("a\r\nb + c\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plus := result.OfKind("PLUS")[0]
+	if pos := result.Position(plus); pos != (lexer.Position{Filename: "sum.x", Offset: 5, Line: 2, Column: 3}) {
+		t.Errorf("unexpected position %#v", pos)
+	}
+	if pos := plus.Pos(); pos.String() != "2:3" || pos.Offset != -1 {
+		t.Errorf("unexpected position %#v", pos)
+	}
+
+	lx := &lexer.Lexer{Grammar: g, Strict: true}
+	_, err = lx.TokenizeSource("sum.x", strings.NewReader("+ $\n"))
+	var unknown lexer.UnknownInputError
+	if !errors.As(err, &unknown) || err.Error() != "sum.x:1:3: unknown input '$'" {
+		t.Errorf("expected the error to name the file, got %v", err)
+	}
+}
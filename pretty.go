@@ -0,0 +1,119 @@
+package lexer
+
+import "strings"
+
+/* --- PRETTY-PRINTING ---
+Simple languages are formatted from their tokens
+alone, by rules per category of token: binary
+operators spaced on either side, statements
+ending a line after their terminator, and blocks
+between braces broken onto lines of their own
+and indented. Otherwise, tokens keep the line
+they were on, runs of blank lines collapse to
+one, and tokens spaced apart get a single space.
+Whitespace is only ever collapsed to a space or
+line break, never dropped, so the printed text
+tokenizes into the same tokens. */
+
+/* Rules of the pretty-printer. */
+type PrettyConfig struct {
+	Indent         string   // Written per level of a block; a tab if empty.
+	SpaceOperators bool     // Space binary operators on either side.
+	Terminators    []string // Signatures of kinds ending a statement, e.g. `;`.
+	BlockOpen      []string // Signatures of kinds opening a block, e.g. `{`.
+	BlockClose     []string // Signatures of kinds closing a block, e.g. `}`.
+}
+
+/* Rules of the pretty-printer for C-like languages. */
+func DefaultPrettyConfig() PrettyConfig {
+	return PrettyConfig{
+		Indent:         "\t",
+		SpaceOperators: true,
+		Terminators:    []string{";"},
+		BlockOpen:      []string{"{"},
+		BlockClose:     []string{"}"},
+	}
+}
+
+/*
+Re-print the tokens per the rules, ending in a
+line break. Comments are kept; whitespace is
+replaced. The tokens are expected in order, as
+the tokenizer produces them.
+*/
+func (pc PrettyConfig) Print(tokens TokenObjects) string {
+	indent := pc.Indent
+	if indent == "" {
+		indent = "\t"
+	}
+
+	var out strings.Builder
+	depth := 0
+	prev := -1 // Index of the last token written.
+	for i, to := range tokens {
+		if to.Kind == nil || to.IsTrivia() {
+			continue
+		}
+		if containsString(pc.BlockClose, string(to.Kind.Signature)) && depth > 0 {
+			depth--
+		}
+
+		if prev >= 0 {
+			sep := pc.separator(tokens, prev, i)
+			if strings.HasSuffix(sep, "\n") {
+				sep += strings.Repeat(indent, depth)
+			}
+			out.WriteString(sep)
+		}
+		out.Write(to.Symbol)
+
+		if containsString(pc.BlockOpen, string(to.Kind.Signature)) {
+			depth++
+		}
+		prev = i
+	}
+	if prev >= 0 {
+		out.WriteByte('\n')
+	}
+	return out.String()
+}
+
+/*
+The whitespace between the tokens at indexes
+`prev` and `i`, before indentation.
+*/
+func (pc PrettyConfig) separator(tokens TokenObjects, prev, i int) string {
+	p, to := tokens[prev], tokens[i]
+	is := func(to TokenObject, sigs []string) bool {
+		return containsString(sigs, string(to.Kind.Signature))
+	}
+	switch {
+	case to.Kind.Id == CommentId && to.LineNo == p.LineNo:
+		// Trailing comments stay on their line.
+		return " "
+	case to.LineNo > p.LineNo+1:
+		return "\n\n"
+	case to.LineNo > p.LineNo || p.Kind.Id == CommentId ||
+		is(p, pc.Terminators) || is(p, pc.BlockOpen) || is(to, pc.BlockClose):
+		return "\n"
+	case pc.SpaceOperators && (isBinaryOperator(tokens, i) || isBinaryOperator(tokens, prev)):
+		return " "
+	case prev < i-1:
+		// Spaced apart in the source.
+		return " "
+	}
+	return ""
+}
+
+/*
+Determine if the token at index `i` is a binary
+operator; an operator after an operand.
+*/
+func isBinaryOperator(tokens TokenObjects, i int) bool {
+	to := tokens[i]
+	if to.Kind == nil || to.Kind.Id < FirstUserKindId || !isOperatorSig(to.Kind.Signature) {
+		return false
+	}
+	prev := tokens.CursorAt(i).PrevSignificant()
+	return prev.Valid() && endsOperand(prev.Token())
+}
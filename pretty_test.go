@@ -0,0 +1,35 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestPrettyPrint(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	for _, kind := range []struct{ name, sig string }{
+		{"PLUS", "+"}, {"MINUS", "-"}, {"ASSIGN", "="}, {"EQ", "=="},
+		{"LPAREN", "("}, {"RPAREN", ")"}, {"LBRACE", "{"}, {"RBRACE", "}"}, {"SEMI", ";"},
+	} {
+		g.AddKind(kind.name, kind.sig, "")
+	}
+	if err := g.SetLineComments("//"); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := g.TokenizeText("f(a) { x=a+1; y = -x;  // neg\n\n\n   if (x==y) { g(x); }\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := lexer.DefaultPrettyConfig().Print(index.Tokens())
+	want := "f(a) {\n\tx = a + 1;\n\ty = -x; // neg\n\n\tif (x == y) {\n\t\tg(x);\n\t}\n}\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	pc := lexer.PrettyConfig{Indent: "  "}
+	if got, want := pc.Print(index.Tokens()), "f(a) { x=a+1; y = -x; // neg\n\nif (x==y) { g(x); }\n}\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,127 @@
+package lexer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+/* --- PROVENANCE ---
+Serialized tokens go stale when either their
+source or the grammar that tokenized it changes.
+Results record where they came from: the lexer
+version, the grammar's name and a checksum of
+its definition, and the source's name and a
+checksum of its content. Downstream systems may
+compare these against the current source and
+grammar before trusting stored tokens.
+
+Formatters implementing `ResultFormatter` write
+this alongside the tokens. */
+
+// Version of the lexer, recorded in the provenance
+// of results.
+const Version = "0.1.0"
+
+/* Where a series of tokens came from. */
+type Provenance struct {
+	Lexer           string `json:"lexer"`                     // Version of the lexer.
+	Grammar         string `json:"grammar"`                   // Name of the grammar.
+	GrammarChecksum string `json:"grammar_checksum"`          // From `Grammar.Checksum`.
+	GrammarVersion  string `json:"grammar_version,omitempty"` // `Grammar.Version`, if declared.
+	Source          string `json:"source,omitempty"`          // Name of the source.
+	SourceChecksum  string `json:"source_checksum,omitempty"` // SHA-256 of the source, in hex.
+}
+
+/*
+Checksum the definition of this grammar: its
+kinds with their IDs, and how they are matched.
+Grammars tokenizing input alike have equal
+checksums; the SHA-256 is returned in hex.
+*/
+func (g *Grammar) Checksum() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	h := sha256.New()
+	for _, id := range g.kinds.Ids() {
+		kind := g.kinds.Get(id)
+		fmt.Fprintf(h, "kind %d %q %q\n", kind.Id, kind.Name, kind.Signature.String())
+		if anchor, ok := g.anchors[id]; ok {
+			fmt.Fprintf(h, "anchor %s\n", anchor)
+		}
+		if typ, ok := g.literals[id]; ok {
+			fmt.Fprintf(h, "literal %s\n", typ)
+		}
+		if policy, ok := g.trivia[id]; ok {
+			fmt.Fprintf(h, "trivia %s\n", policy)
+		}
+	}
+	if g.ident != nil {
+		fmt.Fprintf(h, "ident %t %t %q\n", g.ident.letters, g.ident.digits, g.ident.chars)
+	}
+	comments := append([]string(nil), g.comments...)
+	sort.Strings(comments)
+	fmt.Fprintf(h, "comment %q\n", comments)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+/* Describe the provenance of tokens `g` gives. */
+func newProvenance(g *Grammar, source string) Provenance {
+	return Provenance{Lexer: Version, Grammar: g.Name, GrammarChecksum: g.Checksum(), GrammarVersion: g.Version, Source: source}
+}
+
+/*
+A formatter that may also write the provenance
+of the tokens of a result.
+*/
+type ResultFormatter interface {
+	TokenFormatter
+	FormatResult(w io.Writer, tr TokenResult) error
+}
+
+/*
+Write the tokens of a result with the given
+formatter, along with their provenance if the
+formatter is a `ResultFormatter`.
+*/
+func FormatResult(w io.Writer, f TokenFormatter, tr TokenResult) error {
+	if rf, ok := f.(ResultFormatter); ok {
+		return rf.FormatResult(w, tr)
+	}
+	return f.Format(w, tr.Tokens)
+}
+
+/* Writes a JSON object of the provenance and tokens. */
+func (f JSONFormatter) FormatResult(w io.Writer, tr TokenResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", f.Indent)
+	tokens := tr.Tokens
+	if tokens == nil {
+		tokens = TokenObjects{}
+	}
+	return enc.Encode(struct {
+		Provenance Provenance   `json:"provenance"`
+		Tokens     TokenObjects `json:"tokens"`
+	}{tr.Provenance, tokens})
+}
+
+/*
+Writes a map of the provenance, keyed by
+`provenance`, before the tokens.
+*/
+func (MsgpackFormatter) FormatResult(w io.Writer, tr TokenResult) error {
+	me := NewMsgpackEncoder(w)
+	if err := me.EncodeProvenance(tr.Provenance); err != nil {
+		return err
+	}
+	for _, to := range tr.Tokens {
+		if err := me.Encode(to); err != nil {
+			return err
+		}
+	}
+	return me.Flush()
+}
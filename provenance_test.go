@@ -0,0 +1,81 @@
+package lexer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestGrammarChecksum(t *testing.T) {
+	a, b := lexer.NewGrammar("a"), lexer.NewGrammar("b")
+	a.AddKind("PLUS", "+", "")
+	b.AddKind("PLUS", "+", "Addition.")
+	if a.Checksum() != b.Checksum() {
+		t.Error("expected grammars tokenizing alike to have equal checksums")
+	}
+
+	before := a.Checksum()
+	a.AddKind("MINUS", "-", "")
+	if a.Checksum() == before {
+		t.Error("expected the checksum to change with the kinds")
+	}
+	before = a.Checksum()
+	if err := a.SetLineComments("#"); err != nil {
+		t.Fatal(err)
+	}
+	if a.Checksum() == before {
+		t.Error("expected the checksum to change with comment markers")
+	}
+}
+
+func TestResultProvenance(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	g.Version = "2.0.0"
+	first, _ := g.TokenizeSource("a.x", strings.NewReader("a+b\n"))
+	second, _ := g.TokenizeSource("a.x", strings.NewReader("a+c\n"))
+
+	p := first.Provenance
+	if p.Lexer != lexer.Version || p.Grammar != "x" || p.GrammarChecksum != g.Checksum() || p.GrammarVersion != "2.0.0" || p.Source != "a.x" {
+		t.Errorf("unexpected provenance %+v", p)
+	}
+	if p.SourceChecksum == "" || p.SourceChecksum == second.Provenance.SourceChecksum {
+		t.Errorf("expected sources to have distinct checksums, got %q", p.SourceChecksum)
+	}
+
+	var out bytes.Buffer
+	if err := lexer.FormatResult(&out, lexer.JSONFormatter{}, first); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Provenance lexer.Provenance
+		Tokens     []json.RawMessage
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Provenance != p || len(decoded.Tokens) != 3 {
+		t.Errorf("unexpected output %s", out.String())
+	}
+
+	out.Reset()
+	if err := lexer.FormatResult(&out, lexer.MsgpackFormatter{}, first); err != nil {
+		t.Fatal(err)
+	}
+	dec := lexer.NewMsgpackDecoder(&out, g)
+	if to, err := dec.Decode(); err != nil || string(to.Symbol) != "a" {
+		t.Fatalf("unexpected token %v, %v", to, err)
+	}
+	if dec.Provenance == nil || *dec.Provenance != p {
+		t.Errorf("expected the provenance decoded, got %+v", dec.Provenance)
+	}
+
+	// Formatters without provenance write tokens alone.
+	out.Reset()
+	if err := lexer.FormatResult(&out, lexer.CSVFormatter{}, first); err != nil || strings.Contains(out.String(), "provenance") {
+		t.Errorf("unexpected output %q, %v", out.String(), err)
+	}
+}
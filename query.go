@@ -0,0 +1,202 @@
+package lexer
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* --- TOKEN QUERIES ---
+A small pattern language for finding sequences of
+tokens, for linters and structural search tools
+that don't warrant a full parser.
+
+A pattern is a series of space separated elements:
+
+NAME: A token of the kind named NAME.
+
+NAME(SYMBOL): A token of kind NAME with the symbol
+SYMBOL, e.g. `GENIDEN(main)`.
+
+_: Any single token.
+
+..: Any series of tokens, including none. Matches
+as few tokens as it can.
+
+Any element may be prefixed with `$CAPTURE=` to
+record the tokens it matched under that name.
+
+Whitespace tokens are skipped when matching, unless
+the pattern names a whitespace kind itself. */
+
+/* A single element of a query pattern. */
+type queryElem struct {
+	capture string
+	kind    string // Empty for any kind.
+	symbol  *string
+	series  bool // Set for `..`.
+}
+
+/* Determine if the token satisfies this element. */
+func (qe queryElem) matches(to TokenObject) bool {
+	if qe.kind != "" && (to.Kind == nil || string(to.Kind.Name) != qe.kind) {
+		return false
+	}
+	return qe.symbol == nil || string(to.Symbol) == *qe.symbol
+}
+
+/* Parse a single element of a query pattern. */
+func parseQueryElem(s string) (queryElem, error) {
+	qe := queryElem{}
+
+	if strings.HasPrefix(s, "$") {
+		ind := strings.Index(s, "=")
+		if ind < 2 {
+			return qe, fmt.Errorf("expected `$CAPTURE=` in %q", s)
+		}
+		qe.capture, s = s[1:ind], s[ind+1:]
+	}
+
+	switch s {
+	case "..":
+		qe.series = true
+		return qe, nil
+	case "_":
+		return qe, nil
+	case "":
+		return qe, fmt.Errorf("expected an element after capture %q", qe.capture)
+	}
+
+	if ind := strings.Index(s, "("); ind >= 0 {
+		if !strings.HasSuffix(s, ")") || ind == 0 {
+			return qe, fmt.Errorf("expected `NAME(SYMBOL)`, got %q", s)
+		}
+		symbol := s[ind+1 : len(s)-1]
+		qe.symbol = &symbol
+		s = s[:ind]
+	}
+	qe.kind = s
+	return qe, nil
+}
+
+/* A compiled token query pattern. */
+type TokenQuery struct {
+	elems          []queryElem
+	skipWhitespace bool
+}
+
+/* Compile a query pattern. */
+func CompileQuery(pattern string) (*TokenQuery, error) {
+	q := &TokenQuery{skipWhitespace: true}
+
+	for _, field := range strings.Fields(pattern) {
+		qe, err := parseQueryElem(field)
+		if err != nil {
+			return nil, err
+		}
+		if containsString(triviaKinds, qe.kind) {
+			q.skipWhitespace = false
+		}
+		q.elems = append(q.elems, qe)
+	}
+	if len(q.elems) == 0 {
+		return nil, fmt.Errorf("empty query pattern")
+	}
+	return q, nil
+}
+
+/* A series of tokens matched by a query. */
+type QueryMatch struct {
+	Start    int // Index of the first matched token.
+	End      int // Index after the last matched token.
+	Captures map[string]TokenObjects
+}
+
+/*
+Find every match of this query in the given
+tokens, scanning from the start. Matches do not
+overlap.
+*/
+func (q *TokenQuery) Match(tokens []TokenObject) []QueryMatch {
+	// Indexes of the tokens taking part in
+	// matching.
+	indexes := []int{}
+	for i, to := range tokens {
+		if q.skipWhitespace && to.IsTrivia() {
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+
+	matches := []QueryMatch{}
+	for pos := 0; pos < len(indexes); {
+		qr := queryRun{q, tokens, indexes, make([][2]int, len(q.elems))}
+		end, ok := qr.matchFrom(0, pos)
+		if !ok || end == pos {
+			pos += 1
+			continue
+		}
+
+		m := QueryMatch{indexes[pos], indexes[end-1] + 1, map[string]TokenObjects{}}
+		for ei, qe := range q.elems {
+			if qe.capture == "" {
+				continue
+			}
+			captured := TokenObjects{}
+			if span := qr.spans[ei]; span[1] > span[0] {
+				captured = tokens[indexes[span[0]] : indexes[span[1]-1]+1]
+			}
+			m.Captures[qe.capture] = captured
+		}
+		matches = append(matches, m)
+		pos = end
+	}
+	return matches
+}
+
+/* State of a single attempt at matching a query. */
+type queryRun struct {
+	q       *TokenQuery
+	tokens  []TokenObject
+	indexes []int    // Indexes of the tokens taking part.
+	spans   [][2]int // Span of `indexes` each element matched.
+}
+
+/*
+Match elements from `ei` on against the tokens at
+`indexes[pos:]`, recording the span each element
+matched. Returns the position after the match.
+*/
+func (qr *queryRun) matchFrom(ei int, pos int) (int, bool) {
+	if ei == len(qr.q.elems) {
+		return pos, true
+	}
+	qe := qr.q.elems[ei]
+
+	if qe.series {
+		for end := pos; end <= len(qr.indexes); end++ {
+			qr.spans[ei] = [2]int{pos, end}
+			if found, ok := qr.matchFrom(ei+1, end); ok {
+				return found, true
+			}
+		}
+		return 0, false
+	}
+
+	if pos >= len(qr.indexes) || !qe.matches(qr.tokens[qr.indexes[pos]]) {
+		return 0, false
+	}
+	qr.spans[ei] = [2]int{pos, pos + 1}
+	return qr.matchFrom(ei+1, pos+1)
+}
+
+/*
+Find every match of the pattern in the given
+tokens. See `CompileQuery`.
+*/
+func Query(tokens []TokenObject, pattern string) ([]QueryMatch, error) {
+	q, err := CompileQuery(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return q.Match(tokens), nil
+}
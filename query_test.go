@@ -0,0 +1,48 @@
+package lexer_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestQuery(t *testing.T) {
+	g := lexer.NewGrammar("query")
+	g.AddKind("IF", "if", "")
+	g.AddKind("LBRACE", "{", "")
+	g.AddKind("RBRACE", "}", "")
+	tokens := g.TokenizeLine("if a b { c } if d {}", 1)
+
+	matches, err := lexer.Query(tokens, "IF $cond=.. LBRACE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %v", matches)
+	}
+	if found := fmt.Sprint(symbolsOf(matches[0].Captures["cond"])); found != "[a   b]" {
+		t.Errorf("unexpected capture %s", found)
+	}
+	if matches[1].Start != 12 || matches[1].End != 17 {
+		t.Errorf("unexpected span of second match %d-%d", matches[1].Start, matches[1].End)
+	}
+
+	matches, _ = lexer.Query(tokens, "LBRACE $name=GENIDEN(c) _")
+	if len(matches) != 1 || string(matches[0].Captures["name"][0].Symbol) != "c" {
+		t.Errorf("unexpected matches %v", matches)
+	}
+
+	// Naming a whitespace kind stops it from being skipped.
+	matches, _ = lexer.Query(tokens, "IF GENIDEN")
+	spaced, _ := lexer.Query(tokens, "IF WHTSPACE GENIDEN")
+	if len(matches) != 2 || len(spaced) != 2 {
+		t.Errorf("expected 2 matches with and without whitespace, got %d and %d", len(matches), len(spaced))
+	}
+
+	for _, pattern := range []string{"", "$=IF", "IF(", "$x="} {
+		if _, err := lexer.CompileQuery(pattern); err == nil {
+			t.Errorf("expected an error for pattern %q", pattern)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package lexer
+
+import "fmt"
+
+/* --- KIND REMAPPING ---
+Kinds change between versions of a grammar: they
+are renamed, merged, and given new IDs as kinds
+are added before them. Tokens archived from an
+old version keep its kinds. Rather than lex the
+sources again, which may be long gone, tokens are
+given the kinds of the new version by name:
+through a map of old names to new, or the
+grammar's aliases, declared with `@alias` or
+`AliasKind`. Aliases also apply as tokens are
+read back by `ReadTokensJSON` and
+`ReadTokensMsgpack`. A kind of the old name takes
+precedence over an alias of it. */
+
+/*
+Declare `old` an alias of the named kind, so
+tokens of a kind named `old` are given that kind
+when remapped or read back.
+*/
+func (g *Grammar) AliasKind(old string, kind string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.kinds.ByName(tokenName(kind)); !ok {
+		return fmt.Errorf("unknown kind %q", kind)
+	}
+	g.setAlias(tokenName(old), tokenName(kind))
+	return nil
+}
+
+/* Alias a kind by name. */
+func (g *Grammar) setAlias(old tokenName, kind tokenName) {
+	if g.aliases == nil {
+		g.aliases = map[tokenName]tokenName{}
+	}
+	g.aliases[old] = kind
+}
+
+/*
+Alias kinds, by name. Names already aliased keep
+their alias unless `overwrite` is set.
+*/
+func (g *Grammar) applyAliases(aliases map[tokenName]tokenName, overwrite bool) {
+	for old, kind := range aliases {
+		if _, aliased := g.aliases[old]; aliased && !overwrite {
+			continue
+		}
+		g.setAlias(old, kind)
+	}
+}
+
+/*
+Give the tokens, and their comments, the kinds of
+this grammar by name: the name `remap` maps the
+name of their kind to, if any, else the same
+name or its alias. Literal values are parsed as
+this grammar parses them. Returns new tokens; the
+given tokens are not modified. Tokens of a kind
+the grammar lacks are an error.
+*/
+func (g *Grammar) RemapKinds(tokens TokenObjects, remap map[string]string) (TokenObjects, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	kr := kindResolver{g: g}
+	return kr.remap(tokens, remap)
+}
+
+/* Give the tokens the kinds of the default grammar by name. */
+func RemapKinds(tokens TokenObjects, remap map[string]string) (TokenObjects, error) {
+	return defaultGrammar.RemapKinds(tokens, remap)
+}
+
+/* Copy the tokens with the kinds they are remapped to. */
+func (kr *kindResolver) remap(tokens TokenObjects, remap map[string]string) (TokenObjects, error) {
+	remapped := make(TokenObjects, len(tokens))
+	for i, to := range tokens {
+		if to.Kind != nil {
+			name := to.Kind.Name
+			if renamed, ok := remap[string(name)]; ok && renamed != string(name) {
+				name = tokenName(renamed)
+				to = to.Derive("remap")
+			}
+			kind, err := kr.resolve(name)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", to.Pos(), err)
+			}
+			to.Kind = kind
+			to.Value = kr.g.literalValue(kind.Id, to.Symbol)
+		}
+		if to.Comments != nil {
+			comments, err := kr.remap(to.Comments, remap)
+			if err != nil {
+				return nil, err
+			}
+			to.Comments = comments
+		}
+		remapped[i] = to
+	}
+	return remapped, nil
+}
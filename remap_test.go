@@ -0,0 +1,64 @@
+package lexer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestRemapKinds(t *testing.T) {
+	old := lexer.NewGrammar("v1")
+	if err := old.LoadTokens(strings.NewReader("PLUS +\nMINUS -\nSTAR *\n")); err != nil {
+		t.Fatal(err)
+	}
+	tokens := old.TokenizeLine("a+b-c*d", 1)
+
+	g := lexer.NewGrammar("v2")
+	if err := g.LoadTokens(strings.NewReader("MUL *\nADD +\nSUB -\n@alias PLUS ADD\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AliasKind("STAR", "MUL"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AliasKind("TIMES", "DIV"); err == nil {
+		t.Error("expected an error aliasing an unknown kind")
+	}
+
+	remapped, err := g.RemapKinds(tokens, map[string]string{"MINUS": "SUB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "GENIDEN:a ADD:+ GENIDEN:b SUB:- GENIDEN:c MUL:* GENIDEN:d"
+	if got := renderKinds(remapped); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	add, _ := g.LookupKind("ADD")
+	if !remapped[1].Is(add) {
+		t.Errorf("expected the grammar's own kind, got %v", remapped[1].Kind)
+	}
+	if got := renderKinds(tokens); got != "GENIDEN:a PLUS:+ GENIDEN:b MINUS:- GENIDEN:c STAR:* GENIDEN:d" {
+		t.Errorf("expected the given tokens unchanged, got %s", got)
+	}
+
+	if _, err := g.RemapKinds(tokens, nil); err == nil {
+		t.Error("expected an error for a kind without a name or alias in the grammar")
+	}
+
+	var buf bytes.Buffer
+	if err := (lexer.JSONFormatter{}).Format(&buf, old.TokenizeLine("a+b", 1)); err != nil {
+		t.Fatal(err)
+	}
+	read, err := g.ReadTokensJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := renderKinds(read); got != "GENIDEN:a ADD:+ GENIDEN:b" {
+		t.Errorf("expected tokens read back with aliased kinds, got %s", got)
+	}
+
+	if err := g.LoadTokens(strings.NewReader("ADD +\n@alias PLUS SUM\n")); err == nil {
+		t.Error("expected an error for an alias of an undefined kind")
+	}
+}
@@ -0,0 +1,170 @@
+package lexer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+/* --- GRAMMAR REPORTS ---
+A summary of the kinds of a grammar, for its
+authors to spot definitions that make matching
+slow or ambiguous before they show up in
+tokenized input: kinds sharing a signature, of
+which only the first is ever matched, and
+families of signatures prefixing one another,
+which are matched by maximal munch.
+
+Signatures are literal, so there are no patterns
+whose complexity could be reported; the number of
+states of an automaton matching every signature
+stands in for it. Only kinds defined by tokens
+files are summarized. */
+
+/* A summary of the kinds of a grammar. */
+type GrammarReport struct {
+	Name  string
+	Kinds int // Kinds defined by tokens files.
+
+	Keywords  int // Kinds whose signatures are words, e.g. `return`.
+	Operators int // Kinds whose signatures are symbols, e.g. `<<=`.
+	MultiWord int // Kinds whose signatures span several words.
+	Anchored  int // Kinds matched only at the start or end of a line.
+	Literals  int // Kinds whose symbols are parsed into values.
+
+	Shortest []TokenKind // Kinds with the shortest signatures.
+	Longest  []TokenKind // Kinds with the longest signatures.
+
+	// Families of kinds whose signatures all start with that of
+	// the first, e.g. `<`, `<<` and `<<=`.
+	Clusters [][]TokenKind
+
+	// Kinds sharing a signature; only the first of each is matched.
+	Duplicates [][]TokenKind
+
+	// States of an automaton matching every signature.
+	States int
+}
+
+/* Summarize the kinds of this grammar. */
+func (g *Grammar) Report() GrammarReport {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	report := GrammarReport{Name: g.Name}
+	kinds := []TokenKind{}
+	for _, id := range g.kinds.Ids() {
+		if id < FirstUserKindId {
+			continue
+		}
+		kind := g.kinds.Get(id)
+		kinds = append(kinds, kind)
+
+		switch sig := string(kind.Signature); {
+		case isKeyword(sig):
+			report.Keywords += 1
+		case isSpaced(sig):
+			report.MultiWord += 1
+		default:
+			report.Operators += 1
+		}
+		if _, ok := g.anchors[id]; ok {
+			report.Anchored += 1
+		}
+		if _, ok := g.literals[id]; ok {
+			report.Literals += 1
+		}
+	}
+	report.Kinds = len(kinds)
+	if len(kinds) == 0 {
+		return report
+	}
+
+	// Sorting by signature puts each kind right
+	// after those whose signatures prefix its own.
+	sort.SliceStable(kinds, func(i, j int) bool {
+		return string(kinds[i].Signature) < string(kinds[j].Signature)
+	})
+
+	shortest, longest := len(kinds[0].Signature), len(kinds[0].Signature)
+	prefixes := map[string]bool{}
+	var root, same []TokenKind
+	for i, kind := range kinds {
+		sig := string(kind.Signature)
+		if len(sig) < shortest {
+			shortest = len(sig)
+		}
+		if len(sig) > longest {
+			longest = len(sig)
+		}
+		for n := 1; n <= len(sig); n++ {
+			prefixes[sig[:n]] = true
+		}
+
+		if i > 0 && sig == string(kinds[i-1].Signature) {
+			same = append(same, kind)
+		} else {
+			report.Duplicates = appendGroup(report.Duplicates, same)
+			same = []TokenKind{kind}
+		}
+		if len(root) > 0 && strings.HasPrefix(sig, string(root[0].Signature)) {
+			root = append(root, kind)
+		} else {
+			report.Clusters = appendGroup(report.Clusters, root)
+			root = []TokenKind{kind}
+		}
+	}
+	report.Duplicates = appendGroup(report.Duplicates, same)
+	report.Clusters = appendGroup(report.Clusters, root)
+	report.States = len(prefixes) + 1
+
+	for _, kind := range kinds {
+		if len(kind.Signature) == shortest {
+			report.Shortest = append(report.Shortest, kind)
+		}
+		if len(kind.Signature) == longest {
+			report.Longest = append(report.Longest, kind)
+		}
+	}
+	return report
+}
+
+/* Append a group of kinds, if there are several. */
+func appendGroup(groups [][]TokenKind, group []TokenKind) [][]TokenKind {
+	if len(group) < 2 {
+		return groups
+	}
+	return append(groups, group)
+}
+
+/* Write the report as text. */
+func (gr GrammarReport) Format(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "grammar %s: %d kinds\n", gr.Name, gr.Kinds)
+	fmt.Fprintf(&b, "  keywords %d, operators %d, multi-word %d, anchored %d, literals %d\n",
+		gr.Keywords, gr.Operators, gr.MultiWord, gr.Anchored, gr.Literals)
+	if len(gr.Shortest) > 0 {
+		fmt.Fprintf(&b, "  shortest: %s\n", kindList(gr.Shortest))
+		fmt.Fprintf(&b, "  longest: %s\n", kindList(gr.Longest))
+	}
+	fmt.Fprintf(&b, "  automaton states: %d\n", gr.States)
+	for _, cluster := range gr.Clusters {
+		fmt.Fprintf(&b, "  prefix cluster: %s\n", kindList(cluster))
+	}
+	for _, dup := range gr.Duplicates {
+		fmt.Fprintf(&b, "  duplicate signature: %s\n", kindList(dup))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+/* List kinds as `NAME "signature"`, comma separated. */
+func kindList(kinds []TokenKind) string {
+	parts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		parts[i] = fmt.Sprintf("%s %q", kind.Name, kind.Signature.String())
+	}
+	return strings.Join(parts, ", ")
+}
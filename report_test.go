@@ -0,0 +1,50 @@
+package lexer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestGrammarReport(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("LT", "<", "")
+	g.AddKind("SHL", "<<", "")
+	g.AddKind("LE", "<=", "")
+	g.AddKind("IF", "if", "")
+	g.AddKind("WHEN", "if", "")
+	g.AddKind("GROUP_BY", "GROUP BY", "")
+	if err := g.SetAnchor("IF", lexer.AnchorStart); err != nil {
+		t.Fatal(err)
+	}
+
+	report := g.Report()
+	if report.Kinds != 6 || report.Keywords != 2 || report.Operators != 3 || report.MultiWord != 1 || report.Anchored != 1 {
+		t.Errorf("unexpected counts %+v", report)
+	}
+	if len(report.Shortest) != 1 || report.Shortest[0].Name != "LT" {
+		t.Errorf("expected LT shortest, got %v", report.Shortest)
+	}
+	if len(report.Longest) != 1 || report.Longest[0].Name != "GROUP_BY" {
+		t.Errorf("expected GROUP_BY longest, got %v", report.Longest)
+	}
+	if len(report.Clusters) != 2 || len(report.Clusters[0]) != 3 {
+		t.Errorf("expected the '<' family clustered, got %v", report.Clusters)
+	}
+	if len(report.Duplicates) != 1 || report.Duplicates[0][0].Name != "IF" || report.Duplicates[0][1].Name != "WHEN" {
+		t.Errorf("expected IF and WHEN duplicated, got %v", report.Duplicates)
+	}
+	// Root, then G-R-O-U-P-space-B-Y, <, <<, <=, i, if.
+	if report.States != 14 {
+		t.Errorf("expected 14 states, got %d", report.States)
+	}
+
+	var out strings.Builder
+	if err := report.Format(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `duplicate signature: IF "if", WHEN "if"`) {
+		t.Errorf("unexpected report\n%s", out.String())
+	}
+}
@@ -0,0 +1,202 @@
+package lexer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"time"
+)
+
+/* --- TOKEN RESULTS ---
+Tokenizing a whole source gives more than its
+tokens: the text of each line, diagnostics and
+stats as well. A `TokenResult` holds all of them
+together, so new fields may be added to it
+without changing the signatures of functions
+returning it. */
+
+/* Everything tokenizing a source gives. */
+type TokenResult struct {
+	Source string       // Name of the input, e.g. its path; empty if unnamed.
+	Tokens TokenObjects // Every token, in order.
+	Index  LineIndex    // Tokens per line, with the text of each line.
+
+	// Recorded if the lexer records diagnostics, up to its limit;
+	// nil otherwise.
+	Diagnostics *Diagnostics
+
+	// Lines and tokens counted. Time spent per stage is only
+	// recorded if the lexer has a profile.
+	Stats Profile
+
+	Bytes   int64         // Bytes of source read, before decoding.
+	Elapsed time.Duration // Time spent tokenizing, profiled or not.
+
+	// Versions and checksums of the lexer, grammar and source.
+	Provenance Provenance
+}
+
+/* Basic facts of a result, for logging and monitoring. */
+type ResultMetadata struct {
+	Source         string        `json:"source,omitempty"`
+	Tokens         int           `json:"tokens"`
+	Lines          int           `json:"lines"`
+	Bytes          int64         `json:"bytes"`
+	Elapsed        time.Duration `json:"elapsed_ns"`
+	Lexer          string        `json:"lexer"` // Version of the lexer.
+	Grammar        string        `json:"grammar"`
+	GrammarVersion string        `json:"grammar_version,omitempty"`
+}
+
+/* Gather the basic facts of the result. */
+func (tr TokenResult) Metadata() ResultMetadata {
+	return ResultMetadata{
+		Source:         tr.Source,
+		Tokens:         len(tr.Tokens),
+		Lines:          tr.Stats.Lines,
+		Bytes:          tr.Bytes,
+		Elapsed:        tr.Elapsed,
+		Lexer:          tr.Provenance.Lexer,
+		Grammar:        tr.Provenance.Grammar,
+		GrammarVersion: tr.Provenance.GrammarVersion,
+	}
+}
+
+/* Counts the bytes written to it. */
+type byteCounter int64
+
+func (bc *byteCounter) Write(p []byte) (int, error) {
+	*bc += byteCounter(len(p))
+	return len(p), nil
+}
+
+/* Number of tokens in the result. */
+func (tr TokenResult) Len() int {
+	return len(tr.Tokens)
+}
+
+/*
+Call `fn` with each token in order, until it
+returns false.
+*/
+func (tr TokenResult) Each(fn func(i int, to TokenObject) bool) {
+	for i, to := range tr.Tokens {
+		if !fn(i, to) {
+			return
+		}
+	}
+}
+
+/* Retrieve the tokens for which `keep` returns true. */
+func (tr TokenResult) Filter(keep func(to TokenObject) bool) TokenObjects {
+	kept := TokenObjects{}
+	for _, to := range tr.Tokens {
+		if keep(to) {
+			kept = append(kept, to)
+		}
+	}
+	return kept
+}
+
+/* Retrieve the tokens of the named kinds. */
+func (tr TokenResult) OfKind(names ...string) TokenObjects {
+	return tr.Filter(func(to TokenObject) bool {
+		return to.Kind != nil && containsString(names, string(to.Kind.Name))
+	})
+}
+
+/* Retrieve the tokens that are not trivia. */
+func (tr TokenResult) Significant() TokenObjects {
+	return tr.Filter(func(to TokenObject) bool { return !to.IsTrivia() })
+}
+
+/*
+Break down each line read from `r` into tokens,
+gathering everything tokenizing gives into a
+result under the given source name. The lexer's
+own diagnostics are left untouched; those of the
+source are in the result, as is a checksum of
+the source read.
+*/
+func (lx *Lexer) TokenizeSource(name string, r io.Reader) (TokenResult, error) {
+	run := *lx
+	run.Profile = nil
+	if lx.Profile != nil {
+		run.Profile = &Profile{}
+	}
+	run.Diagnostics = nil
+	if lx.Diagnostics != nil {
+		run.Diagnostics = &Diagnostics{Max: lx.Diagnostics.Max}
+	}
+
+	start := time.Now()
+	h := sha256.New()
+	var n byteCounter
+	li, err := run.TokenizeIndexed(io.TeeReader(r, io.MultiWriter(h, &n)))
+	tr := TokenResult{Source: name, Tokens: li.Tokens(), Index: li, Diagnostics: run.Diagnostics}
+	tr.Bytes, tr.Elapsed = int64(n), time.Since(start)
+	tr.Provenance = newProvenance(lx.grammar(), name)
+	tr.Provenance.SourceChecksum = hex.EncodeToString(h.Sum(nil))
+	if run.Profile != nil {
+		tr.Stats = *run.Profile
+		lx.Profile.add(tr.Stats)
+	}
+	tr.Stats.Lines = len(li.input)
+	tr.Stats.Tokens = len(li.tokens)
+	return tr, withFilename(err, name)
+}
+
+/*
+Break down each line of `r` into tokens,
+gathering everything tokenizing gives into a
+result.
+*/
+func (g *Grammar) TokenizeSource(name string, r io.Reader) (TokenResult, error) {
+	return g.lexer().TokenizeSource(name, r)
+}
+
+/*
+Break down each line of `r` into tokens using
+the default grammar, gathering everything
+tokenizing gives into a result.
+*/
+func TokenizeSource(name string, r io.Reader) (TokenResult, error) {
+	return defaultGrammar.TokenizeSource(name, r)
+}
+
+/*
+Break down each line of the named file into
+tokens, gathering everything tokenizing gives
+into a result.
+*/
+func (lx *Lexer) TokenizeFileResult(name string) (TokenResult, error) {
+	if lx.Cache != nil {
+		return lx.tokenizeResultCached(name)
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return TokenResult{Source: name}, err
+	}
+	defer file.Close()
+
+	return lx.TokenizeSource(name, file)
+}
+
+/*
+Break down each line of the named file into
+tokens, gathering everything tokenizing gives
+into a result.
+*/
+func (g *Grammar) TokenizeFileResult(name string) (TokenResult, error) {
+	return g.lexer().TokenizeFileResult(name)
+}
+
+/*
+Break down each line of the named file into
+tokens using the default grammar, gathering
+everything tokenizing gives into a result.
+*/
+func TokenizeFileResult(name string) (TokenResult, error) {
+	return defaultGrammar.TokenizeFileResult(name)
+}
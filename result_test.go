@@ -0,0 +1,73 @@
+package lexer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestTokenResult(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	if err := g.SetIdentClasses("letter"); err != nil {
+		t.Fatal(err)
+	}
+	lx := &lexer.Lexer{Grammar: g, Diagnostics: &lexer.Diagnostics{}}
+
+	result, err := lx.TokenizeSource("sum.x", strings.NewReader("a + b\n1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != "sum.x" || result.Len() != 6 || result.Stats.Lines != 2 || result.Stats.Tokens != 6 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if text, ok := result.Index.Text(2); !ok || text != "1" {
+		t.Errorf("expected the text of line 2, got %q", text)
+	}
+	if len(result.Diagnostics.List) != 1 || len(lx.Diagnostics.List) != 0 {
+		t.Errorf("expected one diagnostic in the result only, got %v", result.Diagnostics)
+	}
+
+	if got := symbolsOf(result.OfKind("PLUS")); len(got) != 1 || got[0] != "+" {
+		t.Errorf("expected '+', got %q", got)
+	}
+	if got := symbolsOf(result.Significant()); len(got) != 4 {
+		t.Errorf("expected 4 significant tokens, got %q", got)
+	}
+
+	seen := 0
+	result.Each(func(i int, to lexer.TokenObject) bool {
+		seen += 1
+		return i < 2
+	})
+	if seen != 3 {
+		t.Errorf("expected iteration to stop after 3 tokens, got %d", seen)
+	}
+}
+
+func TestResultMetadata(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	if err := g.LoadTokens(strings.NewReader("@version 1.4.0\nPLUS +\n")); err != nil {
+		t.Fatal(err)
+	}
+	if g.Version != "1.4.0" {
+		t.Errorf("expected the declared version, got %q", g.Version)
+	}
+
+	result, err := g.TokenizeSource("a.x", strings.NewReader("a+b\nc\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := result.Metadata()
+	if md.Source != "a.x" || md.Tokens != 4 || md.Lines != 2 || md.Bytes != 6 {
+		t.Errorf("unexpected metadata %+v", md)
+	}
+	if md.Elapsed <= 0 || md.Lexer != lexer.Version || md.Grammar != "x" || md.GrammarVersion != "1.4.0" {
+		t.Errorf("unexpected metadata %+v", md)
+	}
+
+	if err := g.LoadTokens(strings.NewReader("@version\n")); err == nil {
+		t.Error("expected an error for a version directive without a version")
+	}
+}
@@ -0,0 +1,127 @@
+package lexer
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+/* --- SARIF ---
+Diagnostics written in the Static Analysis
+Results Interchange Format, version 2.1.0, read
+by code scanning services and editors alike.
+Each diagnostic is a result of the rule that
+reported it; those of registered lint rules are
+warnings, and any other, such as the lexer's
+own, errors. */
+
+// Rule of results of diagnostics naming no rule; the lexer's own.
+const SARIFLexerRule = "lexer"
+
+// SARIF level of each severity.
+var sarifLevels = map[Severity]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+	SeverityInfo:    "note",
+}
+
+/* Diagnostics about a source file. */
+type FileDiagnostics struct {
+	Source      string // Path of the file, as a relative URI if relative.
+	Diagnostics []Diagnostic
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleId    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine   int `json:"startLine"`
+			StartColumn int `json:"startColumn"`
+			EndColumn   int `json:"endColumn"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+/*
+Write the diagnostics of the given files as a
+SARIF log of a single run of the named tool.
+Rules are listed in order of their first result.
+*/
+func WriteSARIF(w io.Writer, tool string, files []FileDiagnostics) error {
+	run := sarifRun{
+		Tool:    sarifTool{sarifDriver{Name: tool, Version: Version, Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+	rules := map[string]bool{}
+	for _, file := range files {
+		for _, d := range file.Diagnostics {
+			rule := d.Rule
+			if rule == "" {
+				rule = d.Code
+			}
+			if rule == "" {
+				rule = SARIFLexerRule
+			}
+			level := sarifLevels[d.Severity]
+			if !rules[rule] {
+				rules[rule] = true
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{rule})
+			}
+
+			var loc sarifLocation
+			pos := d.Token.Pos()
+			loc.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(file.Source)
+			loc.PhysicalLocation.Region.StartLine = pos.Line
+			loc.PhysicalLocation.Region.StartColumn = pos.Column
+			loc.PhysicalLocation.Region.EndColumn = pos.Column + utf8.RuneCount(d.Token.Symbol)
+			run.Results = append(run.Results, sarifResult{rule, level, sarifMessage{d.Message}, []sarifLocation{loc}})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
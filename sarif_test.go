@@ -0,0 +1,82 @@
+package lexer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("LPAREN", "(", "")
+	g.AddKind("RPAREN", ")", "")
+	index, err := g.TokenizeText("f(x \n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := index.Tokens()
+
+	diags := lexer.DefaultLintConfig().Lint(tokens)
+	for _, be := range lexer.CheckBrackets(tokens, g.BracketPairs()...).Errors {
+		diags = append(diags, lexer.Diagnostic{Token: be.Token, Message: be.Reason, Rule: "brackets"})
+	}
+	diags = append(diags, lexer.Diagnostic{Token: tokens[0], Message: "unknown input"})
+
+	var buf bytes.Buffer
+	files := []lexer.FileDiagnostics{{Source: "src/f.x", Diagnostics: diags}}
+	if err := lexer.WriteSARIF(&buf, "panza-lex", files); err != nil {
+		t.Fatal(err)
+	}
+
+	var log struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string
+					Rules []struct{ Id string }
+				}
+			}
+			Results []struct {
+				RuleId    string
+				Level     string
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+						Region           struct{ StartLine, StartColumn, EndColumn int }
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || log.Runs[0].Tool.Driver.Name != "panza-lex" {
+		t.Fatalf("unexpected log %s", buf.String())
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 3 {
+		t.Errorf("expected 3 rules, got %v", run.Tool.Driver.Rules)
+	}
+
+	want := []struct{ rule, level string }{
+		{"trailing-whitespace", "warning"},
+		{"brackets", "error"},
+		{lexer.SARIFLexerRule, "error"},
+	}
+	if len(run.Results) != len(want) {
+		t.Fatalf("expected %d results, got %s", len(want), buf.String())
+	}
+	for i, w := range want {
+		if r := run.Results[i]; r.RuleId != w.rule || r.Level != w.level {
+			t.Errorf("result %d: expected %s %s, got %s %s", i, w.rule, w.level, r.RuleId, r.Level)
+		}
+	}
+	loc := run.Results[1].Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "src/f.x" || loc.Region.StartLine != 1 || loc.Region.StartColumn != 2 || loc.Region.EndColumn != 3 {
+		t.Errorf("unexpected location %+v", loc)
+	}
+}
@@ -0,0 +1,140 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/* --- GRAMMAR VERSIONING ---
+Grammars declaring a `@version` are expected to
+be versioned semantically, as source tokenized
+with one version should tokenize the same with
+any later version of the same major version. A
+grammar diff is classified by the bump it calls
+for:
+
+major: Kinds were removed, renamed, given new
+signatures or new IDs; source may tokenize
+differently, and stored tokens may not read back.
+minor: Kinds were only added.
+patch: No kinds changed.
+
+Before 1.0.0 anything may change, so a minor
+bump suffices for major changes. */
+
+/* The version bump a change to a grammar calls for. */
+type Compatibility uint8
+
+const (
+	CompatPatch Compatibility = iota
+	CompatMinor
+	CompatMajor
+)
+
+var compatibilityNames = []string{"patch", "minor", "major"}
+
+func (c Compatibility) String() string {
+	if int(c) < len(compatibilityNames) {
+		return compatibilityNames[c]
+	}
+	return fmt.Sprintf("Compatibility(%d)", uint8(c))
+}
+
+/* Look up a compatibility by its name, e.g. `minor`. */
+func ParseCompatibility(name string) (Compatibility, error) {
+	for i, known := range compatibilityNames {
+		if name == known {
+			return Compatibility(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown compatibility %q, expected patch, minor or major", name)
+}
+
+/* Classify the change by the version bump it calls for. */
+func (gd GrammarDiff) Compatibility() Compatibility {
+	switch {
+	case len(gd.Removed) > 0 || len(gd.Renamed) > 0 || len(gd.Resignatured) > 0 || len(gd.Renumbered) > 0:
+		return CompatMajor
+	case len(gd.Added) > 0:
+		return CompatMinor
+	}
+	return CompatPatch
+}
+
+/* A `MAJOR.MINOR.PATCH` version; pre-release and build suffixes are ignored. */
+type semver [3]int
+
+/* Parse a version, with or without a leading `v`. */
+func parseSemver(version string) (semver, error) {
+	var v semver
+	core := strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("version %q is not of the form MAJOR.MINOR.PATCH", version)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("version %q is not of the form MAJOR.MINOR.PATCH", version)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+/* Compare versions; negative if `v` is lower, positive if higher. */
+func (v semver) compare(o semver) int {
+	for i := range v {
+		if v[i] != o[i] {
+			return v[i] - o[i]
+		}
+	}
+	return 0
+}
+
+/*
+Check that going from version `old` to `new` is
+bump enough for a change of the given
+compatibility. Versions must not go down, even
+for patch changes.
+*/
+func CheckVersionBump(old, new string, c Compatibility) error {
+	ov, err := parseSemver(old)
+	if err != nil {
+		return err
+	}
+	nv, err := parseSemver(new)
+	if err != nil {
+		return err
+	}
+
+	if c == CompatMajor && ov[0] == 0 {
+		c = CompatMinor
+	}
+	switch {
+	case nv.compare(ov) < 0:
+		return fmt.Errorf("version %s is lower than %s", new, old)
+	case c == CompatMajor && nv[0] <= ov[0]:
+		return fmt.Errorf("major change needs a major version bump from %s, got %s", old, new)
+	case c == CompatMinor && nv[0] == ov[0] && nv[1] <= ov[1]:
+		return fmt.Errorf("%s change needs a minor version bump from %s, got %s", c, old, new)
+	}
+	return nil
+}
+
+/*
+Check that the `@version` of the new grammar is
+bump enough for the changes since the old.
+Returns the changes' compatibility.
+*/
+func CheckGrammarVersions(old, new *Grammar) (Compatibility, error) {
+	c := DiffGrammars(old, new).Compatibility()
+	if old.Version == "" || new.Version == "" {
+		return c, fmt.Errorf("grammars %s and %s must both declare a @version", old.Name, new.Name)
+	}
+	return c, CheckVersionBump(old.Version, new.Version, c)
+}
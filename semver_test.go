@@ -0,0 +1,52 @@
+package lexer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestGrammarCompatibility(t *testing.T) {
+	load := func(name, tokens string) *lexer.Grammar {
+		g := lexer.NewGrammar(name)
+		if err := g.LoadTokens(strings.NewReader(tokens)); err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+	base := load("base", "@version 1.2.3\nPLUS +\nMINUS -\n")
+	cases := []struct {
+		tokens string
+		compat lexer.Compatibility
+		ok     bool
+	}{
+		{"@version 1.2.4\nPLUS +\nMINUS -\n", lexer.CompatPatch, true},
+		{"@version 1.2.3\nPLUS +\nMINUS -\n", lexer.CompatPatch, true},
+		{"@version 1.2.2\nPLUS +\nMINUS -\n", lexer.CompatPatch, false},
+		{"@version 1.3.0\nPLUS +\nMINUS -\nSTAR *\n", lexer.CompatMinor, true},
+		{"@version 1.2.4\nPLUS +\nMINUS -\nSTAR *\n", lexer.CompatMinor, false},
+		{"@version 2.0.0\nPLUS +\n", lexer.CompatMajor, true},
+		{"@version 1.9.0\nPLUS +\nMINUS --\n", lexer.CompatMajor, false},
+		{"@version v2.0.0-rc.1\nPLUS +\nSUB -\n", lexer.CompatMajor, true},
+	}
+	for _, c := range cases {
+		compat, err := lexer.CheckGrammarVersions(base, load("new", c.tokens))
+		if compat != c.compat || (err == nil) != c.ok {
+			t.Errorf("%q: expected %s, ok %t, got %s, %v", c.tokens, c.compat, c.ok, compat, err)
+		}
+	}
+
+	if err := lexer.CheckVersionBump("0.4.1", "0.5.0", lexer.CompatMajor); err != nil {
+		t.Errorf("expected a minor bump to do before 1.0.0, got %v", err)
+	}
+	if err := lexer.CheckVersionBump("1.x", "2.0.0", lexer.CompatMajor); err == nil {
+		t.Error("expected a malformed version to be rejected")
+	}
+	if _, err := lexer.CheckGrammarVersions(base, load("bare", "PLUS +\n")); err == nil {
+		t.Error("expected a grammar without a version to be rejected")
+	}
+	if c, err := lexer.ParseCompatibility("minor"); err != nil || c != lexer.CompatMinor {
+		t.Errorf("expected minor, got %s, %v", c, err)
+	}
+}
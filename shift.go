@@ -0,0 +1,79 @@
+package lexer
+
+import "strings"
+
+/* --- POSITION SHIFTING ---
+Editors re-lex a buffer after it changes, but in
+the meantime want token positions that still
+line up with the edited text. `ShiftTokens` moves
+tokens past an edit by however far the edit moved
+them. */
+
+/*
+Replaces the range from the start position up to,
+but not including, the end position with `Text`.
+Positions are 1-based, as on `TokenObject`. An
+insertion has equal start and end positions; a
+deletion has no text.
+*/
+type TextEdit struct {
+	StartLine tokenLineNo
+	StartPos  tokenPosition
+	EndLine   tokenLineNo
+	EndPos    tokenPosition
+	Text      string
+}
+
+/* Calculate where the inserted text ends. */
+func (te TextEdit) newEnd() (tokenLineNo, tokenPosition) {
+	ind := strings.LastIndexByte(te.Text, '\n')
+	if ind < 0 {
+		return te.StartLine, te.StartPos + tokenPosition(len(te.Text))
+	}
+	lines := tokenLineNo(strings.Count(te.Text, "\n"))
+	return te.StartLine + lines, tokenPosition(len(te.Text)-ind-1) + 1
+}
+
+/* Determine if the token ends at or before the edit. */
+func (te TextEdit) isBefore(to TokenObject) bool {
+	if to.LineNo != te.StartLine {
+		return to.LineNo < te.StartLine
+	}
+	return to.Position+tokenPosition(len(to.Symbol)) <= te.StartPos
+}
+
+/* Determine if the token starts at or after the edit. */
+func (te TextEdit) isAfter(to TokenObject) bool {
+	if to.LineNo != te.EndLine {
+		return to.LineNo > te.EndLine
+	}
+	return to.Position >= te.EndPos
+}
+
+/*
+Adjust the positions of tokens to account for
+the given edit. Tokens before the edit are kept
+as they are, tokens after it are moved, and tokens
+overlapping the edited range are dropped as they
+no longer reflect the text. Returns a new series
+of tokens; the given tokens are not modified.
+*/
+func ShiftTokens(tokens []TokenObject, edit TextEdit) TokenObjects {
+	endLine, endPos := edit.newEnd()
+	shifted := TokenObjects{}
+
+	for _, to := range tokens {
+		switch {
+		case edit.isBefore(to):
+		case edit.isAfter(to):
+			if to.LineNo == edit.EndLine {
+				to.Position = to.Position - edit.EndPos + endPos
+			}
+			to.LineNo = to.LineNo - edit.EndLine + endLine
+		default:
+			continue
+		}
+		shifted = append(shifted, to)
+	}
+	return shifted
+}
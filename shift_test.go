@@ -0,0 +1,62 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+type linePos struct {
+	line, pos uint64
+}
+
+func positionsOf(tokens []lexer.TokenObject) []linePos {
+	found := []linePos{}
+	for _, to := range tokens {
+		found = append(found, linePos{uint64(to.LineNo), uint64(to.Position)})
+	}
+	return found
+}
+
+func TestShiftTokens(t *testing.T) {
+	// " \t \n\t \t"
+	tokens := lexer.TokenizeLines([]string{" \t ", "\t \t"})
+	for i := range tokens {
+		tokens[i].LineNo += 1
+	}
+
+	cases := []struct {
+		name     string
+		edit     lexer.TextEdit
+		expected []linePos
+	}{
+		{
+			"insert on line",
+			lexer.TextEdit{StartLine: 1, StartPos: 2, EndLine: 1, EndPos: 2, Text: "ab"},
+			[]linePos{{1, 1}, {1, 4}, {1, 5}, {2, 1}, {2, 2}, {2, 3}},
+		},
+		{
+			"insert lines",
+			lexer.TextEdit{StartLine: 1, StartPos: 4, EndLine: 1, EndPos: 4, Text: "\n\nab"},
+			[]linePos{{1, 1}, {1, 2}, {1, 3}, {4, 1}, {4, 2}, {4, 3}},
+		},
+		{
+			"delete across lines",
+			lexer.TextEdit{StartLine: 1, StartPos: 2, EndLine: 2, EndPos: 2},
+			[]linePos{{1, 1}, {1, 2}, {1, 3}},
+		},
+	}
+	for _, c := range cases {
+		found := positionsOf(lexer.ShiftTokens(tokens, c.edit))
+		if len(found) != len(c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, found)
+			continue
+		}
+		for i := range found {
+			if found[i] != c.expected[i] {
+				t.Errorf("%s: expected %v, got %v", c.name, c.expected, found)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package lexer
+
+import (
+	"bytes"
+)
+
+/* --- SHRINKING ---
+Input found to trip up the lexer, by fuzzing
+say, is often far larger than the bug needs.
+`Shrink` cuts it down by delta debugging: parts
+of the input are removed for as long as what is
+left still reproduces the bug, first whole lines
+then single bytes, until no one part may be
+removed. What reproduces the bug is up to a
+predicate; a lexer gives predicates of common
+bugs. */
+
+/* Reports if the input reproduces the bug being shrunk. */
+type ShrinkPredicate func(input []byte) bool
+
+/*
+Shrink the input to a smaller input for which
+`fails` still holds, such that removing any one
+line or byte of it no longer does. Returns false
+if `fails` does not hold of the input given.
+*/
+func Shrink(input []byte, fails ShrinkPredicate) ([]byte, bool) {
+	if !fails(input) {
+		return input, false
+	}
+	lines := ddmin(splitUnits(input, true), fails)
+	return bytes.Join(ddmin(splitUnits(bytes.Join(lines, nil), false), fails), nil), true
+}
+
+/* Split input into lines, keeping terminators, or else bytes. */
+func splitUnits(input []byte, lines bool) [][]byte {
+	units := [][]byte{}
+	for len(input) > 0 {
+		n := 1
+		if lines {
+			if n = bytes.IndexByte(input, '\n') + 1; n == 0 {
+				n = len(input)
+			}
+		}
+		units = append(units, input[:n])
+		input = input[n:]
+	}
+	return units
+}
+
+/*
+Find a subset of the units for which `fails`
+holds of their concatenation, minimal in that
+removing any one unit no longer does. `fails`
+must hold of all of them.
+*/
+func ddmin(units [][]byte, fails ShrinkPredicate) [][]byte {
+	test := func(subset [][]byte) bool { return fails(bytes.Join(subset, nil)) }
+
+	n := 2
+	for len(units) >= 2 {
+		size := (len(units) + n - 1) / n
+		var chunks, rests [][][]byte
+		for start := 0; start < len(units); start += size {
+			end := start + size
+			if end > len(units) {
+				end = len(units)
+			}
+			chunks = append(chunks, units[start:end])
+			rests = append(rests, append(append([][]byte{}, units[:start]...), units[end:]...))
+		}
+
+		reduced := false
+		for _, chunk := range chunks {
+			if test(chunk) {
+				units, n, reduced = chunk, 2, true
+				break
+			}
+		}
+		// Of two chunks, each is the rest of the other.
+		for i := 0; i < len(rests) && !reduced && n > 2; i++ {
+			if test(rests[i]) {
+				units, n, reduced = rests[i], n-1, true
+			}
+		}
+		if reduced {
+			continue
+		}
+		if n >= len(units) {
+			break
+		}
+		if n *= 2; n > len(units) {
+			n = len(units)
+		}
+	}
+	if len(units) == 1 && test(nil) {
+		return nil
+	}
+	return units
+}
+
+/* Predicate holding of input making this lexer panic. */
+func (lx *Lexer) Panics() ShrinkPredicate {
+	return func(input []byte) (panicked bool) {
+		defer func() {
+			if recover() != nil {
+				panicked = true
+			}
+		}()
+		lx.TokenizeReader(bytes.NewReader(input))
+		return false
+	}
+}
+
+/* Predicate holding of input this lexer fails to tokenize. */
+func (lx *Lexer) Fails() ShrinkPredicate {
+	return func(input []byte) bool {
+		_, err := lx.TokenizeReader(bytes.NewReader(input))
+		return err != nil
+	}
+}
+
+/*
+Predicate holding of input this lexer gives a
+token of the named kind for, e.g. `ERROR` when
+it is the lexer's fallback.
+*/
+func (lx *Lexer) ProducesKind(name string) ShrinkPredicate {
+	return func(input []byte) bool {
+		tokens, _ := lx.TokenizeReader(bytes.NewReader(input))
+		for _, to := range tokens {
+			if to.Kind != nil && string(to.Kind.Name) == name {
+				return true
+			}
+		}
+		return false
+	}
+}
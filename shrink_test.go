@@ -0,0 +1,47 @@
+package lexer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestShrink(t *testing.T) {
+	input := []byte(strings.Repeat("let x = 1;\n", 20) + "let y = x $ 2;\n" + strings.Repeat("x = x + 1;\n", 20))
+	shrunk, ok := lexer.Shrink(input, func(in []byte) bool {
+		return bytes.Contains(in, []byte("$")) && bytes.Contains(in, []byte("y"))
+	})
+	if !ok || string(shrunk) != "y$" {
+		t.Errorf("expected y$, got %q", shrunk)
+	}
+
+	if _, ok := lexer.Shrink(input, func([]byte) bool { return false }); ok {
+		t.Error("expected input not reproducing the bug to be refused")
+	}
+}
+
+func TestShrinkPredicates(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	if err := g.SetIdentClasses("letter"); err != nil {
+		t.Fatal(err)
+	}
+	input := []byte("a + b\nc + d$e\nf\n")
+
+	lx := &lexer.Lexer{Grammar: g, Fallback: "ERROR"}
+	if shrunk, ok := lexer.Shrink(input, lx.ProducesKind("ERROR")); !ok || string(shrunk) != "$" {
+		t.Errorf("expected $, got %q", shrunk)
+	}
+	lx = &lexer.Lexer{Grammar: g, Strict: true}
+	if shrunk, ok := lexer.Shrink(input, lx.Fails()); !ok || string(shrunk) != "$" {
+		t.Errorf("expected $, got %q", shrunk)
+	}
+
+	lx = &lexer.Lexer{Grammar: g}
+	lx.OnToken("PLUS", func(ctx *lexer.TokenContext) { panic("plus") })
+	if shrunk, ok := lexer.Shrink(input, lx.Panics()); !ok || string(shrunk) != "+" {
+		t.Errorf("expected +, got %q", shrunk)
+	}
+}
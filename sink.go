@@ -0,0 +1,231 @@
+package lexer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+/* --- TOKEN SINKS ---
+Sinks receive tokens as the tokenizer produces them,
+rather than after a whole file has been tokenized.
+Slice, writer and channel sinks are provided. */
+
+/*
+Receives tokens from the tokenizer one at a time.
+`Flush` is called once all input has been
+tokenized.
+*/
+type TokenSink interface {
+	Emit(to TokenObject) error
+	Flush() error
+}
+
+/* Collects emitted tokens into a slice. */
+type SliceSink struct {
+	Tokens []TokenObject
+}
+
+func (ss *SliceSink) Emit(to TokenObject) error {
+	ss.Tokens = append(ss.Tokens, to)
+	return nil
+}
+
+func (ss *SliceSink) Flush() error { return nil }
+
+/*
+Writes emitted tokens as text lines, buffered
+until `Flush`.
+*/
+type WriterSink struct {
+	w *bufio.Writer
+}
+
+/* Initialize a new `WriterSink`. */
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{bufio.NewWriter(w)}
+}
+
+func (ws *WriterSink) Emit(to TokenObject) error {
+	_, err := fmt.Fprintf(ws.w, "%d:%d\t%s\t'%s'\n", to.LineNo, to.Position, to.Kind.Name, to.Symbol)
+	return err
+}
+
+func (ws *WriterSink) Flush() error { return ws.w.Flush() }
+
+/*
+Sends emitted tokens down a channel. The channel
+is left open on `Flush`; closing it is up to the
+caller.
+*/
+type ChanSink chan<- TokenObject
+
+func (cs ChanSink) Emit(to TokenObject) error {
+	cs <- to
+	return nil
+}
+
+func (cs ChanSink) Flush() error { return nil }
+
+/*
+Break down a single line, emitting each token
+to the given sink. The sink is not flushed. In
+strict mode, the tokens before any unknown input
+are emitted before the error is returned.
+*/
+func (lx *Lexer) TokenizeLineTo(line string, lineNo tokenLineNo, sink TokenSink) error {
+	tokens, tokErr := lx.tokenizeLineErr(line, lineNo)
+	for _, to := range tokens {
+		if err := sink.Emit(to); err != nil {
+			return err
+		}
+	}
+	return tokErr
+}
+
+/*
+Break down a single line, emitting each token
+to the given sink. The sink is not flushed.
+*/
+func (g *Grammar) TokenizeLineTo(line string, lineNo tokenLineNo, sink TokenSink) error {
+	return g.lexer().TokenizeLineTo(line, lineNo, sink)
+}
+
+/*
+Break down a single line using the default
+grammar, emitting each token to the given sink.
+*/
+func TokenizeLineTo(line string, lineNo tokenLineNo, sink TokenSink) error {
+	return defaultGrammar.TokenizeLineTo(line, lineNo, sink)
+}
+
+/*
+Tokens of one input on their way to a sink,
+tokenized line by line as `tokenizeTokenFile`
+does. Tokens are held until later lines can no
+longer change them: the last token that is
+neither whitespace nor a comment may yet have
+comments attached, and line breaks after it may
+yet collapse into one.
+*/
+type sinkRun struct {
+	lx      *Lexer
+	sink    TokenSink
+	run     lexRun
+	pending TokenObjects // Tokens not yet emitted.
+	lineNo  tokenLineNo
+}
+
+/* Start tokenizing an input to the given sink. */
+func (lx *Lexer) newSinkRun(sink TokenSink) *sinkRun {
+	return &sinkRun{lx: lx, sink: sink, run: lx.newRun()}
+}
+
+/*
+Tokenize the next line, with its terminator if
+it has one, emitting the tokens it settles. In
+strict mode, every token before any unknown
+input is emitted before the error is returned.
+*/
+func (sr *sinkRun) line(text string) error {
+	sr.lineNo += 1
+	line := splitTerminator(text, 0)
+	start := len(sr.pending)
+	sr.run.lap(stageScanning)
+
+	var err error
+	sr.pending, err = sr.lx.tokenizeLineLocked(sr.pending, line.Text, nil, sr.lineNo, &sr.run)
+	if err != nil {
+		if emitErr := sr.emit(len(sr.pending)); emitErr != nil {
+			return emitErr
+		}
+		return err
+	}
+	sr.pending = sr.lx.endLine(sr.pending, start, len(line.Text), line.Terminator, sr.lineNo, &sr.run)
+	return sr.emit(sr.settled())
+}
+
+/*
+Number of pending tokens no later line can
+change: those before the last token that is
+neither whitespace nor a comment, or if there is
+none, all but a trailing line break.
+*/
+func (sr *sinkRun) settled() int {
+	for i := len(sr.pending) - 1; i >= 0; i-- {
+		if to := sr.pending[i]; !to.IsTrivia() && to.Kind.Id != CommentId {
+			return i
+		}
+	}
+	if n := len(sr.pending); n > 0 && sr.pending[n-1].Kind.Id == NewlineId {
+		return n - 1
+	}
+	return len(sr.pending)
+}
+
+/* Emit the first `n` pending tokens. */
+func (sr *sinkRun) emit(n int) error {
+	for _, to := range sr.pending[:n] {
+		if err := sr.sink.Emit(to); err != nil {
+			return err
+		}
+	}
+	sr.pending = append(sr.pending[:0], sr.pending[n:]...)
+	return nil
+}
+
+/* Emit the tokens held back, then flush the sink. */
+func (sr *sinkRun) close() error {
+	if err := sr.emit(len(sr.pending)); err != nil {
+		return err
+	}
+	return sr.sink.Flush()
+}
+
+/*
+Break down a file line by line, emitting each
+token to the given sink as soon as later lines
+can no longer change it. Tokens are as
+`TokenizeFile` gives them. The sink is flushed
+once the file has been read.
+*/
+func (lx *Lexer) TokenizeFileTo(name string, sink TokenSink) error {
+	file, err := openTokenFile(name, lx.decoder())
+	if err != nil {
+		return withFilename(err, name)
+	}
+	defer file.Close()
+
+	if lx.Newlines != NewlineOmit {
+		// Line breaks are only known with their terminators.
+		file.scanner.Split(scanLinesKeep)
+	}
+	sr := lx.newSinkRun(sink)
+	for file.Scan() {
+		if err := sr.line(file.Text()); err != nil {
+			return withFilename(err, name)
+		}
+	}
+	if err := file.Err(); err != nil {
+		return withFilename(err, name)
+	}
+	return withFilename(sr.close(), name)
+}
+
+/*
+Break down a file line by line, emitting each
+token to the given sink as its line is
+tokenized. The sink is flushed once the file has
+been read.
+*/
+func (g *Grammar) TokenizeFileTo(name string, sink TokenSink) error {
+	return g.lexer().TokenizeFileTo(name, sink)
+}
+
+/*
+Break down a file line by line using the default
+grammar, emitting each token to the given sink.
+*/
+func TokenizeFileTo(name string, sink TokenSink) error {
+	return defaultGrammar.TokenizeFileTo(name, sink)
+}
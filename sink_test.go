@@ -0,0 +1,113 @@
+package lexer_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func writeSource(t *testing.T, text string) string {
+	name := filepath.Join(t.TempDir(), "source.pz")
+	if err := os.WriteFile(name, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestTokenizeFileTo(t *testing.T) {
+	name := writeSource(t, "\t \n \t\n")
+
+	sink := &lexer.SliceSink{}
+	if err := lexer.TokenizeFileTo(name, sink); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.Tokens) != 4 {
+		t.Fatalf("expected 4 tokens, got %d", len(sink.Tokens))
+	}
+	if sink.Tokens[2].LineNo != 2 {
+		t.Errorf("expected third token on line 2, got %d", sink.Tokens[2].LineNo)
+	}
+
+	var buf bytes.Buffer
+	if err := lexer.TokenizeFileTo(name, lexer.NewWriterSink(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	expected := "1:1\tTABLINE\t'\t'\n1:2\tWHTSPACE\t' '\n2:1\tWHTSPACE\t' '\n2:2\tTABLINE\t'\t'\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	ch := make(chan lexer.TokenObject, len(sink.Tokens))
+	if err := lexer.TokenizeFileTo(name, lexer.ChanSink(ch)); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+	if len(ch) != len(sink.Tokens) {
+		t.Errorf("expected %d tokens on channel, got %d", len(sink.Tokens), len(ch))
+	}
+}
+
+func TestTokenizeFileToMissing(t *testing.T) {
+	if err := lexer.TokenizeFileTo("does-not-exist.pz", &lexer.SliceSink{}); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestTokenizeFileToMatchesTokenizeFile(t *testing.T) {
+	name := writeSource(t, "# header\na + b # sum\n\n  \n\nc # c\n# trailing\n")
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	if err := g.SetLineComments("#"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, comments := range []lexer.CommentMode{lexer.CommentEmit, lexer.CommentAttach, lexer.CommentDrop} {
+		for _, newlines := range []lexer.NewlineMode{lexer.NewlineOmit, lexer.NewlineEmit, lexer.NewlineCollapse} {
+			lx := &lexer.Lexer{Grammar: g, Comments: comments, Newlines: newlines}
+			want, err := lx.TokenizeFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sink := &lexer.SliceSink{}
+			if err := lx.TokenizeFileTo(name, sink); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(lexer.TokenObjects(sink.Tokens), want) {
+				t.Errorf("%s, %s: expected %v from the sink, got %v", comments, newlines, want, sink.Tokens)
+			}
+
+			fed := &lexer.SliceSink{}
+			feeder := lx.NewFeeder(fed)
+			text, _ := os.ReadFile(name)
+			for _, b := range text {
+				feeder.Write([]byte{b})
+			}
+			if err := feeder.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(lexer.TokenObjects(fed.Tokens), want) {
+				t.Errorf("%s, %s: expected %v from the feeder, got %v", comments, newlines, want, fed.Tokens)
+			}
+		}
+	}
+}
+
+func TestTokenizeFileToStrictFilename(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	lx := &lexer.Lexer{Grammar: g, Strict: true}
+	name := writeSource(t, "+\n+ ?\n")
+
+	sink := &lexer.SliceSink{}
+	err := lx.TokenizeFileTo(name, sink)
+	var unknown lexer.UnknownInputError
+	if !errors.As(err, &unknown) || unknown.Filename != name || unknown.LineNo != 2 {
+		t.Errorf("expected '?' reported in %s at line 2, got %v", name, err)
+	}
+}
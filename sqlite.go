@@ -0,0 +1,123 @@
+package lexer
+
+import (
+	"database/sql"
+)
+
+/* --- SQLITE EXPORT ---
+Tokens may be written into a SQLite database, so
+token corpora of large code bases can be queried
+with SQL. Any `database/sql` driver for SQLite
+may be used; the lexer depends on none, so the
+caller opens the database with the driver of
+their choosing.
+
+Two tables are written: `kinds`, the kinds of
+each grammar exported, and `tokens`, every token
+by file, with columns for the file, line, kind
+and symbol indexed. */
+
+// Statements creating the tables and indexes
+// written to, if missing.
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS kinds (
+		grammar TEXT NOT NULL,
+		id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		signature TEXT NOT NULL,
+		description TEXT NOT NULL,
+		PRIMARY KEY (grammar, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS tokens (
+		file TEXT NOT NULL,
+		seq INTEGER NOT NULL,
+		line INTEGER NOT NULL,
+		position INTEGER NOT NULL,
+		grammar TEXT NOT NULL,
+		kind INTEGER NOT NULL,
+		kind_name TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		PRIMARY KEY (file, seq)
+	)`,
+	`CREATE INDEX IF NOT EXISTS tokens_line ON tokens (file, line)`,
+	`CREATE INDEX IF NOT EXISTS tokens_kind ON tokens (kind_name)`,
+	`CREATE INDEX IF NOT EXISTS tokens_symbol ON tokens (symbol)`,
+}
+
+/* Create the tables tokens are exported to, if missing. */
+func CreateSQLiteTables(db *sql.DB) error {
+	for _, stmt := range sqliteSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Write the tokens of a result into the database,
+along with the kinds of the grammar they were
+tokenized with, in a single transaction. Tokens
+previously exported for the same source are
+replaced. The tables must have been created with
+`CreateSQLiteTables`.
+*/
+func ExportSQLite(db *sql.DB, g *Grammar, result TokenResult) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := exportSQLite(tx, g, result); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+/* Copy every kind of the grammar, in order of ID. */
+func (g *Grammar) allKinds() []TokenKind {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	kinds := []TokenKind{}
+	for _, id := range g.kinds.Ids() {
+		kinds = append(kinds, g.kinds.Get(id))
+	}
+	return kinds
+}
+
+/* Write the kinds and tokens of an export. */
+func exportSQLite(tx *sql.Tx, g *Grammar, result TokenResult) error {
+	kinds, err := tx.Prepare(`INSERT OR REPLACE INTO kinds (grammar, id, name, signature, description) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer kinds.Close()
+
+	// Kinds are copied first, so the grammar is
+	// not locked while the database is written.
+	for _, kind := range g.allKinds() {
+		_, err := kinds.Exec(g.Name, int64(kind.Id), string(kind.Name), kind.Signature.String(), string(kind.Description))
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM tokens WHERE file = ?`, result.Source); err != nil {
+		return err
+	}
+	tokens, err := tx.Prepare(`INSERT INTO tokens (file, seq, line, position, grammar, kind, kind_name, symbol) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer tokens.Close()
+
+	for i, to := range result.Tokens {
+		_, err := tokens.Exec(result.Source, i, int64(to.LineNo), int64(to.Position), g.Name,
+			int64(to.Kind.Id), string(to.Kind.Name), string(to.Symbol))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
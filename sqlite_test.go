@@ -0,0 +1,135 @@
+package lexer_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+// A driver recording the statements executed,
+// standing in for a SQLite driver.
+type recordDriver struct{ log *[]string }
+type recordConn struct{ log *[]string }
+type recordStmt struct {
+	log   *[]string
+	query string
+}
+
+func (d recordDriver) Open(string) (driver.Conn, error) { return recordConn(d), nil }
+
+func (c recordConn) Prepare(query string) (driver.Stmt, error) {
+	return recordStmt{c.log, query}, nil
+}
+func (c recordConn) Close() error              { return nil }
+func (c recordConn) Begin() (driver.Tx, error) { return c, nil }
+func (c recordConn) Commit() error             { *c.log = append(*c.log, "COMMIT"); return nil }
+func (c recordConn) Rollback() error           { *c.log = append(*c.log, "ROLLBACK"); return nil }
+
+func (s recordStmt) Close() error  { return nil }
+func (s recordStmt) NumInput() int { return -1 }
+func (s recordStmt) Exec(args []driver.Value) (driver.Result, error) {
+	verb := strings.Fields(s.query)[0]
+	*s.log = append(*s.log, fmt.Sprint(verb, args))
+	if sqlExecHook != nil {
+		sqlExecHook()
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s recordStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+var sqlLog []string
+
+// Called on every statement executed, if set.
+var sqlExecHook func()
+
+func init() {
+	sql.Register("lexer-record", recordDriver{&sqlLog})
+}
+
+func TestExportSQLite(t *testing.T) {
+	db, err := sql.Open("lexer-record", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "Addition.")
+	result, err := g.TokenizeSource("sum.x", strings.NewReader("a+b\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sqlLog = nil
+	if err := lexer.CreateSQLiteTables(db); err != nil {
+		t.Fatal(err)
+	}
+	if len(sqlLog) != 5 || !strings.HasPrefix(sqlLog[0], "CREATE") {
+		t.Fatalf("unexpected schema statements %q", sqlLog)
+	}
+
+	sqlLog = nil
+	if err := lexer.ExportSQLite(db, g, result); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"DELETE[sum.x]",
+		"INSERT[sum.x 0 1 1 x 1 GENIDEN a]",
+		"INSERT[sum.x 1 1 2 x 256 PLUS +]",
+		"INSERT[sum.x 2 1 3 x 1 GENIDEN b]",
+		"COMMIT",
+	}
+	if got := sqlLog[len(sqlLog)-len(want):]; strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected tokens\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	if kind := sqlLog[len(sqlLog)-len(want)-1]; kind != "INSERT[x 256 PLUS + Addition.]" {
+		t.Errorf("expected PLUS exported last of the kinds, got %s", kind)
+	}
+}
+
+func TestExportSQLiteUnlocked(t *testing.T) {
+	db, err := sql.Open("lexer-record", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	g := lexer.NewGrammar("x")
+	result, err := g.TokenizeSource("a.x", strings.NewReader("a\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The grammar may be changed while the
+	// database is written, as a slow database
+	// would otherwise stall its other users.
+	changed := false
+	sqlExecHook = func() {
+		sqlExecHook = nil
+		done := make(chan struct{})
+		go func() {
+			g.AddKind("PLUS", "+", "")
+			close(done)
+		}()
+		select {
+		case <-done:
+			changed = true
+		case <-time.After(time.Second):
+		}
+	}
+	defer func() { sqlExecHook = nil }()
+
+	if err := lexer.ExportSQLite(db, g, result); err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("expected the grammar unlocked while exporting")
+	}
+}
@@ -0,0 +1,336 @@
+package lexer
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+/* --- TOKEN STREAMS ---
+Helpers for picking through a series of tokens, so
+consumers need not re-write the same loops over
+the results of the tokenizer. */
+
+/*
+Find the index of the first token of the given
+kind. Returns -1 if none found.
+*/
+func (tom TokenObjects) FirstOfKind(kind TokenKind) int {
+	for i, to := range tom {
+		if to.Is(kind) {
+			return i
+		}
+	}
+	return -1
+}
+
+/* Retrieve every token of the given kind. */
+func (tom TokenObjects) AllOfKind(kind TokenKind) TokenObjects {
+	found := TokenObjects{}
+	for _, to := range tom {
+		if to.Is(kind) {
+			found = append(found, to)
+		}
+	}
+	return found
+}
+
+/*
+Retrieve the tokens between the first token of
+the open kind and its matching token of the close
+kind, excluding both. Nested pairs of the two
+kinds are included in the result. Returns false
+if there is no open token or it is never closed.
+*/
+func (tom TokenObjects) Between(openKind TokenKind, closeKind TokenKind) (TokenObjects, bool) {
+	start := tom.FirstOfKind(openKind)
+	if start < 0 {
+		return nil, false
+	}
+
+	depth := 0
+	for i := start + 1; i < len(tom); i++ {
+		switch {
+		case tom[i].Is(openKind):
+			depth += 1
+		case tom[i].Is(closeKind):
+			if depth == 0 {
+				return tom[start+1 : i], true
+			}
+			depth -= 1
+		}
+	}
+	return nil, false
+}
+
+/*
+Retrieve up to `n` tokens starting from index
+`i`, clipped to the bounds of the series.
+*/
+func (tom TokenObjects) Window(i int, n int) TokenObjects {
+	if i < 0 {
+		n += i
+		i = 0
+	}
+	if i > len(tom) || n <= 0 {
+		return TokenObjects{}
+	}
+	if i+n > len(tom) {
+		n = len(tom) - i
+	}
+	return tom[i : i+n]
+}
+
+/* The range of token indexes of a single line. */
+type lineRange struct {
+	start int
+	end   int
+}
+
+/* A line of input, as it was split. */
+type InputLine struct {
+	Text       string // The line without its terminator.
+	Terminator string // "\n", "\r\n", or empty for a final line without one.
+	Offset     int    // Byte offset of the line from the start of input.
+}
+
+/*
+Locates the tokens of each line in a series of
+tokens, as produced by the tokenizer. When built
+while tokenizing, the original text of each line
+is kept as well.
+*/
+type LineIndex struct {
+	tokens TokenObjects
+	lines  map[tokenLineNo]lineRange
+	input  []InputLine // Every line read, from line 1.
+}
+
+/*
+Build an index of the lines in this series. The
+tokens of each line are expected to be together
+and in order, as the tokenizer produces them.
+*/
+func (tom TokenObjects) LineIndex() LineIndex {
+	li := LineIndex{tom, map[tokenLineNo]lineRange{}, nil}
+
+	for i, to := range tom {
+		lr, ok := li.lines[to.LineNo]
+		if !ok {
+			lr.start = i
+		}
+		lr.end = i + 1
+		li.lines[to.LineNo] = lr
+	}
+	return li
+}
+
+/* Retrieve the tokens of the given line. */
+func (li LineIndex) Line(lineNo tokenLineNo) TokenObjects {
+	lr, ok := li.lines[lineNo]
+	if !ok {
+		return TokenObjects{}
+	}
+	return li.tokens[lr.start:lr.end]
+}
+
+/* Retrieve the numbers of lines holding tokens, in order. */
+func (li LineIndex) Lines() []tokenLineNo {
+	lines := []tokenLineNo{}
+	for lineNo := range li.lines {
+		lines = append(lines, lineNo)
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i] < lines[j] })
+	return lines
+}
+
+/* Retrieve every token in this index. */
+func (li LineIndex) Tokens() TokenObjects {
+	return li.tokens
+}
+
+/*
+Retrieve the original text of the given line,
+without its terminator, such as for showing the
+line a token is on. Returns false if the line was
+not recorded.
+*/
+func (li LineIndex) Text(lineNo tokenLineNo) (string, bool) {
+	line, ok := li.Input(lineNo)
+	return line.Text, ok
+}
+
+/*
+Retrieve the given line as it was split from the
+input. Returns false if the line was not
+recorded.
+*/
+func (li LineIndex) Input(lineNo tokenLineNo) (InputLine, bool) {
+	if lineNo < 1 || int(lineNo) > len(li.input) {
+		return InputLine{}, false
+	}
+	return li.input[lineNo-1], true
+}
+
+/*
+Rebuild the input the index was built from,
+terminators included. Empty if the original
+text was not kept.
+*/
+func (li LineIndex) Source() string {
+	var sb strings.Builder
+	for _, line := range li.input {
+		sb.WriteString(line.Text)
+		sb.WriteString(line.Terminator)
+	}
+	return sb.String()
+}
+
+/*
+Split function for a `bufio.Scanner` yielding
+each line with its terminator.
+*/
+func scanLinesKeep(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+/*
+Split a line from its terminator. A carriage
+return is only taken as a terminator at the end
+of input, as `bufio.ScanLines` does.
+*/
+func splitTerminator(line string, offset int) InputLine {
+	for _, term := range []string{"\r\n", "\n", "\r"} {
+		if strings.HasSuffix(line, term) {
+			return InputLine{line[:len(line)-len(term)], term, offset}
+		}
+	}
+	return InputLine{line, "", offset}
+}
+
+/*
+Break down each line read from `r` into tokens,
+keeping the text of each line alongside them.
+Lines are numbered from 1.
+*/
+func (lx *Lexer) TokenizeIndexed(r io.Reader) (LineIndex, error) {
+	r, err := newTextReader(r, lx.decoder())
+	if err != nil {
+		return LineIndex{}, err
+	}
+
+	li := LineIndex{TokenObjects{}, map[tokenLineNo]lineRange{}, []InputLine{}}
+	scanner := newLineScanner(r)
+	scanner.Split(scanLinesKeep)
+	lineNo := tokenLineNo(0)
+	offset := 0
+	run := lx.newRun()
+
+	for scanner.Scan() {
+		lineNo += 1
+		line := splitTerminator(scanner.Text(), offset)
+		offset += len(line.Text) + len(line.Terminator)
+		start := len(li.tokens)
+		run.lap(stageScanning)
+
+		li.input = append(li.input, line)
+		li.tokens, err = lx.tokenizeLineLocked(li.tokens, line.Text, nil, lineNo, &run)
+		if err == nil {
+			li.tokens = lx.endLine(li.tokens, start, len(line.Text), line.Terminator, lineNo, &run)
+		}
+		if len(li.tokens) > start {
+			li.lines[lineNo] = lineRange{start, len(li.tokens)}
+		}
+		if err != nil {
+			return li, err
+		}
+	}
+	return li, scanner.Err()
+}
+
+/*
+Break down each line read from `r` into tokens,
+keeping the text of each line alongside them.
+*/
+func (g *Grammar) TokenizeIndexed(r io.Reader) (LineIndex, error) {
+	return g.lexer().TokenizeIndexed(r)
+}
+
+/*
+Break down each line read from `r` into tokens
+using the default grammar, keeping the text of
+each line alongside them.
+*/
+func TokenizeIndexed(r io.Reader) (LineIndex, error) {
+	return defaultGrammar.TokenizeIndexed(r)
+}
+
+/*
+Break down text into tokens, splitting it into
+lines and keeping the text and terminator of
+each line alongside them. Lines are numbered
+from 1.
+*/
+func (lx *Lexer) TokenizeText(text string) (LineIndex, error) {
+	return lx.TokenizeIndexed(strings.NewReader(text))
+}
+
+/*
+Break down text into tokens, splitting it into
+lines and keeping the text and terminator of
+each line alongside them.
+*/
+func (g *Grammar) TokenizeText(text string) (LineIndex, error) {
+	return g.lexer().TokenizeText(text)
+}
+
+/*
+Break down text into tokens using the default
+grammar, splitting it into lines and keeping the
+text and terminator of each line alongside them.
+*/
+func TokenizeText(text string) (LineIndex, error) {
+	return defaultGrammar.TokenizeText(text)
+}
+
+/*
+Break down each line of a file into tokens,
+keeping the text of each line alongside them.
+*/
+func (lx *Lexer) TokenizeFileIndexed(name string) (LineIndex, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return LineIndex{}, err
+	}
+	defer file.Close()
+
+	return lx.TokenizeIndexed(file)
+}
+
+/*
+Break down each line of a file into tokens,
+keeping the text of each line alongside them.
+*/
+func (g *Grammar) TokenizeFileIndexed(name string) (LineIndex, error) {
+	return g.lexer().TokenizeFileIndexed(name)
+}
+
+/*
+Break down each line of a file into tokens using
+the default grammar, keeping the text of each
+line alongside them.
+*/
+func TokenizeFileIndexed(name string) (LineIndex, error) {
+	return defaultGrammar.TokenizeFileIndexed(name)
+}
@@ -0,0 +1,115 @@
+package lexer_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestStreamHelpers(t *testing.T) {
+	g := lexer.NewGrammar("stream")
+	open := g.AddKind("LPAREN", "(", "")
+	close := g.AddKind("RPAREN", ")", "")
+	tokens := g.TokenizeLines([]string{"a(b(c)d)e", "", "(f"})
+	for i := range tokens {
+		tokens[i].LineNo += 1
+	}
+
+	if i := tokens.FirstOfKind(close); i != 5 {
+		t.Errorf("expected first RPAREN at 5, got %d", i)
+	}
+	if found := tokens.AllOfKind(open); len(found) != 3 {
+		t.Errorf("expected 3 LPAREN, got %v", found)
+	}
+
+	between, ok := tokens.Between(open, close)
+	if found := fmt.Sprint(symbolsOf(between)); !ok || found != "[b ( c ) d]" {
+		t.Errorf("unexpected tokens between parens %s", found)
+	}
+	if _, ok := tokens[8:].Between(open, close); ok {
+		t.Error("expected an unclosed paren to find nothing")
+	}
+
+	if found := fmt.Sprint(symbolsOf(tokens.Window(7, 5))); found != "[) e ( f]" {
+		t.Errorf("unexpected window %s", found)
+	}
+	if found := tokens.Window(-2, 3); len(found) != 1 {
+		t.Errorf("expected window clipped to 1 token, got %v", found)
+	}
+
+	index := tokens.LineIndex()
+	if found := fmt.Sprint(index.Lines()); found != "[1 3]" {
+		t.Errorf("unexpected lines %s", found)
+	}
+	if found := fmt.Sprint(symbolsOf(index.Line(3))); found != "[( f]" {
+		t.Errorf("unexpected tokens on line 3 %s", found)
+	}
+	if found := index.Line(2); len(found) != 0 {
+		t.Errorf("expected no tokens on line 2, got %v", found)
+	}
+}
+
+func TestTokenizeIndexed(t *testing.T) {
+	g := lexer.NewGrammar("indexed")
+	g.AddKind("PLUS", "+", "")
+
+	index, err := g.TokenizeIndexed(strings.NewReader("a + b\n\nc+\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := index.Tokens()
+	if len(tokens) != 7 {
+		t.Fatalf("expected 7 tokens, got %v", tokens)
+	}
+
+	last := tokens[len(tokens)-1]
+	if text, ok := index.Text(last.LineNo); !ok || text != "c+" {
+		t.Errorf("expected line text %q, got %q", "c+", text)
+	}
+	if text, ok := index.Text(2); !ok || text != "" {
+		t.Errorf("expected empty line 2 to be recorded, got %q", text)
+	}
+	if _, ok := index.Text(4); ok {
+		t.Error("expected no text past the last line")
+	}
+	if found := fmt.Sprint(symbolsOf(index.Line(3))); found != "[c +]" {
+		t.Errorf("unexpected tokens on line 3 %s", found)
+	}
+}
+
+func TestTokenizeText(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "plus")
+
+	src := "a+b\r\n\nc\n+d"
+	index, err := g.TokenizeText(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := index.Source(); got != src {
+		t.Errorf("expected round trip of %q, got %q", src, got)
+	}
+
+	want := []lexer.InputLine{
+		{Text: "a+b", Terminator: "\r\n", Offset: 0},
+		{Text: "", Terminator: "\n", Offset: 5},
+		{Text: "c", Terminator: "\n", Offset: 6},
+		{Text: "+d", Terminator: "", Offset: 8},
+	}
+	lineNo := index.Tokens()[0].LineNo
+	for _, w := range want {
+		line, ok := index.Input(lineNo)
+		if !ok || line != w {
+			t.Errorf("line %d: expected %+v, got %+v", lineNo, w, line)
+		}
+		lineNo += 1
+	}
+	if _, ok := index.Input(5); ok {
+		t.Error("expected no line past the end")
+	}
+	if toks := index.Line(4); len(toks) != 2 || toks[1].Symbol.String() != "d" {
+		t.Errorf("unexpected tokens of last line %v", toks)
+	}
+}
@@ -0,0 +1,47 @@
+package lexer
+
+import "fmt"
+
+/* --- STRICT MODE ---
+Input matching no kind is normally emitted as a
+token of the lexer's fallback kind. A strict
+lexer fails on it instead, for input that must
+hold nothing but known tokens, say generated DSL
+files.
+
+Identifiers declared with `@ident` are still
+allowed; without `@ident`, every generic symbol
+is unknown input. */
+
+/* Input matching no kind, found by a strict lexer. */
+type UnknownInputError struct {
+	Filename string // Name of the source, if known.
+	LineNo   tokenLineNo
+	Position tokenPosition
+	Symbol   string
+}
+
+/* Where the unknown input is. */
+func (ue UnknownInputError) Pos() Position {
+	return Position{Filename: ue.Filename, Offset: -1, Line: int(ue.LineNo), Column: int(ue.Position)}
+}
+
+func (ue UnknownInputError) Error() string {
+	return fmt.Sprintf("%s: unknown input '%s'", ue.Pos(), ue.Symbol)
+}
+
+/*
+Determine if a generic symbol is made only of
+the grammar's identifier classes.
+*/
+func (g *Grammar) isIdentifier(symbol string) bool {
+	if g.ident == nil || symbol == "" {
+		return false
+	}
+	for _, r := range symbol {
+		if !g.ident.Contains(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package lexer_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestStrictMode(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	lx := &lexer.Lexer{Grammar: g, Strict: true}
+
+	if _, err := lx.TokenizeReader(strings.NewReader("+ +\n++\n")); err != nil {
+		t.Fatalf("expected known input to pass, got %v", err)
+	}
+
+	tokens, err := lx.TokenizeReader(strings.NewReader("+\n+ ?\n"))
+	var unknown lexer.UnknownInputError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected an UnknownInputError, got %v", err)
+	}
+	if unknown.LineNo != 2 || unknown.Position != 3 || unknown.Symbol != "?" {
+		t.Errorf("expected '?' at 2:3, got %v", unknown)
+	}
+	if len(tokens) != 3 {
+		t.Errorf("expected the tokens before the error, got %v", tokens)
+	}
+
+	// Declared identifiers are not unknown input.
+	if err := g.SetIdentClasses("letter"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lx.TokenizeBytes([]byte("a+b")); err != nil {
+		t.Errorf("expected identifiers to pass, got %v", err)
+	}
+	if _, err := lx.TokenizeBytes([]byte("a+1")); err == nil {
+		t.Error("expected an error for '1'")
+	}
+
+	sink := &lexer.SliceSink{}
+	if err := lx.TokenizeLineTo("a ?", 1, sink); err == nil || len(sink.Tokens) != 2 {
+		t.Errorf("expected 2 tokens and an error, got %v, %v", sink.Tokens, err)
+	}
+}
@@ -0,0 +1,143 @@
+package lexer
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+/* --- SUGGESTIONS ---
+When a symbol is not a known token but closely
+resembles one, such as `=!` or `retrun`, suggest
+the kinds it may have been meant as. Resemblance
+is measured by edit distance, counting swapped
+adjacent characters as a single edit. */
+
+/* A kind a symbol may have been meant as. */
+type Suggestion struct {
+	Kind     TokenKind
+	Distance int // Number of edits between the two.
+}
+
+/*
+Calculate the number of insertions, deletions,
+substitutions and adjacent transpositions needed
+to turn `a` into `b`.
+*/
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	rows := make([][]int, len(ra)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(rb)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			best := rows[i-1][j] + 1
+			if d := rows[i][j-1] + 1; d < best {
+				best = d
+			}
+			if d := rows[i-1][j-1] + cost; d < best {
+				best = d
+			}
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				if d := rows[i-2][j-2] + 1; d < best {
+					best = d
+				}
+			}
+			rows[i][j] = best
+		}
+	}
+	return rows[len(ra)][len(rb)]
+}
+
+/*
+Determine how many edits a symbol may be from a
+signature to be considered a near miss; short
+symbols allow fewer.
+*/
+func suggestionLimit(symbol string) int {
+	if len([]rune(symbol)) <= 3 {
+		return 1
+	}
+	return 2
+}
+
+/*
+Find the kinds defined by the tokens file that
+the given symbol closely resembles, closest
+first. Built in kinds are never suggested.
+*/
+func (g *Grammar) Suggest(symbol string) []Suggestion {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.suggest(symbol)
+}
+
+/*
+Find kinds resembling the symbol. The caller
+must hold the grammar's read lock.
+*/
+func (g *Grammar) suggest(symbol string) []Suggestion {
+	limit := suggestionLimit(symbol)
+	found := []Suggestion{}
+
+	for _, id := range g.kinds.Ids() {
+		if id < FirstUserKindId {
+			continue
+		}
+		kind := g.kinds.Get(id)
+		d := editDistance(symbol, string(kind.Signature))
+		if d > 0 && d <= limit {
+			found = append(found, Suggestion{kind, d})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Distance < found[j].Distance
+	})
+	return found
+}
+
+/* Find kinds of the default grammar resembling the symbol. */
+func Suggest(symbol string) []Suggestion {
+	return defaultGrammar.Suggest(symbol)
+}
+
+/*
+Build a diagnostic message for a token not
+matching any kind, suggesting the closest kind if
+there is one.
+*/
+func (g *Grammar) UnknownTokenMessage(tok TokenObject) string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return fmt.Sprintf("unknown token `%s`%s", tok.Symbol, g.didYouMean(string(tok.Symbol)))
+}
+
+/*
+Hint at the closest kind to the symbol, e.g.
+"; did you mean `==`?"; empty if there is none.
+Single characters get no hint, being an edit
+from every other. The caller must hold the
+grammar's read lock.
+*/
+func (g *Grammar) didYouMean(symbol string) string {
+	if utf8.RuneCountInString(symbol) < 2 {
+		return ""
+	}
+	if found := g.suggest(symbol); len(found) > 0 {
+		return fmt.Sprintf("; did you mean `%s`?", found[0].Kind.Signature)
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package lexer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestSuggest(t *testing.T) {
+	g := lexer.NewGrammar("suggest")
+	g.AddKind("NE", "!=", "")
+	g.AddKind("EQ", "==", "")
+	g.AddKind("RETURN", "return", "")
+
+	found := g.Suggest("=!")
+	if len(found) != 2 || found[0].Kind.Name != "NE" || found[0].Distance != 1 {
+		t.Errorf("unexpected suggestions for =! %v", found)
+	}
+	if found := g.Suggest("retrun"); len(found) != 1 || found[0].Kind.Name != "RETURN" {
+		t.Errorf("unexpected suggestions for retrun %v", found)
+	}
+	if found := g.Suggest("value"); len(found) != 0 {
+		t.Errorf("expected no suggestions for value, got %v", found)
+	}
+
+	tok := g.TokenizeLine("retrun", 1)[0]
+	if msg := g.UnknownTokenMessage(tok); msg != "unknown token `retrun`; did you mean `return`?" {
+		t.Errorf("unexpected message %q", msg)
+	}
+
+	diags := &lexer.Diagnostics{}
+	lx := &lexer.Lexer{Grammar: g, Diagnostics: diags}
+	if _, err := lx.TokenizeReader(strings.NewReader("a =! b\n")); err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for _, d := range diags.List {
+		messages = append(messages, d.Message)
+	}
+	if !strings.Contains(strings.Join(messages, "\n"), "unknown input '=!'; did you mean `!=`?") {
+		t.Errorf("expected a suggestion diagnosed, got %q", messages)
+	}
+}
@@ -0,0 +1,89 @@
+package lexer
+
+import "strings"
+
+/* --- SUPPRESSION ---
+A line may silence diagnostics about it with a
+suppression marker, e.g. `#: lex-ignore LEX001`,
+once a lexer's `Suppress` is set to the marker.
+The codes following the marker, separated by
+spaces or commas, are silenced; a marker with no
+codes silences every diagnostic. Lint rules may
+be named in place of their codes.
+
+Unknown input silenced does not fail a strict
+lexer, but falls back on the fallback kind as it
+would otherwise. */
+
+/* Diagnostics a line silences. */
+type suppression struct {
+	all   bool     // Every diagnostic, as no codes were given.
+	codes []string // Codes or rule names silenced.
+}
+
+/*
+Find the suppression marker in a line, and the
+codes following it. The marker must end at a
+space, a tab or the end of the line.
+*/
+func parseSuppression(line, marker string) suppression {
+	if marker == "" {
+		return suppression{}
+	}
+	for from := 0; ; {
+		i := strings.Index(line[from:], marker)
+		if i < 0 {
+			return suppression{}
+		}
+		rest := line[from+i+len(marker):]
+		if rest == "" || rest[0] == ' ' || rest[0] == '\t' {
+			codes := strings.FieldsFunc(rest, func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t' || r == '\r'
+			})
+			return suppression{all: len(codes) == 0, codes: codes}
+		}
+		from += i + len(marker)
+	}
+}
+
+/* Determine if the diagnostic is silenced. */
+func (s suppression) silences(d Diagnostic) bool {
+	if s.all {
+		return true
+	}
+	for _, code := range s.codes {
+		if code == d.Code || d.Rule != "" && code == d.Rule {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Record a diagnostic, if the lexer records
+diagnostics and the line does not silence it.
+*/
+func (lx *Lexer) diagnose(run *lexRun, d Diagnostic) {
+	if lx.Diagnostics != nil && !run.suppress.silences(d) {
+		lx.Diagnostics.add(d)
+	}
+}
+
+/*
+Leave out the diagnostics silenced by the
+suppression marker on their line of `src`, e.g.
+those of lint rules, found after tokenizing.
+*/
+func SuppressDiagnostics(diags []Diagnostic, src LineSource, marker string) []Diagnostic {
+	if marker == "" {
+		return diags
+	}
+	kept := []Diagnostic{}
+	for _, d := range diags {
+		line, ok := src.Text(d.Token.LineNo)
+		if !ok || !parseSuppression(line, marker).silences(d) {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,50 @@
+package lexer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestSuppressDiagnostics(t *testing.T) {
+	g := lexer.NewGrammar("suppress")
+	g.AddKind("PLUS", "+", "")
+	if err := g.SetLineComments("#:"); err != nil {
+		t.Fatal(err)
+	}
+	text := "1+2 #: lex-ignore LEX001\n3+4 #: lex-ignore LEX002\n5+6 #: lex-ignored\n7 #: lex-ignore\n"
+
+	diags := &lexer.Diagnostics{}
+	lx := &lexer.Lexer{Grammar: g, Diagnostics: diags, Suppress: "lex-ignore"}
+	if _, err := lx.TokenizeReader(strings.NewReader(text)); err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, d := range diags.List {
+		found = append(found, string(d.Token.Symbol))
+	}
+	if strings.Join(found, " ") != "3 4 5 6" {
+		t.Errorf("expected only lines 2 and 3 reported, got %v", found)
+	}
+
+	lx = &lexer.Lexer{Grammar: g, Strict: true, Suppress: "lex-ignore"}
+	if _, err := lx.TokenizeReader(strings.NewReader("1+2 #: lex-ignore LEX001\n")); err != nil {
+		t.Errorf("expected silenced input to pass strict mode, got %v", err)
+	}
+	if _, err := lx.TokenizeReader(strings.NewReader("1+2 #: lex-ignore LINT001\n")); err == nil {
+		t.Errorf("expected input silenced for other codes to fail strict mode")
+	}
+
+	index, err := g.TokenizeText("a+b  #: lex-ignore operator-spacing,LINT004\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lc := lexer.DefaultLintConfig()
+	lc.MaxLineLength = 10
+	src := lexer.NewSourceLines("a+b  #: lex-ignore operator-spacing,LINT004\n")
+	kept := lexer.SuppressDiagnostics(lc.Lint(index.Tokens()), src, "lex-ignore")
+	if len(kept) != 0 {
+		t.Errorf("expected every lint diagnostic silenced, got %v", kept)
+	}
+}
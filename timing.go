@@ -0,0 +1,150 @@
+package lexer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+/* --- KIND TIMINGS ---
+Where a profile splits the time spent tokenizing
+by stage, kind timings split the time spent
+matching by the kind matched, so grammar authors
+can find the signatures that make lexing slow.
+Each match is timed from looking for a kind to
+the full symbol of the token, and counted in a
+histogram of latencies. Timing is opt-in, as
+with profiles. */
+
+// Upper bounds of the histogram buckets, the last bucket having none.
+var timingBounds = []time.Duration{
+	100 * time.Nanosecond,
+	time.Microsecond,
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+}
+
+/* Time spent matching tokens of a kind. */
+type KindTiming struct {
+	Kind  string
+	Count int // Tokens matched.
+	Total time.Duration
+	Max   time.Duration
+
+	// Tokens matched per latency; below 100ns, 1µs, 10µs, 100µs,
+	// 1ms, then the rest.
+	Buckets [6]int
+}
+
+/* Mean time spent matching a token of the kind. */
+func (kt KindTiming) Mean() time.Duration {
+	if kt.Count == 0 {
+		return 0
+	}
+	return kt.Total / time.Duration(kt.Count)
+}
+
+/*
+Time spent matching per kind. The zero value is
+ready for use. Not safe for use by more than one
+goroutine at a time.
+*/
+type KindTimings struct {
+	kinds map[tokenName]*KindTiming
+}
+
+/* Count a match of the named kind taking `d`. */
+func (kts *KindTimings) record(name tokenName, d time.Duration) {
+	if kts.kinds == nil {
+		kts.kinds = map[tokenName]*KindTiming{}
+	}
+	kt, ok := kts.kinds[name]
+	if !ok {
+		kt = &KindTiming{Kind: string(name)}
+		kts.kinds[name] = kt
+	}
+	kt.Count += 1
+	kt.Total += d
+	if d > kt.Max {
+		kt.Max = d
+	}
+	bucket := len(timingBounds)
+	for i, bound := range timingBounds {
+		if d < bound {
+			bucket = i
+			break
+		}
+	}
+	kt.Buckets[bucket] += 1
+}
+
+/* Add the timings of another run to these. */
+func (kts *KindTimings) add(o *KindTimings) {
+	if kts.kinds == nil {
+		kts.kinds = map[tokenName]*KindTiming{}
+	}
+	for name, okt := range o.kinds {
+		kt, ok := kts.kinds[name]
+		if !ok {
+			kt = &KindTiming{Kind: okt.Kind}
+			kts.kinds[name] = kt
+		}
+		kt.Count += okt.Count
+		kt.Total += okt.Total
+		if okt.Max > kt.Max {
+			kt.Max = okt.Max
+		}
+		for i, n := range okt.Buckets {
+			kt.Buckets[i] += n
+		}
+	}
+}
+
+/* Timings of each kind matched, the slowest in total first. */
+func (kts *KindTimings) Report() []KindTiming {
+	report := make([]KindTiming, 0, len(kts.kinds))
+	for _, kt := range kts.kinds {
+		report = append(report, *kt)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Total != report[j].Total {
+			return report[i].Total > report[j].Total
+		}
+		return report[i].Kind < report[j].Kind
+	})
+	return report
+}
+
+/* Write the report as a table, with a column per histogram bucket. */
+func (kts *KindTimings) Format(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-16s %8s %12s %10s %10s", "kind", "count", "total", "mean", "max")
+	for _, bound := range timingBounds {
+		b.WriteString(" " + padLeft("<"+bound.String(), 7))
+	}
+	b.WriteString(" " + padLeft(">="+timingBounds[len(timingBounds)-1].String(), 7) + "\n")
+
+	for _, kt := range kts.Report() {
+		fmt.Fprintf(&b, "%-16s %8d %s %s %s", kt.Kind, kt.Count,
+			padLeft(kt.Total.String(), 12), padLeft(kt.Mean().String(), 10), padLeft(kt.Max.String(), 10))
+		for _, n := range kt.Buckets {
+			fmt.Fprintf(&b, " %7d", n)
+		}
+		b.WriteByte('\n')
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+/* Pad `s` with spaces on the left to `width` runes, as `µs` is two bytes. */
+func padLeft(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return strings.Repeat(" ", width-n) + s
+	}
+	return s
+}
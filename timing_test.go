@@ -0,0 +1,46 @@
+package lexer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestKindTimings(t *testing.T) {
+	g := lexer.NewGrammar("timing")
+	g.AddKind("PLUS", "+", "")
+	timings := &lexer.KindTimings{}
+	lx := &lexer.Lexer{Grammar: g, Timings: timings}
+	if _, err := lx.TokenizeReader(strings.NewReader("a + b\nc+d+e\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[string]int{}
+	for _, kt := range timings.Report() {
+		counts[kt.Kind] = kt.Count
+		sum := 0
+		for _, n := range kt.Buckets {
+			sum += n
+		}
+		if sum != kt.Count || kt.Max > kt.Total || kt.Mean() > kt.Max {
+			t.Errorf("inconsistent timing %+v", kt)
+		}
+	}
+	if counts["PLUS"] != 3 || counts["GENIDEN"] != 5 || counts["WHTSPACE"] != 2 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+
+	var buf bytes.Buffer
+	if err := timings.Format(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "kind") || !strings.HasSuffix(lines[0], ">=1ms") {
+		t.Errorf("unexpected report %q", buf.String())
+	}
+	if report := (&lexer.KindTimings{}).Report(); len(report) != 0 {
+		t.Errorf("expected no timings of an unused lexer, got %v", report)
+	}
+}
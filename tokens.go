@@ -2,9 +2,17 @@ package lexer
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func init() {
@@ -12,19 +20,42 @@ func init() {
 }
 
 // Lexer Token Field Types
-type tokenId uint16        // TokenKind Primary Key
-type tokenName string      // Human readable ID, still should be unique
-type tokenSignature []byte // Character sequence identity
+type tokenId uint16          // TokenKind Primary Key
+type tokenName string        // Human readable ID, still should be unique
+type tokenSignature []byte   // Character sequence identity
+type tokenDescription string // Documentation taken from the tokens file
 
 type tokenLineNo uint64   // Token Line Position
 type tokenPosition uint64 // Token Lateral Position
 
+/*
+IDs of the built in `TokenKind`s. IDs below
+`FirstUserKindId` are reserved for built in kinds,
+so adding built ins never shifts the IDs of kinds
+defined by a tokens file.
+*/
+const (
+	WhtspaceId tokenId = iota
+	GenIdenId
+	GenTypeId
+	GenObjId
+	NewlineId
+	CReturnId
+	TablineId
+	CommentId
+	ErrorId
+)
+
+// ID given to the first kind defined by a tokens
+// file; those after are numbered consecutively.
+const FirstUserKindId tokenId = 256
+
 /*
 Compare the given signature, see if is
 a substring of this signature.
 */
 func (ts tokenSignature) Contains(ots tokenSignature) bool {
-	return strings.Contains(string(ts), string(ots))
+	return bytes.Contains(ts, ots)
 }
 
 /*
@@ -32,7 +63,28 @@ Compare the given signature, see if is
 equivalent to this signature.
 */
 func (ts tokenSignature) Compare(ots tokenSignature) bool {
-	return string(ts) == string(ots)
+	return bytes.Equal(ts, ots)
+}
+
+func (ts tokenSignature) String() string { return string(ts) }
+
+/*
+Signatures are character sequences; render
+them as strings rather than base64 when
+dumped to JSON.
+*/
+func (ts tokenSignature) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(ts))
+}
+
+/* Read signatures back from their JSON strings. */
+func (ts *tokenSignature) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*ts = tokenSignature(s)
+	return nil
 }
 
 /*
@@ -40,9 +92,10 @@ Represents a type of token and it's basic
 identity.
 */
 type TokenKind struct {
-	Id        tokenId
-	Name      tokenName
-	Signature tokenSignature
+	Id          tokenId          `json:"id"`
+	Name        tokenName        `json:"name"`
+	Signature   tokenSignature   `json:"signature"`
+	Description tokenDescription `json:"description,omitempty"`
 }
 
 func (tk TokenKind) asString() string {
@@ -51,19 +104,52 @@ func (tk TokenKind) asString() string {
 func (tk TokenKind) String() string   { return tk.asString() }
 func (tk TokenKind) GoString() string { return tk.asString() }
 
+/*
+Compare the given kind, see if it is the same
+kind as this one. Kinds are the same if their
+ID, name and signature match; descriptions are
+not compared.
+*/
+func (tk TokenKind) Equal(otk TokenKind) bool {
+	return tk.Id == otk.Id && tk.Name == otk.Name && tk.Signature.Compare(otk.Signature)
+}
+
 /*
 Initialize a new `TokenObject` from this
 `TokenKind`.
 */
 func (tk TokenKind) New(line tokenLineNo, pos tokenPosition, symbol tokenSignature) *TokenObject {
-	return &TokenObject{&tk, line, pos, symbol}
+	return &TokenObject{Kind: &tk, LineNo: line, Position: pos, Symbol: symbol}
 }
 
 type TokenObject struct {
-	Kind     *TokenKind
-	LineNo   tokenLineNo
-	Position tokenPosition
-	Symbol   tokenSignature // Captures Token Object value if needed
+	Kind     *TokenKind     `json:"kind"` // Shared by tokens of the kind; must not be modified.
+	LineNo   tokenLineNo    `json:"line"`
+	Position tokenPosition  `json:"position"`
+	Symbol   tokenSignature `json:"symbol"`             // Captures Token Object value if needed
+	Value    any            `json:"value,omitempty"`    // Parsed symbol of literal kinds.
+	Comments TokenObjects   `json:"comments,omitempty"` // Comments, and trivia, attached per the lexer's comment mode and the grammar's trivia policies.
+	Count    int            `json:"count,omitempty"`    // Line breaks of a `NEWLINE` collapsed by the lexer; 0 otherwise.
+	Origin   *Origin        `json:"origin,omitempty"`   // What produced the token, if not found as is.
+}
+
+/* Determine if this token is of the given kind. */
+func (to TokenObject) Is(kind TokenKind) bool {
+	return to.Kind != nil && to.Kind.Equal(kind)
+}
+
+/* Determine if both tokens are of the same kind. */
+func (to TokenObject) SameKind(oto TokenObject) bool {
+	return oto.Kind != nil && to.Is(*oto.Kind)
+}
+
+/*
+Compare the given token, see if it has the same
+kind and symbol as this one, wherever either
+appears.
+*/
+func (to TokenObject) EqualIgnoringPosition(oto TokenObject) bool {
+	return to.SameKind(oto) && to.Symbol.Compare(oto.Symbol)
 }
 
 func (to TokenObject) asString() string {
@@ -72,29 +158,20 @@ func (to TokenObject) asString() string {
 func (to TokenObject) String() string   { return to.asString() }
 func (to TokenObject) GoString() string { return to.asString() }
 
-// Holds what will be the next ID given to a `TokenKind`.
-var tokenKindId tokenId = 0
+/* Initialize a `TokenKind` in the given grammar. */
+func (g *Grammar) newKind(name tokenName, sig tokenSignature, desc tokenDescription) TokenKind {
+	id := g.nextId
+	g.nextId += 1
 
-// Tracks the last recorded largest `TokenKind` Name.
-var tokenKindNameMaxSize int = 0
-
-// Tracks the last recorded largest `TokenKind` Signature.
-var tokenKindSignatureMaxSize int = 0
-
-/* Initialize a `TokenKind`. */
-func newKind(name tokenName, sig tokenSignature) TokenKind {
-	id := tokenKindId
-	tokenKindId += 1
-
-	if len(name) > tokenKindNameMaxSize {
-		tokenKindNameMaxSize = len(name)
+	if len(name) > g.nameMaxSize {
+		g.nameMaxSize = len(name)
 	}
 
-	if len(sig) > tokenKindSignatureMaxSize {
-		tokenKindSignatureMaxSize = len(sig)
+	if len(sig) > g.signatureMaxSize {
+		g.signatureMaxSize = len(sig)
 	}
 
-	return TokenKind{id, name, sig}
+	return TokenKind{id, name, sig, desc}
 }
 
 /* --- TOKEN MAPPING ---
@@ -103,274 +180,749 @@ types-- `TokenKinds`; how they are stored, how to
 find/identify them, etc.
 */
 
-type tokenKindMap map[tokenId]TokenKind
+type tokenKindMap struct {
+	kinds map[tokenId]TokenKind
+	refs  map[tokenId]*TokenKind // Copy of each kind shared by its tokens.
+	ids   []tokenId              // IDs in the order they were added.
+	bytes *byteIndex             // Kinds per first byte of their signatures; keywords aside.
 
-/* Retrieve a list of IDs in this map. */
-func (tkm tokenKindMap) Ids() []tokenId {
-	var ids []tokenId = []tokenId{}
+	keywords *keywordIndex // Kinds with word signatures.
+}
+
+/* A kind's ID and signature, as indexed. */
+type indexedKind struct {
+	id     tokenId
+	sig    string
+	spaced bool // Words of the signature are separated by whitespace.
+}
+
+/*
+Tracks, per byte, the kinds whose signatures
+start with it, so matching only compares input
+against signatures it could begin.
+*/
+type byteIndex struct {
+	starts [256][]indexedKind // In order of ID, and so of when kinds were added.
+	spaces [256]int           // Whitespace kinds holding each byte.
+}
+
+/* Initialize an empty `tokenKindMap`. */
+func newTokenKindMap() tokenKindMap {
+	return tokenKindMap{kinds: map[tokenId]TokenKind{}, refs: map[tokenId]*TokenKind{}, bytes: &byteIndex{}, keywords: &keywordIndex{}}
+}
 
-	for i := range tkm {
-		ids = append(ids, i)
+/* Index a kind by the first byte of its signature. */
+func (bi *byteIndex) add(kind TokenKind) {
+	bi.countSpaces(kind, 1)
+	if len(kind.Signature) == 0 {
+		return
 	}
-	return ids
+	list := bi.starts[kind.Signature[0]]
+	i := len(list)
+	for i > 0 && list[i-1].id > kind.Id {
+		i--
+	}
+	list = append(list, indexedKind{})
+	copy(list[i+1:], list[i:])
+	list[i] = indexedKind{kind.Id, string(kind.Signature), isSpaced(string(kind.Signature))}
+	bi.starts[kind.Signature[0]] = list
+}
+
+/* Drop a kind from the index. */
+func (bi *byteIndex) remove(kind TokenKind) {
+	bi.countSpaces(kind, -1)
+	if len(kind.Signature) == 0 {
+		return
+	}
+	list := bi.starts[kind.Signature[0]]
+	for i, ik := range list {
+		if ik.id == kind.Id {
+			bi.starts[kind.Signature[0]] = append(list[:i:i], list[i+1:]...)
+			return
+		}
+	}
+}
+
+/*
+Retrieve the kinds whose signatures start with
+the given byte. The list is shared and must not
+be modified.
+*/
+func (tkm tokenKindMap) Starting(b byte) []indexedKind {
+	return tkm.bytes.starts[b]
+}
+
+/*
+Retrieve the only kind a byte can match, if
+there is one; that whose signature is the byte
+alone, if no other signature starts with it.
+*/
+func (tkm tokenKindMap) ByByte(b byte) (tokenId, bool) {
+	list := tkm.bytes.starts[b]
+	if len(list) != 1 || len(list[0].sig) != 1 {
+		return 0, false
+	}
+	return list[0].id, true
+}
+
+/*
+Retrieve a list of IDs in this map, in the order
+they were added. The list is shared and must not
+be modified.
+*/
+func (tkm tokenKindMap) Ids() []tokenId {
+	return tkm.ids
 }
 
 /* Retrieve a `TokenKind` per the tokenId */
 func (tkm tokenKindMap) Get(id tokenId) TokenKind {
-	return tkm[id]
+	return tkm.kinds[id]
+}
+
+/*
+Retrieve the copy of a `TokenKind` shared by
+tokens of the kind, per the tokenId.
+*/
+func (tkm tokenKindMap) Ref(id tokenId) *TokenKind {
+	return tkm.refs[id]
+}
+
+/* Store a `TokenKind` per its tokenId. */
+func (tkm *tokenKindMap) put(kind TokenKind) {
+	if old, ok := tkm.kinds[kind.Id]; ok {
+		tkm.unindex(old)
+	} else {
+		tkm.ids = append(tkm.ids, kind.Id)
+	}
+	tkm.kinds[kind.Id] = kind
+	tkm.index(kind)
+
+	ref := kind
+	tkm.refs[kind.Id] = &ref
+}
+
+/* Index a kind for matching, as a keyword or by its first byte. */
+func (tkm *tokenKindMap) index(kind TokenKind) {
+	if isKeyword(string(kind.Signature)) {
+		tkm.keywords.add(kind)
+		return
+	}
+	tkm.bytes.add(kind)
+}
+
+/* Drop a kind from the indexes for matching. */
+func (tkm *tokenKindMap) unindex(kind TokenKind) {
+	if isKeyword(string(kind.Signature)) {
+		tkm.keywords.remove(kind)
+		return
+	}
+	tkm.bytes.remove(kind)
+}
+
+/* Retrieve a `TokenKind` per its tokenName. */
+func (tkm tokenKindMap) ByName(name tokenName) (TokenKind, bool) {
+	for _, id := range tkm.ids {
+		if kind := tkm.kinds[id]; kind.Name == name {
+			return kind, true
+		}
+	}
+	return TokenKind{}, false
 }
 
-/* Add a new `TokenKind`. */
-func (tkm tokenKindMap) Add(name tokenName, sig tokenSignature) {
-	kind := newKind(name, sig)
-	tkm[kind.Id] = kind
+/* Add a new `TokenKind` to the given grammar. */
+func (g *Grammar) add(name tokenName, sig tokenSignature, desc tokenDescription) TokenKind {
+	kind := g.newKind(name, sig, desc)
+	g.kinds.put(kind)
+	return kind
 }
 
 /*
 Search this map for a `TokenKind` matching
 the given signature. Returns a set of IDs of
-potential matches.
+potential matches, in order of ID.
 
 If a series of IDs are provided, `Find` will
 only run a comparison against that series,
-otherwise looking through the entire map.
+otherwise looking through the entire map. The
+given series is filtered in place and should not
+be used afterwards.
 */
 func (tkm tokenKindMap) Find(sig tokenSignature, ids ...tokenId) []tokenId {
-	if !(len(ids) > 0) {
-		ids = tkm.Ids()
-	}
+	var found []tokenId
 
-	var found []tokenId = []tokenId{}
+	if len(ids) > 0 {
+		found = ids[:0]
+	} else {
+		ids = tkm.ids
+	}
 
-	for i := range ids {
-		id := ids[i]
-		if tkm[id].Signature.Contains(sig) {
+	for _, id := range ids {
+		if tkm.kinds[id].Signature.Contains(sig) {
 			found = append(found, id)
 		}
 	}
-	return found
+	return sortIds(found)
 }
 
 /*
 Search this map for a `TokenKind` matching
 the given signature. Returns a set of IDs of
-potential matches. Note this function looks
-for exact matches.
+potential matches, in order of ID. Note this
+function looks for exact matches.
 
 If a series of IDs are provided, `Find` will
 only run a comparison against that series,
-otherwise looking through the entire map.
+otherwise looking through the entire map. The
+given series is filtered in place and should not
+be used afterwards.
 */
 func (tkm tokenKindMap) FindEx(sig tokenSignature, ids ...tokenId) []tokenId {
-	if !(len(ids) > 0) {
-		ids = tkm.Ids()
-	}
+	var found []tokenId
 
-	var found []tokenId = []tokenId{}
+	if len(ids) > 0 {
+		found = ids[:0]
+	} else {
+		ids = tkm.ids
+	}
 
-	for i := range ids {
-		id := ids[i]
-		if tkm[id].Signature.Compare(sig) {
+	for _, id := range ids {
+		if tkm.kinds[id].Signature.Compare(sig) {
 			found = append(found, id)
 		}
 	}
 
-	return found
+	return sortIds(found)
 }
 
-// Tokens added are stored here at runtime.
-var tokenKinds tokenKindMap = tokenKindMap{}
+/* Sort IDs in place, in ascending order. */
+func sortIds(ids []tokenId) []tokenId {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
 
-/* --- TOKENIZING --- */
+/*
+Find the kinds whose signatures contain `sig`,
+in order of ID; kinds added earlier come first.
+*/
+func (g *Grammar) Find(sig string) []TokenKind {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
 
-/* Array in which to hold `TokenObject` instances. */
-type tokenObjectsMap []TokenObject
+	return g.kindsOf(g.kinds.Find(tokenSignature(sig)))
+}
 
 /*
-Calculate token position start.
-
-Ensure no slicing is attempted outside
-the bounds of the given line.
+Find the kinds whose signatures are exactly
+`sig`, in order of ID. Of these, the first is
+the one matched when tokenizing.
 */
-func calcStep(line string) tokenPosition {
-	step := tokenKindSignatureMaxSize
-	step = step - (step - len(line))
-	return tokenPosition(step)
+func (g *Grammar) FindEx(sig string) []TokenKind {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.kindsOf(g.kinds.FindEx(tokenSignature(sig)))
 }
 
-/* Calculate the view used to inspect a token. */
-func calcView(line string, pos tokenPosition, step tokenPosition) string {
-	if int(pos+step) > len(line) {
-		return line[pos:]
+/* Retrieve the kinds of the given IDs. */
+func (g *Grammar) kindsOf(ids []tokenId) []TokenKind {
+	kinds := make([]TokenKind, len(ids))
+	for i, id := range ids {
+		kinds[i] = g.kinds.Get(id)
 	}
-	return line[pos : pos+step]
+	return kinds
 }
 
-/* Calculate the view used to inspect a token looking backwards. */
-func calcViewR(line string, pos tokenPosition, step tokenPosition) string {
-	if (pos - step) > 0 {
-		return line[0:0]
-	}
-	return line[pos-step : pos]
+/* Find the kinds of the default grammar whose signatures contain `sig`. */
+func Find(sig string) []TokenKind {
+	return defaultGrammar.Find(sig)
+}
+
+/* Find the kinds of the default grammar whose signatures are exactly `sig`. */
+func FindEx(sig string) []TokenKind {
+	return defaultGrammar.FindEx(sig)
 }
 
 /*
-Determine if the given sequence of
-characters is a token.
+Look up a loaded `TokenKind` by name, including
+its description from the tokens file.
 */
-func isToken(line string) bool {
-	step := calcStep(line)
-	view := calcViewR(line, step, 1)
-	sig := tokenSignature(line)
+func (g *Grammar) LookupKind(name string) (TokenKind, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
 
-	matches := tokenKinds.Find(sig)
+	return g.kinds.ByName(tokenName(name))
+}
 
-	for len(matches) == 0 || view == " " {
-		matches = tokenKinds.Find(sig, matches...)
+/* Look up a `TokenKind` of the default grammar by name. */
+func LookupKind(name string) (TokenKind, bool) {
+	return defaultGrammar.LookupKind(name)
+}
 
-		if (step - 1) == 0 {
-			break
-		}
-		step -= 1
-		sig = tokenSignature(line[:step])
-		view = line[step-1 : step]
-	}
+/* --- TOKENIZING --- */
 
-	matches = tokenKinds.FindEx(sig, matches...)
+/*
+Array in which to hold `TokenObject` instances,
+as returned by the tokenizer. Results may be
+kept in variables and fields of this type, or of
+`[]TokenObject`.
+*/
+type TokenObjects []TokenObject
 
-	return (len(matches) > 0)
+/*
+Determine if the given sequence of characters
+starts with a token. The sequence never starts a
+line, so kinds anchored to the start are not
+considered.
+*/
+func (g *Grammar) isToken(line string) bool {
+	_, _, ok := g.matchKind(line, false)
+	return ok
 }
 
 /*
-Given a string, a distance to slice up to from zero,
-and an array of IDs, retrieve the most likely tokenId
-and it's signature of the given value.
-
-Note that if no tokenId can be found, this function
-returns an ID of `1` by default. This is to ensure
-any non-defined values can be tokenized generically.
+Find the kind whose signature matches the
+longest run of input `line` starts with; of
+equally long ones, that added first. Returns the
+ID of the kind and the length of input matched.
+`atStart` is set if only whitespace comes before
+the line given, for kinds anchored to the start.
 */
-func findToken(line string, step tokenPosition, ids ...tokenId) (tokenId, tokenSignature) {
-	view := calcView(line, 0, step)
-	sig := tokenSignature(view)
-
-	// If no IDs are passed to this function,
-	// attempt to perform a lookup of potential
-	// matches.
-	if len(ids) == 0 {
-		ids = tokenKinds.Find(sig, ids...)
+func (g *Grammar) matchKind(line string, atStart bool) (tokenId, int, bool) {
+	if line == "" {
+		return 0, 0, false
 	}
 
-	switch len(ids) {
-	case 0:
-		// In the event no potential token kinds
-		// are found, return a generic token ID
-		// and a signature of the current view.
-		return 1, tokenSignature(line)
-	case 1:
-		ids = tokenKinds.FindEx(sig, ids...)
-		if len(ids) == 0 {
-			return findToken(line, step+1, ids...)
+	var best tokenId
+	size := 0
+	for _, ik := range g.kinds.Starting(line[0]) {
+		n, ok := len(ik.sig), strings.HasPrefix(line, ik.sig)
+		if ik.spaced {
+			n, ok = g.matchWords(line, ik.sig)
 		}
-		return ids[0], sig
-	}
-
-	// Ensure there is no token immediatly ahead
-	// of the current view.
-	// If there is, find the exact matching ids
-	// to current view and try again.
-	if !isToken(calcView(line, step, 1)) {
-		ids = tokenKinds.FindEx(sig, ids...)
-		if len(ids) == 0 {
-			ids = append(ids, 1)
+		if !ok || n <= size || !g.anchorHolds(ik.id, line, n, atStart) {
+			continue
 		}
-		return findToken(line, step, ids...)
+		best, size = ik.id, n
 	}
+	// Keywords and other signatures never match
+	// the same input, so there are no ties.
+	if id, n, ok := g.matchKeyword(line, size, atStart); ok {
+		best, size = id, n
+	}
+	return best, size, size > 0
+}
 
-	// If no token is found, expand the view
-	// using the same line and current set
-	// of token IDs.
-	return findToken(line, step+1, ids...)
+/*
+Retrieve the only kind a byte can match, unless
+the kind is anchored. Such bytes are tokens by
+themselves, needing no further matching.
+*/
+func (g *Grammar) singleByte(b byte) (tokenId, bool) {
+	id, ok := g.kinds.ByByte(b)
+	if !ok {
+		return 0, false
+	}
+	if _, anchored := g.anchors[id]; anchored {
+		return 0, false
+	}
+	return id, true
 }
 
-/* Identify the entirety of a generic token. */
-func findIdenToken(line string) tokenSignature {
-	// If the given string is only a single
-	// char, chances are it has no token
-	// or will not have any tokens adjacent
-	// to itself.
-	if len(line) == 1 {
-		return tokenSignature(line)
+/*
+Given a string, retrieve the tokenId and
+signature of the token it starts with. Input is
+matched by maximal munch: the longest signature
+the line starts with wins, so `<<=` is never
+split into `<<` and `=`.
+
+Note that if no tokenId can be found, this function
+returns `GenIdenId` by default. This is to ensure
+any non-defined values can be tokenized generically;
+the lexer picks the kind they are emitted as.
+*/
+func (g *Grammar) findToken(line string, atStart bool) (tokenId, string) {
+	if id, n, ok := g.matchKind(line, atStart); ok {
+		return id, line[:n]
 	}
+	// The full identity is left to
+	// `findIdenToken`.
+	return GenIdenId, line[:1]
+}
 
-	step := 1
-	view, lookAhead := line[:step], line[step:]
+/*
+Identify the entirety of a generic token; up to
+the next token on the line. If the grammar
+declares identifier classes, the token also ends
+before the first character outside of them. At
+least one character is always included.
+*/
+func (g *Grammar) findIdenToken(line string) string {
+	limit := g.identEnd(line)
+	c := NewCursor(line).AdvanceRune()
 
-	// Ensure there are no tokens ahead of
-	// the current view on the line.
-	// Break the loop either when the step
-	// goes out of bounds, or if there is
-	// a token ahead of the view.
-	for !isToken(lookAhead) {
-		view, lookAhead = line[:step], line[step:]
-		step += 1
-		if step > len(line) {
-			break
-		}
+	for c.Offset() < limit && !g.isToken(c.Rest()) {
+		c = c.AdvanceRune()
 	}
-	return tokenSignature(view)
+	return c.Passed()
 }
 
-/* Break down a single line into a series of tokens. */
-func TokenizeLine(line string, lineNo tokenLineNo) tokenObjectsMap {
-	var pos tokenPosition = 0
-	var tokens tokenObjectsMap = tokenObjectsMap{}
+/*
+Break down a single line into a series of tokens.
+In strict mode, tokens stop before any unknown
+input; `TokenizeLineTo` reports it as an error.
+*/
+func (lx *Lexer) TokenizeLine(line string, lineNo tokenLineNo) TokenObjects {
+	tokens, _ := lx.tokenizeLineErr(line, lineNo)
+	return tokens
+}
+
+/*
+Break down a single line into a series of
+tokens, failing on unknown input in strict mode.
+*/
+func (lx *Lexer) tokenizeLineErr(line string, lineNo tokenLineNo) (TokenObjects, error) {
+	run := lx.newRun()
+	return lx.tokenizeLineLocked(TokenObjects{}, line, nil, lineNo, &run)
+}
+
+/*
+Break down a single line, from `pos` on, into a
+series of tokens, appending them to `tokens`.
+The caller must hold the grammar's read lock. If
+`src` is given it holds the bytes of `line`, and
+symbols are slices of it rather than copies.
+Unknown input falls back on the lexer's fallback
+kind; in strict mode, the tokens before it are
+returned with an `UnknownInputError` instead.
+
+Returns the position tokenizing stopped at; the
+end of the line, unless an action switched
+grammars.
+*/
+func (lx *Lexer) tokenizeLine(g *Grammar, tokens TokenObjects, line string, src []byte, lineNo tokenLineNo, pos tokenPosition, run *lexRun) (TokenObjects, tokenPosition, error) {
+	var atStart bool = g.isBlank(line[:pos]) // Only whitespace so far.
+
+	fallback, err := lx.fallback(g)
+	if err != nil {
+		return tokens, pos, err
+	}
+	if lx.Suppress != "" {
+		run.suppress = parseSuppression(line, lx.Suppress)
+	}
 
 	for pos < tokenPosition(len(line)) {
 		var id tokenId
-		var sig tokenSignature
+		var sig string
+		var unknown bool
+		var gaveUp *StepLimitError // Set if matching gave up.
+		var began time.Time
+		if lx.Timings != nil {
+			began = time.Now()
+		}
 
-		id, sig = findToken(line[pos:], 1)
-		if id == 1 {
+		if g.isComment(line[pos:]) {
+			// Comments run to the end of the line.
+			id, sig = CommentId, line[pos:]
+		} else if single, ok := g.singleByte(line[pos]); ok {
+			id, sig = single, line[pos:pos+1]
+		} else {
+			id, sig = g.findToken(line[pos:], atStart)
+		}
+		run.lap(stageMatching)
+		if id == GenIdenId {
 			// Current token is GENIDEN;
 			// get full identity.
-			sig = findIdenToken(string(sig))
+			ok := true
+			if lx.MaxSteps > 0 {
+				guard := stepGuard{max: lx.MaxSteps}
+				if sig, ok = g.scanIdenToken(line[pos:], &guard); !ok {
+					limit := g.stepLimit(line[pos:], lineNo, pos+1, guard.steps)
+					gaveUp = &limit
+				}
+			} else {
+				sig = g.findIdenToken(line[pos:])
+			}
+			run.lap(stageIdentifiers)
+			if !ok {
+				if lx.Strict && !run.suppress.silences(Diagnostic{Code: CodeStepLimit}) {
+					return tokens, pos, *gaveUp
+				}
+				id = fallback
+			} else if !g.isIdentifier(sig) {
+				if lx.Strict && !run.suppress.silences(Diagnostic{Code: CodeUnknownInput}) {
+					return tokens, pos, UnknownInputError{LineNo: lineNo, Position: pos + 1, Symbol: sig}
+				}
+				id = fallback
+				unknown = true
+			}
+		}
+		if lx.Timings != nil {
+			lx.Timings.record(g.kinds.Ref(id).Name, time.Since(began))
+		}
+		// Tokens are built in place and share
+		// their kind, rather than each being
+		// allocated on its own.
+		symbol := lx.symbol(id, sig, src, pos)
+		to := TokenObject{
+			Kind:     g.kinds.Ref(id),
+			LineNo:   lineNo,
+			Position: pos + 1,
+			Symbol:   symbol,
+			Value:    g.literalValue(id, symbol),
+		}
+		if unknown && lx.Diagnostics != nil {
+			lx.diagnose(run, Diagnostic{Token: to, Message: fmt.Sprintf("unknown input '%s'%s", sig, g.didYouMean(sig)), Code: CodeUnknownInput})
+		}
+		if gaveUp != nil {
+			lx.diagnose(run, Diagnostic{Token: to, Message: gaveUp.message(), Code: CodeStepLimit})
 		}
-		tokens = append(tokens, *tokenKinds.Get(id).New(lineNo, pos+1, sig))
 		pos += tokenPosition(len(sig))
+		atStart = atStart && isWhitespaceKind(to.Kind)
+
+		keep, switched := true, false
+		if lx.actions != nil {
+			to, keep, switched = lx.act(g, to, run)
+		}
+		switch {
+		case !keep:
+		case lx.SkipWhitespace != WhitespaceNone && to.IsWhitespaceOf(lx.SkipWhitespace):
+		case to.Kind.Id == CommentId:
+			tokens = lx.retainComment(g, tokens, to)
+		default:
+			tokens = lx.retainTrivia(g, tokens, to)
+		}
+		run.lap(stageOutput)
+		if switched {
+			return tokens, pos, nil
+		}
 	}
 
-	return tokens
+	return tokens, pos, nil
 }
 
-/* Break down multiple lines into a series of tokens. */
-func TokenizeLines(lines []string) tokenObjectsMap {
-	var tokens tokenObjectsMap = tokenObjectsMap{}
+/* Break down a single line into a series of tokens. */
+func (g *Grammar) TokenizeLine(line string, lineNo tokenLineNo) TokenObjects {
+	return g.lexer().TokenizeLine(line, lineNo)
+}
+
+/*
+Break down a single line into a series of
+tokens using the default grammar.
+*/
+func TokenizeLine(line string, lineNo tokenLineNo) TokenObjects {
+	return defaultGrammar.TokenizeLine(line, lineNo)
+}
+
+/*
+Break down a single line into a series of
+tokens, leaving out tokens of the given kinds;
+e.g. a parser may skip `WhtspaceId` where a
+formatter keeps it.
+*/
+func (lx *Lexer) TokenizeLineSkipping(line string, lineNo tokenLineNo, kinds ...tokenId) TokenObjects {
+	tokens := lx.TokenizeLine(line, lineNo)
+	if len(kinds) == 0 {
+		return tokens
+	}
+
+	kept := tokens[:0]
+	for _, to := range tokens {
+		if !containsId(kinds, to.Kind.Id) {
+			kept = append(kept, to)
+		}
+	}
+	return kept
+}
+
+/*
+Break down a single line into a series of
+tokens, leaving out tokens of the given kinds.
+*/
+func (g *Grammar) TokenizeLineSkipping(line string, lineNo tokenLineNo, kinds ...tokenId) TokenObjects {
+	return g.lexer().TokenizeLineSkipping(line, lineNo, kinds...)
+}
+
+/*
+Break down a single line into a series of
+tokens using the default grammar, leaving out
+tokens of the given kinds.
+*/
+func TokenizeLineSkipping(line string, lineNo tokenLineNo, kinds ...tokenId) TokenObjects {
+	return defaultGrammar.TokenizeLineSkipping(line, lineNo, kinds...)
+}
+
+/* Determine if `id` is among `ids`. */
+func containsId(ids []tokenId, id tokenId) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Break down multiple lines into a series of tokens.
+Lines are numbered from 0. `TokenizeText` splits
+text into lines itself, keeping terminators.
+*/
+func (lx *Lexer) TokenizeLines(lines []string) TokenObjects {
+	var tokens TokenObjects = TokenObjects{}
 
 	for lineId := range lines {
 		line := lines[lineId]
 		lineNo := tokenLineNo(lineId)
-		tokens = append(tokens, TokenizeLine(line, lineNo)...)
+		tokens = append(tokens, lx.TokenizeLine(line, lineNo)...)
 	}
 
 	return tokens
 }
 
+/* Break down multiple lines into a series of tokens. */
+func (g *Grammar) TokenizeLines(lines []string) TokenObjects {
+	return g.lexer().TokenizeLines(lines)
+}
+
+/*
+Break down multiple lines into a series of
+tokens using the default grammar.
+*/
+func TokenizeLines(lines []string) TokenObjects {
+	return defaultGrammar.TokenizeLines(lines)
+}
+
+/*
+Break down multiple lines, from a file,
+into a series of tokens. Returns
+`ErrBinaryInput` if the file appears to be
+binary. If the lexer has a cache, tokens of
+unchanged files are taken from it.
+*/
+func (lx *Lexer) TokenizeFile(name string) (TokenObjects, error) {
+	if lx.Cache != nil {
+		return lx.tokenizeCached(name)
+	}
+	file, err := openTokenFile(name, lx.decoder())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tokens, err := lx.tokenizeTokenFile(file)
+	return tokens, withFilename(err, name)
+}
+
 /*
 Break down multiple lines, from a file,
 into a series of tokens.
 */
-func TokenizeFile(name string) tokenObjectsMap {
-	file := newTokenFile(name)
+func (g *Grammar) TokenizeFile(name string) (TokenObjects, error) {
+	return g.lexer().TokenizeFile(name)
+}
+
+/*
+Break down multiple lines, from a file,
+into a series of tokens using the default
+grammar.
+*/
+func TokenizeFile(name string) (TokenObjects, error) {
+	return defaultGrammar.TokenizeFile(name)
+}
+
+/*
+Break down multiple lines, from a file of the
+given file system, into a series of tokens.
+*/
+func (lx *Lexer) TokenizeFileFS(fsys fs.FS, name string) (TokenObjects, error) {
+	file, err := openTokenFileFS(fsys, name, lx.decoder())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return lx.tokenizeTokenFile(file)
+}
 
-	tokens := tokenObjectsMap{}
+/*
+Break down multiple lines, from a file of the
+given file system, into a series of tokens.
+*/
+func (g *Grammar) TokenizeFileFS(fsys fs.FS, name string) (TokenObjects, error) {
+	return g.lexer().TokenizeFileFS(fsys, name)
+}
+
+/*
+Break down multiple lines, from a file of the
+given file system, into a series of tokens
+using the default grammar.
+*/
+func TokenizeFileFS(fsys fs.FS, name string) (TokenObjects, error) {
+	return defaultGrammar.TokenizeFileFS(fsys, name)
+}
+
+/*
+Break down each line read from `r` into tokens.
+Returns `ErrBinaryInput` if `r` appears to be
+binary.
+*/
+func (lx *Lexer) TokenizeReader(r io.Reader) (TokenObjects, error) {
+	r, err := newTextReader(r, lx.decoder())
+	if err != nil {
+		return nil, err
+	}
+	return lx.tokenizeTokenFile(tokenFile{scanner: newLineScanner(r)})
+}
+
+/*
+Break down each line of an open file into tokens.
+In strict mode, stops at the first unknown input.
+*/
+func (lx *Lexer) tokenizeTokenFile(file tokenFile) (TokenObjects, error) {
+	tokens := TokenObjects{}
 	lineNo := tokenLineNo(0)
+	run := lx.newRun()
+	if lx.Newlines != NewlineOmit {
+		// Line breaks are only known with their terminators.
+		file.scanner.Split(scanLinesKeep)
+	}
+
 	for file.Scan() {
 		lineNo += 1
-		tokens = append(tokens, TokenizeLine(file.Text(), lineNo)...)
+		line := splitTerminator(file.Text(), 0)
+		start := len(tokens)
+		run.lap(stageScanning)
+
+		var err error
+		tokens, err = lx.tokenizeLineLocked(tokens, line.Text, nil, lineNo, &run)
+		if err != nil {
+			return tokens, err
+		}
+		tokens = lx.endLine(tokens, start, len(line.Text), line.Terminator, lineNo, &run)
 	}
 
-	return tokens
+	return tokens, file.Err()
+}
+
+/*
+Break down a single line into a series of tokens,
+appending them to `tokens` and holding the read
+lock of each grammar tokenizing it while doing
+so.
+*/
+func (lx *Lexer) tokenizeLineLocked(tokens TokenObjects, line string, src []byte, lineNo tokenLineNo, run *lexRun) (TokenObjects, error) {
+	start, pos := len(tokens), tokenPosition(0)
+	for {
+		g := run.grammar(lx)
+		g.mu.RLock()
+		var err error
+		tokens, pos, err = lx.tokenizeLine(g, tokens, line, src, lineNo, pos, run)
+		g.mu.RUnlock()
+
+		if err != nil || pos >= tokenPosition(len(line)) {
+			run.count(len(tokens) - start)
+			return tokens, err
+		}
+	}
 }
 
 /* --- TOKEN REPRESENTATION ---
@@ -380,22 +932,77 @@ simple creatures it's best to help ourselves out and
 provide some way to comprehend the inner workings of our
 lexer. */
 
-/* Render token representation. */
-func RenderTokenRepr() string {
-	var render string = ""
-	var id tokenId = 0
+/*
+Write the representation of the kinds at
+`from` up to, but not including, `to` in the
+order they were added. The range is clamped to
+the kinds there are.
+*/
+func (g *Grammar) WriteKinds(w io.Writer, from, to int) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
 
-	for id < tokenKindId {
-		t := tokenKinds[id]
-		id += 1
-		render += fmt.Sprintf("[%d]\t%s\t'%s'\n", t.Id, t, t.Signature)
+	ids := g.kinds.Ids()
+	if to > len(ids) {
+		to = len(ids)
+	}
+	if from < 0 {
+		from = 0
 	}
-	return render
+	if from > to {
+		from = to
+	}
+	for _, id := range ids[from:to:to] {
+		t := g.kinds.Get(id)
+		if _, err := fmt.Fprintf(w, "[%d]\t%s\t'%s'\t%s\n", t.Id, t, t.Signature, t.Description); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* Number of kinds of the grammar, built in kinds included. */
+func (g *Grammar) KindCount() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.kinds.Ids())
+}
+
+/*
+Render the representation of the kinds at
+`from` up to, but not including, `to`; a page
+of a large grammar, say.
+*/
+func (g *Grammar) RenderKinds(from, to int) string {
+	var b strings.Builder
+	g.WriteKinds(&b, from, to)
+	return b.String()
+}
+
+/* Render token representation. */
+func (g *Grammar) RenderTokenRepr() string {
+	return g.RenderKinds(0, math.MaxInt)
+}
+
+/* Write token representation. */
+func (g *Grammar) WriteTokenRepr(w io.Writer) error {
+	return g.WriteKinds(w, 0, math.MaxInt)
+}
+
+/* Render the kinds of the default grammar at `from` up to `to`. */
+func RenderKinds(from, to int) string {
+	return defaultGrammar.RenderKinds(from, to)
+}
+
+/* Render token representation of the default grammar. */
+func RenderTokenRepr() string {
+	return defaultGrammar.RenderTokenRepr()
 }
 
 /* Render token representation to stdout. */
 func DisplayTokensRepr() {
-	fmt.Println(RenderTokenRepr())
+	defaultGrammar.WriteTokenRepr(os.Stdout)
+	fmt.Println()
 }
 
 /* --- TOKEN LOADING ---
@@ -421,13 +1028,71 @@ token.
 
 COMMENTS: Any additional information not necessary
 for token instantiation, but useful for us to explain
-purpose/ideas/etc.
+purpose/ideas/etc. A comment trailing a token
+definition is kept as that token's `Description`.
+
+NOTE: Comments are annotated using '#:'.
+
+A TOKEN_SEQUENCE may be double quoted, as a Go
+string, to hold spaces or escapes:
+NOT_IN "not in"
+A sequence merely starting with a quote, e.g.
+`"` alone, is taken as is.
+
+Lines whose TOKEN_NAME starts with '@' are
+directives, configuring the grammar rather than
+defining a token:
+
+@ident [CLASS...]: Characters generic identifiers
+are made of; `letter`, `digit`, or any literal
+characters. Identifiers end before the first
+character outside of these.
+
+@literal KIND TYPE: Parse the symbols of a kind
+defined above into values of `int`, `float`,
+`string` or `bool`.
+
+@anchor KIND ANCHOR: Only match a kind defined
+above at the `start` or `end` of a line.
 
-NOTE: Comments are annotated using '#:'. */
+@version VERSION: The version of the grammar's
+definition, recorded in the provenance of
+results.
+
+@alias OLD KIND: Give tokens of a kind named OLD,
+as by an older version of the grammar, the kind
+defined above when remapped or read back.
+
+@comment MARKER...: Lex the rest of a line from
+any of the markers as a `COMMENT` token.
+
+@trivia KIND POLICY: Declare a kind defined
+above, or a built in whitespace kind or
+`COMMENT`, trivia whose tokens are kept with
+`preserve`, attached to the token before with
+`attach`, or left out with `drop`.
+
+@when FLAG...: Definitions up to the next `@end`
+are only loaded if every flag is enabled in the
+grammar's `Flags`; `!FLAG` if it is not enabled.
+Guards do not nest, and do not apply to
+directives. */
+
+/*
+Initialize a new line scanner over `r`. Lines
+are not limited in length; the buffer grows to
+hold the longest line read, where the default
+buffer would stop scanning at 64KB.
+*/
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), math.MaxInt)
+	return scanner
+}
 
 /* Represents token file when open. */
 type tokenFile struct {
-	file    *os.File
+	file    fs.File
 	scanner *bufio.Scanner
 }
 
@@ -443,6 +1108,10 @@ func (tf tokenFile) Text() string {
 	return tf.scanner.Text()
 }
 
+func (tf tokenFile) Err() error {
+	return tf.scanner.Err()
+}
+
 /* Ensure no error raised, panic otherwise. */
 func check(err error) {
 	if err != nil {
@@ -450,16 +1119,42 @@ func check(err error) {
 	}
 }
 
-/* Initialize a new `tokenFile` */
-func newTokenFile(name string) tokenFile {
+/*
+Initialize a new `tokenFile`, returning any
+error. Input is transcoded with `decode`, if
+given.
+*/
+func openTokenFile(name string, decode Decoder) (tokenFile, error) {
 	file, err := os.Open(name)
-	check(err)
-	return tokenFile{file, bufio.NewScanner(file)}
+	if err != nil {
+		return tokenFile{}, err
+	}
+	return newTokenFileOf(file, decode)
 }
 
-/* Opens a scanner to the tokens file. */
-func openTokensFile() tokenFile {
-	return newTokenFile("../lexer.tokens")
+/*
+Initialize a new `tokenFile` from the given
+file system, returning any error.
+*/
+func openTokenFileFS(fsys fs.FS, name string, decode Decoder) (tokenFile, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return tokenFile{}, err
+	}
+	return newTokenFileOf(file, decode)
+}
+
+/*
+Initialize a new `tokenFile` of an open file,
+closing it if it appears to be binary.
+*/
+func newTokenFileOf(file fs.File, decode Decoder) (tokenFile, error) {
+	r, err := newTextReader(file, decode)
+	if err != nil {
+		file.Close()
+		return tokenFile{}, err
+	}
+	return tokenFile{file, newLineScanner(r)}, nil
 }
 
 /*
@@ -503,51 +1198,376 @@ func parseComment(line string) string {
 	return temp
 }
 
-/* Identify the tokenName and tokenSequence on a single line. */
-func parseLine(line string) (string, string) {
+/*
+Returns the text of the first comment on the
+given line, without the comment marker.
+Returns an empty string if none found.
+*/
+func parseDescription(line string) string {
+	ind := findCommentPos(line)
+	if ind < 0 {
+		return ""
+	}
+	return strings.TrimSpace(line[ind+2:])
+}
+
+/*
+Identify the tokenName, tokenSequence and
+description on a single line.
+*/
+func parseLine(line string) (string, string, string, error) {
+	if name, rest, ok := strings.Cut(line, " "); ok && isQuoted(rest) {
+		return parseQuotedLine(name, rest)
+	}
+
+	desc := parseDescription(line)
 	temp := strings.SplitN(line, " ", 2)
 
 	for i, p := range temp {
 		temp[i] = parseComment(p)
 	}
 	if len(temp) == 1 {
-		return "", ""
+		return "", "", "", nil
 	}
 	if len(temp) > 2 {
 		msg := fmt.Sprintf("expected no more than two objects, got %s", temp)
 		panic(msg)
 	}
-	return temp[0], temp[1]
+	return temp[0], temp[1], desc, nil
 }
 
-/* From the tokens file, load in defined tokens. */
-func loadTokens() {
-	file := openTokensFile()
+/*
+Determine if a tokenSequence is quoted; a
+complete double quoted string, followed by
+nothing but a comment. Anything else starting
+with a quote is taken as is, so `"` alone is
+still a signature.
+*/
+func isQuoted(seq string) bool {
+	quoted, err := strconv.QuotedPrefix(seq)
+	if err != nil || quoted[0] != '"' {
+		return false
+	}
+	rest := strings.TrimLeft(seq[len(quoted):], " ")
+	return rest == "" || findCommentPos(rest) == 0
+}
+
+/*
+Identify the tokenSequence and description
+following a tokenName, the sequence being
+quoted.
+*/
+func parseQuotedLine(name string, rest string) (string, string, string, error) {
+	quoted, _ := strconv.QuotedPrefix(rest)
+	seq, err := strconv.Unquote(quoted)
+	if err != nil {
+		return "", "", "", err
+	}
+	if seq == "" {
+		return "", "", "", fmt.Errorf("empty signature of %q", name)
+	}
+	return name, seq, parseDescription(rest[len(quoted):]), nil
+}
+
+/* A single token definition from a tokens file. */
+type tokenDef struct {
+	name tokenName
+	sig  tokenSignature
+	desc tokenDescription
+	when []string // Flags guarding the definition.
+}
+
+/* Everything defined by a tokens file. */
+type tokenSpec struct {
+	defs     []tokenDef
+	ident    *identClass                // Set by `@ident`.
+	literals map[tokenName]LiteralType  // Set by `@literal`.
+	anchors  map[tokenName]Anchor       // Set by `@anchor`.
+	comments []string                   // Set by `@comment`.
+	aliases  map[tokenName]tokenName    // Set by `@alias`.
+	trivia   map[tokenName]TriviaPolicy // Set by `@trivia`.
+	version  string                     // Set by `@version`.
+	when     []string                   // Flags of the open `@when`, while reading.
+}
+
+/* Apply a directive line to the spec. */
+func (ts *tokenSpec) applyDirective(name string, args string) error {
+	switch name {
+	case "@ident":
+		ident, err := parseIdentClass(args)
+		if err != nil {
+			return err
+		}
+		ts.ident = &ident
+	case "@when":
+		if ts.when != nil {
+			return fmt.Errorf("@when within @when")
+		}
+		flags := strings.Fields(args)
+		if len(flags) == 0 {
+			return fmt.Errorf("@when without flags")
+		}
+		ts.when = flags
+	case "@end":
+		if ts.when == nil {
+			return fmt.Errorf("@end without @when")
+		}
+		ts.when = nil
+	case "@literal":
+		kind, typName, _ := strings.Cut(strings.TrimSpace(args), " ")
+		typ, err := ParseLiteralType(strings.TrimSpace(typName))
+		if err != nil {
+			return err
+		}
+		if !ts.defines(tokenName(kind)) {
+			return fmt.Errorf("@literal of undefined kind %q", kind)
+		}
+		if ts.literals == nil {
+			ts.literals = map[tokenName]LiteralType{}
+		}
+		ts.literals[tokenName(kind)] = typ
+	case "@anchor":
+		kind, anchorName, _ := strings.Cut(strings.TrimSpace(args), " ")
+		anchor, err := ParseAnchor(strings.TrimSpace(anchorName))
+		if err != nil {
+			return err
+		}
+		if !ts.defines(tokenName(kind)) {
+			return fmt.Errorf("@anchor of undefined kind %q", kind)
+		}
+		if ts.anchors == nil {
+			ts.anchors = map[tokenName]Anchor{}
+		}
+		ts.anchors[tokenName(kind)] = anchor
+	case "@version":
+		version := strings.TrimSpace(args)
+		if version == "" || strings.ContainsAny(version, " \t") {
+			return fmt.Errorf("@version expects a single version")
+		}
+		ts.version = version
+	case "@alias":
+		fields := strings.Fields(args)
+		if len(fields) != 2 {
+			return fmt.Errorf("@alias expects an old name and a kind")
+		}
+		if !ts.defines(tokenName(fields[1])) {
+			return fmt.Errorf("@alias of undefined kind %q", fields[1])
+		}
+		if ts.aliases == nil {
+			ts.aliases = map[tokenName]tokenName{}
+		}
+		ts.aliases[tokenName(fields[0])] = tokenName(fields[1])
+	case "@trivia":
+		kind, policyName, _ := strings.Cut(strings.TrimSpace(args), " ")
+		policy, err := ParseTriviaPolicy(strings.TrimSpace(policyName))
+		if err != nil {
+			return err
+		}
+		if !ts.defines(tokenName(kind)) && !isBuiltinTrivia(tokenName(kind)) {
+			return fmt.Errorf("@trivia of undefined kind %q", kind)
+		}
+		if ts.trivia == nil {
+			ts.trivia = map[tokenName]TriviaPolicy{}
+		}
+		ts.trivia[tokenName(kind)] = policy
+	case "@comment":
+		markers := strings.Fields(args)
+		if len(markers) == 0 {
+			return fmt.Errorf("@comment without markers")
+		}
+		ts.comments = markers
+	default:
+		return fmt.Errorf("unknown directive %q", name)
+	}
+	return nil
+}
+
+/*
+Determine if the spec defines the named kind so
+far. `GENIDEN` is always defined.
+*/
+func (ts *tokenSpec) defines(name tokenName) bool {
+	if name == "GENIDEN" {
+		return true
+	}
+	for _, def := range ts.defs {
+		if def.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+/* Read every definition from the given file. */
+func readTokenSpec(file tokenFile) (tokenSpec, error) {
+	spec := tokenSpec{}
+	lineNo := 0
+
+	for file.Scan() {
+		lineNo += 1
+		text := file.Text()
+		if strings.HasPrefix(text, "@") {
+			name, args, _ := strings.Cut(parseComment(text), " ")
+			if err := spec.applyDirective(name, args); err != nil {
+				return spec, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			continue
+		}
+
+		name, seq, desc, err := parseLine(text)
+		if err != nil {
+			return spec, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if name == "" {
+			continue
+		}
+		spec.defs = append(spec.defs, tokenDef{tokenName(name), tokenSignature(seq), tokenDescription(desc), spec.when})
+	}
+	if spec.when != nil {
+		return spec, fmt.Errorf("line %d: @when without @end", lineNo)
+	}
+	return spec, file.Err()
+}
+
+/* Remove all `TokenKind`s and reset their tracking. */
+func (g *Grammar) reset() {
+	g.kinds = newTokenKindMap()
+	g.nextId = 0
+	g.nameMaxSize = 0
+	g.signatureMaxSize = 0
+	g.ident = nil
+	g.literals = nil
+	g.anchors = nil
+	g.comments = nil
+	g.aliases = nil
+	g.trivia = nil
+}
+
+/*
+Replace the loaded tokens with the built in
+tokens followed by the given definitions.
+*/
+func (g *Grammar) setTokens(spec tokenSpec) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.reset()
 
 	// Explicit add of whitespace token
-	// to enforce always ID of 0.
-	tokenKinds.Add(tokenName("WHTSPACE"), tokenSignature(" "))
+	// to enforce always ID of `WhtspaceId`.
+	g.add(tokenName("WHTSPACE"), tokenSignature(" "), "Whitespace.")
 
 	// Explicit add of general objects also to
-	// enforce always ID of 1-3.
-	tokenKinds.Add(tokenName("GENIDEN"), tokenSignature("&IDEN"))
-	tokenKinds.Add(tokenName("GENTYPE"), tokenSignature("&TYPE"))
-	tokenKinds.Add(tokenName("GENOBJ"), tokenSignature("&OBJ"))
+	// enforce always ID of `GenIdenId`-`GenObjId`.
+	g.add(tokenName("GENIDEN"), tokenSignature("&IDEN"), "Generic identifier.")
+	g.add(tokenName("GENTYPE"), tokenSignature("&TYPE"), "Generic type.")
+	g.add(tokenName("GENOBJ"), tokenSignature("&OBJ"), "Generic object.")
 
 	// Explicit add of general whitespace chars.
 	// Cannot properly read these values from
 	// tokens file. Not worth the jerry rigging.
-	tokenKinds.Add(tokenName("NEWLINE"), tokenSignature("\n"))
-	tokenKinds.Add(tokenName("CRETURN"), tokenSignature("\r"))
-	tokenKinds.Add(tokenName("TABLINE"), tokenSignature("\t"))
-
-	for file.Scan() {
-		name, seq := parseLine(file.Text())
-		if name == "" {
-			continue
+	g.add(tokenName("NEWLINE"), tokenSignature("\n"), "Line feed.")
+	g.add(tokenName("CRETURN"), tokenSignature("\r"), "Carriage return.")
+	g.add(tokenName("TABLINE"), tokenSignature("\t"), "Horizontal tab.")
+	g.add(tokenName("COMMENT"), tokenSignature("&COMMENT"), "Line comment.")
+	g.add(tokenName("ERROR"), tokenSignature("&ERROR"), "Input matching no kind.")
+
+	// Kinds from the tokens file start past
+	// the reserved range.
+	g.nextId = FirstUserKindId
+
+	for _, def := range spec.defs {
+		if g.enabled(def.when) {
+			g.add(def.name, def.sig, def.desc)
 		}
-		tokenKinds.Add(tokenName(name), tokenSignature(seq))
 	}
+	g.ident = spec.ident
+	g.applyLiterals(spec.literals, true)
+	g.applyAnchors(spec.anchors, true)
+	g.comments = spec.comments
+	g.applyAliases(spec.aliases, true)
+	g.applyTrivia(spec.trivia, true)
+	g.Version = spec.version
+}
+
+/*
+From the tokens file, load in defined tokens. If
+there is no tokens file, only the built in tokens
+are defined.
+*/
+func loadTokens() {
+	name, err := FindTokensFile("")
+	if err != nil {
+		defaultGrammar.setTokens(tokenSpec{})
+		return
+	}
+	check(defaultGrammar.LoadTokensFile(name))
+	defaultTokensFile = name
+}
+
+/*
+Replace the loaded tokens with those defined in
+the named tokens file. Loaded tokens are left
+untouched if the file cannot be read.
+*/
+func (g *Grammar) LoadTokensFile(name string) error {
+	file, err := openTokenFile(name, nil)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
 
-	file.Close()
+	spec, err := readTokenSpec(file)
+	if err != nil {
+		return err
+	}
+	g.setTokens(spec)
+	return nil
+}
+
+/*
+Replace the tokens of the default grammar with
+those defined in the named tokens file.
+*/
+func LoadTokensFile(name string) error {
+	return defaultGrammar.LoadTokensFile(name)
+}
+
+/*
+Replace the loaded tokens with those defined in
+the named tokens file of the given file system.
+Loaded tokens are left untouched if the file
+cannot be read.
+*/
+func (g *Grammar) LoadTokensFS(fsys fs.FS, name string) error {
+	file, err := openTokenFileFS(fsys, name, nil)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	spec, err := readTokenSpec(file)
+	if err != nil {
+		return err
+	}
+	g.setTokens(spec)
+	return nil
+}
+
+/*
+Replace the tokens of the default grammar with
+those defined in the named tokens file of the
+given file system.
+*/
+func LoadTokensFS(fsys fs.FS, name string) error {
+	return defaultGrammar.LoadTokensFS(fsys, name)
+}
+
+/*
+Replace the loaded tokens with those defined in
+the tokens read from `r`. Loaded tokens are left
+untouched if they cannot be read.
+*/
+func (g *Grammar) LoadTokens(r io.Reader) error {
+	return g.LoadTokensLimited(r, GrammarLimits{})
 }
@@ -2,13 +2,19 @@ package lexer_test
 
 import (
 	"fmt"
+	"os"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/WilkinsonK/panza-lexer"
 )
 
 func TestTokenizeFile(t *testing.T) {
-	tokens := lexer.TokenizeFile("../testfile.pz")
+	tokens, err := lexer.TokenizeFile("../testfile.pz")
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	var to lexer.TokenObject
 	for i := range tokens {
@@ -16,3 +22,269 @@ func TestTokenizeFile(t *testing.T) {
 		fmt.Printf("line[%d]    \tpos[%d]    \tid[%d]    \t%s   \t'%s'\n", to.LineNo, to.Position, to.Kind.Id, to.Kind, to.Symbol)
 	}
 }
+
+func TestKindDescription(t *testing.T) {
+	kind, ok := lexer.LookupKind("WHTSPACE")
+	if !ok {
+		t.Fatal("expected WHTSPACE to be loaded")
+	}
+	if kind.Description != "Whitespace." {
+		t.Errorf("unexpected description %q", kind.Description)
+	}
+}
+
+func TestRenderKinds(t *testing.T) {
+	g := lexer.NewGrammar("render")
+	g.AddKind("PLUS", "+", "Addition.")
+	g.AddKind("MINUS", "-", "")
+	count := g.KindCount()
+
+	full := g.RenderTokenRepr()
+	if lines := strings.Count(full, "\tToken["); lines != count {
+		t.Errorf("expected %d kinds rendered, got %d", count, lines)
+	}
+	page := g.RenderKinds(count-2, count)
+	if page != "[256]\tToken[PLUS]\t'+'\tAddition.\n[257]\tToken[MINUS]\t'-'\t\n" {
+		t.Errorf("unexpected page %q", page)
+	}
+	if !strings.HasSuffix(full, page) {
+		t.Errorf("expected the page to end the full rendering")
+	}
+	if g.RenderKinds(count, count+10) != "" || g.RenderKinds(5, 2) != "" {
+		t.Errorf("expected ranges past the kinds to render nothing")
+	}
+	if found := g.RenderKinds(-3, 1); strings.Count(found, "\n") != 1 {
+		t.Errorf("expected the range clamped to the first kind, got %q", found)
+	}
+}
+
+// Restores the tokens loaded at init.
+func reloadTokens(t *testing.T) {
+	if err := lexer.LoadTokensFS(os.DirFS(".."), "lexer.tokens"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadTokensFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"test.tokens": {Data: []byte("ARROW -> #: Points somewhere.\n")},
+		"test.pz":     {Data: []byte("->\n")},
+	}
+	defer reloadTokens(t)
+
+	if err := lexer.LoadTokensFS(fsys, "test.tokens"); err != nil {
+		t.Fatal(err)
+	}
+	kind, ok := lexer.LookupKind("ARROW")
+	if !ok || kind.Description != "Points somewhere." {
+		t.Fatalf("expected ARROW to be loaded, got %#v", kind)
+	}
+
+	tokens, err := lexer.TokenizeFileFS(fsys, "test.pz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 || tokens[0].Kind.Name != "ARROW" {
+		t.Errorf("unexpected tokens %v", tokens)
+	}
+
+	if err := lexer.LoadTokensFS(fsys, "missing.tokens"); err == nil {
+		t.Error("expected an error for a missing tokens file")
+	}
+	if _, ok := lexer.LookupKind("ARROW"); !ok {
+		t.Error("expected loaded tokens to survive a failed load")
+	}
+}
+
+func BenchmarkTokenizeLine(b *testing.B) {
+	line := "fn main() { x = 1 + 2; if x == 3 { return x; } }"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lexer.TokenizeLine(line, 1)
+	}
+}
+
+func BenchmarkTokenizeLongIdentifier(b *testing.B) {
+	line := strings.Repeat("x", 4096)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lexer.TokenizeLine(line, 1)
+	}
+}
+
+func TestTokenComparison(t *testing.T) {
+	tokens := lexer.TokenizeLines([]string{" \t ", " "})
+	space, _ := lexer.LookupKind("WHTSPACE")
+	tab, _ := lexer.LookupKind("TABLINE")
+
+	if !tokens[0].Is(space) || tokens[1].Is(space) || !tokens[1].Is(tab) {
+		t.Errorf("unexpected kinds %v", tokens)
+	}
+	if !tokens[0].SameKind(tokens[2]) || tokens[0].SameKind(tokens[1]) {
+		t.Errorf("unexpected kind comparison of %v", tokens)
+	}
+	if !tokens[0].EqualIgnoringPosition(tokens[3]) || tokens[0].EqualIgnoringPosition(tokens[1]) {
+		t.Errorf("unexpected comparison ignoring position of %v", tokens)
+	}
+	if space.Equal(tab) || !space.Equal(*tokens[0].Kind) {
+		t.Error("unexpected kind equality")
+	}
+}
+
+func TestTokenizeLongLine(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "plus")
+
+	// Well past the 64KB default of bufio.Scanner.
+	chunk := "abcdefghijklmno+"
+	line := strings.Repeat(chunk, 3<<20/len(chunk))
+	name := writeSource(t, line+"\nz\n")
+
+	lx := &lexer.Lexer{Grammar: g}
+	tokens, err := lx.TokenizeFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 2*len(line)/len(chunk) + 1; len(tokens) != want {
+		t.Fatalf("expected %d tokens, got %d", want, len(tokens))
+	}
+	last := tokens[len(tokens)-2]
+	if last.LineNo != 1 || int(last.Position) != len(line) || last.Kind.Name != "PLUS" {
+		t.Errorf("unexpected end of long line %v", last)
+	}
+	if end := tokens[len(tokens)-1]; end.LineNo != 2 || end.Symbol.String() != "z" {
+		t.Errorf("expected line after the long line to be tokenized, got %v", end)
+	}
+
+	index, err := g.TokenizeIndexed(strings.NewReader(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := index.Text(1); len(text) != len(line) {
+		t.Errorf("expected full text of long line, got %d bytes", len(text))
+	}
+}
+
+func TestTokensShareKind(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "plus")
+
+	tokens := g.TokenizeLines([]string{"+a+", "+"})
+	if tokens[0].Kind != tokens[2].Kind || tokens[0].Kind != tokens[3].Kind {
+		t.Error("expected tokens of a kind to share it")
+	}
+	if tokens[0].Kind == tokens[1].Kind {
+		t.Error("expected tokens of other kinds not to share it")
+	}
+
+	line := strings.Repeat("a+", 64)
+	allocs := testing.AllocsPerRun(10, func() { g.TokenizeLine(line, 1) })
+	if allocs > 256 {
+		t.Errorf("expected fewer than 2 allocations per token, got %v", allocs)
+	}
+}
+
+func TestSingleByteKinds(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	g.AddKind("SEMI", ";", "")
+	if got := symbolsOf(g.TokenizeLine("a+;", 1)); len(got) != 3 || got[1] != "+" {
+		t.Fatalf("expected a, +, ;, got %q", got)
+	}
+
+	// Once '+' begins a longer signature it no
+	// longer matches by itself alone.
+	fsys := fstest.MapFS{"inc.tokens": {Data: []byte("PLUS ++\n")}}
+	if err := g.MergeTokensFS(fsys, "inc.tokens", lexer.MergeOverride); err != nil {
+		t.Fatal(err)
+	}
+	tokens := g.TokenizeLine("a ++;+ ", 1)
+	if got := symbolsOf(tokens); len(got) != 6 || got[2] != "++" || tokens[4].Kind.Name != "GENIDEN" {
+		t.Errorf("expected a, ++, ; and a generic +, got %v", tokens)
+	}
+}
+
+func TestQuotedSignatures(t *testing.T) {
+	fsys := fstest.MapFS{
+		"quoted.tokens": {Data: []byte("NOT_IN \"not in\" #: Negated membership.\n" +
+			"HASHCOLON \"#:\"\nTAB_ARROW \"\\t->\"\nQUOTE \"\n")},
+		"empty.tokens": {Data: []byte("EMPTY \"\"\n")},
+	}
+
+	g := lexer.NewGrammar("x")
+	if err := g.LoadTokensFS(fsys, "quoted.tokens"); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{"NOT_IN": "not in", "HASHCOLON": "#:", "TAB_ARROW": "\t->", "QUOTE": "\""}
+	for name, sig := range cases {
+		if kind, ok := g.LookupKind(name); !ok || kind.Signature.String() != sig {
+			t.Errorf("expected %s %q, got %#v", name, sig, kind)
+		}
+	}
+	if kind, _ := g.LookupKind("NOT_IN"); kind.Description != "Negated membership." {
+		t.Errorf("unexpected description %q", kind.Description)
+	}
+	if got := renderKinds(g.TokenizeLine("x not in y", 1)); got != "GENIDEN:x WHTSPACE:  NOT_IN:not in WHTSPACE:  GENIDEN:y" {
+		t.Errorf("unexpected tokens %s", got)
+	}
+
+	if err := g.LoadTokensFS(fsys, "empty.tokens"); err == nil {
+		t.Error("expected an error for an empty signature")
+	}
+}
+
+// Results kept by callers, naming their type.
+type parsedLine struct {
+	tokens lexer.TokenObjects
+}
+
+func TestNamedResultType(t *testing.T) {
+	line := parsedLine{lexer.TokenizeLine("fn main", 1)}
+	var plain []lexer.TokenObject = line.tokens
+	if len(plain) != 3 || line.tokens.CursorAt(0).Token().Kind.Name != "FN" {
+		t.Errorf("unexpected tokens %v", line.tokens)
+	}
+}
+
+func TestTokenizeLineSkipping(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("PLUS", "+", "")
+	plus, _ := g.LookupKind("PLUS")
+
+	if got := renderKinds(g.TokenizeLineSkipping("a + b", 1, lexer.WhtspaceId)); got != "GENIDEN:a PLUS:+ GENIDEN:b" {
+		t.Errorf("unexpected tokens %s", got)
+	}
+	if got := renderKinds(g.TokenizeLineSkipping("a + b", 1, lexer.WhtspaceId, plus.Id)); got != "GENIDEN:a GENIDEN:b" {
+		t.Errorf("unexpected tokens %s", got)
+	}
+	// Other calls are unaffected.
+	if got := g.TokenizeLine("a + b", 1); len(got) != 5 {
+		t.Errorf("expected 5 tokens, got %d", len(got))
+	}
+}
+
+func TestFindOrder(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("SHLEQ", "<<=", "")
+	g.AddKind("LT", "<", "")
+	g.AddKind("SHL", "<<", "")
+	g.AddKind("ALSO_LT", "<", "")
+
+	for i := 0; i < 10; i++ {
+		found := g.Find("<")
+		if len(found) != 4 || found[0].Name != "SHLEQ" || found[3].Name != "ALSO_LT" {
+			t.Fatalf("expected kinds in order of ID, got %v", found)
+		}
+		for j := 1; j < len(found); j++ {
+			if found[j-1].Id >= found[j].Id {
+				t.Fatalf("expected kinds in order of ID, got %v", found)
+			}
+		}
+	}
+	if exact := g.FindEx("<"); len(exact) != 2 || exact[0].Name != "LT" || exact[1].Name != "ALSO_LT" {
+		t.Errorf("expected LT then ALSO_LT, got %v", exact)
+	}
+	if got := renderKinds(g.TokenizeLine("<", 1)); got != "LT:<" {
+		t.Errorf("expected the first exact match tokenized, got %s", got)
+	}
+}
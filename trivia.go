@@ -0,0 +1,175 @@
+package lexer
+
+import (
+	"fmt"
+	"sort"
+)
+
+/* --- TRIVIA POLICIES ---
+Grammars may declare kinds as trivia, with the
+`@trivia` directive or `SetTrivia`, together
+with what becomes of their tokens, so every
+caller of the grammar treats them the same
+without setting it again:
+
+preserve: Tokens are kept like any other.
+attach: Tokens are attached to the `Comments` of
+the token before them, skipping whitespace, as
+comments are in attach mode. A token with no
+token before it is kept.
+drop: Tokens are left out.
+
+Options of the lexer come first: whitespace it
+skips and comments it attaches or drops go as
+it says, whatever the grammar's policy. A lexer
+given `KeepTrivia` ignores the policies, for
+tools needing every token. Line breaks a lexer
+emits itself are governed by its `Newlines`
+mode. */
+
+/* What becomes of tokens of a trivia kind. */
+type TriviaPolicy uint8
+
+const (
+	TriviaPreserve TriviaPolicy = iota
+	TriviaAttach
+	TriviaDrop
+)
+
+var triviaPolicyNames = []string{"preserve", "attach", "drop"}
+
+func (tp TriviaPolicy) String() string {
+	if int(tp) < len(triviaPolicyNames) {
+		return triviaPolicyNames[tp]
+	}
+	return fmt.Sprintf("TriviaPolicy(%d)", uint8(tp))
+}
+
+/* Look up a trivia policy by its name, e.g. `drop`. */
+func ParseTriviaPolicy(name string) (TriviaPolicy, error) {
+	for i, known := range triviaPolicyNames {
+		if name == known {
+			return TriviaPolicy(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown trivia policy %q, expected preserve, attach or drop", name)
+}
+
+/* Declare the named kind as trivia, with the given policy. */
+func (g *Grammar) SetTrivia(kind string, policy TriviaPolicy) error {
+	if policy > TriviaDrop {
+		return fmt.Errorf("unknown trivia policy %s", policy)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	found, ok := g.kinds.ByName(tokenName(kind))
+	if !ok {
+		return fmt.Errorf("unknown kind %q", kind)
+	}
+	if g.trivia == nil {
+		g.trivia = map[tokenId]TriviaPolicy{}
+	}
+	g.trivia[found.Id] = policy
+	return nil
+}
+
+/* The policy of the named kind; false if it is not declared trivia. */
+func (g *Grammar) TriviaPolicy(kind string) (TriviaPolicy, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	found, ok := g.kinds.ByName(tokenName(kind))
+	if !ok {
+		return 0, false
+	}
+	policy, ok := g.trivia[found.Id]
+	return policy, ok
+}
+
+/* Names of the kinds declared trivia, sorted. */
+func (g *Grammar) TriviaKinds() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	names := make([]string, 0, len(g.trivia))
+	for id := range g.trivia {
+		names = append(names, string(g.kinds.Ref(id).Name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+Declare kinds trivia, by name. Kinds already
+declared keep their policy unless `overwrite` is
+set.
+*/
+func (g *Grammar) applyTrivia(trivia map[tokenName]TriviaPolicy, overwrite bool) {
+	for name, policy := range trivia {
+		kind, ok := g.kinds.ByName(name)
+		if !ok {
+			continue
+		}
+		if _, declared := g.trivia[kind.Id]; declared && !overwrite {
+			continue
+		}
+		if g.trivia == nil {
+			g.trivia = map[tokenId]TriviaPolicy{}
+		}
+		g.trivia[kind.Id] = policy
+	}
+}
+
+/*
+Append a token to `tokens` per its kind's
+trivia policy. The caller must hold the
+grammar's read lock.
+*/
+func (lx *Lexer) retainTrivia(g *Grammar, tokens TokenObjects, to TokenObject) TokenObjects {
+	policy := TriviaPreserve
+	if !lx.KeepTrivia {
+		policy = g.trivia[to.Kind.Id]
+	}
+	switch policy {
+	case TriviaDrop:
+		return tokens
+	case TriviaAttach:
+		return attachTrivia(tokens, to)
+	}
+	return append(tokens, to)
+}
+
+/*
+Attach a token to the `Comments` of the last of
+`tokens` that is neither whitespace nor a
+comment; appended if there is none.
+*/
+func attachTrivia(tokens TokenObjects, to TokenObject) TokenObjects {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i].IsTrivia() || tokens[i].Kind.Id == CommentId {
+			continue
+		}
+		tokens[i].Comments = append(tokens[i].Comments, to)
+		return tokens
+	}
+	return append(tokens, to)
+}
+
+/*
+Determine if the named kind is built in
+whitespace or `COMMENT`, which tokens files may
+declare trivia without defining.
+*/
+func isBuiltinTrivia(name tokenName) bool {
+	if name == "COMMENT" {
+		return true
+	}
+	for _, known := range triviaKinds {
+		if string(name) == known {
+			return true
+		}
+	}
+	return false
+}
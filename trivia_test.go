@@ -0,0 +1,71 @@
+package lexer_test
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestTriviaPolicies(t *testing.T) {
+	fsys := fstest.MapFS{
+		"trivia.tokens":    {Data: []byte("PLUS +\n@comment #\n@trivia COMMENT attach\n@trivia WHTSPACE drop\n@trivia PLUS preserve\n")},
+		"policy.tokens":    {Data: []byte("PLUS +\n@trivia PLUS keep\n")},
+		"undefined.tokens": {Data: []byte("@trivia PLUS drop\nPLUS +\n")},
+	}
+	g := lexer.NewGrammar("trivia")
+	if err := g.LoadTokensFS(fsys, "trivia.tokens"); err != nil {
+		t.Fatal(err)
+	}
+	if kinds := strings.Join(g.TriviaKinds(), " "); kinds != "COMMENT PLUS WHTSPACE" {
+		t.Errorf("expected COMMENT, PLUS and WHTSPACE declared trivia, got %s", kinds)
+	}
+	if policy, ok := g.TriviaPolicy("WHTSPACE"); !ok || policy != lexer.TriviaDrop {
+		t.Errorf("expected WHTSPACE dropped, got %s", policy)
+	}
+	if _, ok := g.TriviaPolicy("GENIDEN"); ok {
+		t.Errorf("expected GENIDEN not declared trivia")
+	}
+
+	render := func(tokens lexer.TokenObjects) string {
+		var b strings.Builder
+		for _, to := range tokens {
+			b.WriteString(string(to.Kind.Name) + ":" + string(to.Symbol))
+			for _, comment := range to.Comments {
+				b.WriteString("[" + string(comment.Symbol) + "]")
+			}
+			b.WriteByte(' ')
+		}
+		return strings.TrimSpace(b.String())
+	}
+	cases := []struct {
+		lx   *lexer.Lexer
+		want string
+	}{
+		{&lexer.Lexer{Grammar: g}, "GENIDEN:a PLUS:+ GENIDEN:b[# note]"},
+		{&lexer.Lexer{Grammar: g, Comments: lexer.CommentDrop}, "GENIDEN:a PLUS:+ GENIDEN:b"},
+		{&lexer.Lexer{Grammar: g, KeepTrivia: true}, "GENIDEN:a WHTSPACE:  PLUS:+ WHTSPACE:  GENIDEN:b WHTSPACE:  COMMENT:# note"},
+	}
+	for _, c := range cases {
+		if got := render(c.lx.TokenizeLine("a + b # note", 1)); got != c.want {
+			t.Errorf("expected %q, got %q", c.want, got)
+		}
+	}
+
+	if err := g.SetTrivia("PLUS", lexer.TriviaDrop); err != nil {
+		t.Fatal(err)
+	}
+	if got := render(g.TokenizeLine("a+b", 1)); got != "GENIDEN:a GENIDEN:b" {
+		t.Errorf("expected PLUS dropped, got %q", got)
+	}
+	if err := g.SetTrivia("MISSING", lexer.TriviaDrop); err == nil {
+		t.Error("expected an error for an unknown kind")
+	}
+
+	for _, name := range []string{"policy.tokens", "undefined.tokens"} {
+		if err := lexer.NewGrammar("x").LoadTokensFS(fsys, name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
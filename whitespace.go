@@ -0,0 +1,209 @@
+package lexer
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* --- WHITESPACE ---
+Whitespace is classified by the grammar's
+whitespace kinds, those whose signatures are only
+whitespace: the built in `WHTSPACE`, `TABLINE`,
+`NEWLINE` and `CRETURN`, and any such kind added.
+The bytes of their signatures are the grammar's
+whitespace, as used for anchors, multi-word
+signatures and telling trivia from tokens.
+
+Whitespace comes in classes, by the bytes of a
+kind's signature: spaces (including vertical
+tabs and form feeds), tabs, line feeds and
+carriage returns. Formatters may tell the kinds
+apart by class, and parsers drop every class at
+once with `Lexer.SkipWhitespace`. Line breaks a
+lexer emits itself are governed by its
+`Newlines` mode instead. */
+
+/* Classes of whitespace, as a set. */
+type WhitespaceClass uint8
+
+const (
+	WhitespaceSpace WhitespaceClass = 1 << iota
+	WhitespaceTab
+	WhitespaceNewline
+	WhitespaceCR
+
+	WhitespaceNone WhitespaceClass = 0
+	WhitespaceAll                  = WhitespaceSpace | WhitespaceTab | WhitespaceNewline | WhitespaceCR
+)
+
+var whitespaceClassNames = []string{"space", "tab", "newline", "cr"}
+
+func (wc WhitespaceClass) String() string {
+	if wc == WhitespaceNone {
+		return "none"
+	}
+	var names []string
+	for i, name := range whitespaceClassNames {
+		if wc&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+/*
+Look up a set of whitespace classes by their
+comma separated names, e.g. `space,tab`; or
+`all` or `none`.
+*/
+func ParseWhitespaceClass(list string) (WhitespaceClass, error) {
+	switch list {
+	case "all":
+		return WhitespaceAll, nil
+	case "none", "":
+		return WhitespaceNone, nil
+	}
+	var wc WhitespaceClass
+next:
+	for _, name := range strings.Split(list, ",") {
+		for i, known := range whitespaceClassNames {
+			if strings.TrimSpace(name) == known {
+				wc |= 1 << i
+				continue next
+			}
+		}
+		return 0, fmt.Errorf("unknown whitespace class %q, expected space, tab, newline, cr, all or none", name)
+	}
+	return wc, nil
+}
+
+/* Class of a whitespace byte; none if `b` is not whitespace. */
+func classOfByte(b byte) WhitespaceClass {
+	switch b {
+	case ' ', '\v', '\f':
+		return WhitespaceSpace
+	case '\t':
+		return WhitespaceTab
+	case '\n':
+		return WhitespaceNewline
+	case '\r':
+		return WhitespaceCR
+	}
+	return WhitespaceNone
+}
+
+/*
+Classes of whitespace the kind's signature is
+made of; none if it is not a whitespace kind.
+*/
+func (tk TokenKind) Whitespace() WhitespaceClass {
+	var wc WhitespaceClass
+	for _, b := range tk.Signature {
+		class := classOfByte(b)
+		if class == WhitespaceNone {
+			return WhitespaceNone
+		}
+		wc |= class
+	}
+	return wc
+}
+
+/* Classes of whitespace the token's kind is made of. */
+func (to TokenObject) Whitespace() WhitespaceClass {
+	if to.Kind == nil {
+		return WhitespaceNone
+	}
+	return to.Kind.Whitespace()
+}
+
+/*
+Determine if the token is whitespace of the
+given classes alone, so `WhitespaceAll` holds
+every whitespace token.
+*/
+func (to TokenObject) IsWhitespaceOf(classes WhitespaceClass) bool {
+	wc := to.Whitespace()
+	return wc != WhitespaceNone && wc&^classes == 0
+}
+
+/*
+Copy of the tokens without whitespace of the
+given classes alone; e.g. `WhitespaceSpace |
+WhitespaceTab` drops the whitespace between
+tokens but keeps line breaks.
+*/
+func (tom TokenObjects) WithoutWhitespace(classes WhitespaceClass) TokenObjects {
+	kept := TokenObjects{}
+	for _, to := range tom {
+		if !to.IsWhitespaceOf(classes) {
+			kept = append(kept, to)
+		}
+	}
+	return kept
+}
+
+/* Determine if `b` is ASCII whitespace. */
+func isSpaceByte(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+/* Determine if a signature is whitespace alone. */
+func isWhitespaceSig(sig tokenSignature) bool {
+	for _, b := range sig {
+		if !isSpaceByte(b) {
+			return false
+		}
+	}
+	return len(sig) > 0
+}
+
+/* Determine if `s` is ASCII whitespace alone, or empty. */
+func allSpace(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isSpaceByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+/* Determine if a kind is a whitespace kind. */
+func isWhitespaceKind(kind *TokenKind) bool {
+	return kind != nil && isWhitespaceSig(kind.Signature)
+}
+
+/*
+Count, per byte, the whitespace kinds whose
+signatures hold it.
+*/
+func (bi *byteIndex) countSpaces(kind TokenKind, delta int) {
+	if !isWhitespaceSig(kind.Signature) {
+		return
+	}
+	seen := [256]bool{}
+	for _, b := range kind.Signature {
+		if !seen[b] {
+			seen[b] = true
+			bi.spaces[b] += delta
+		}
+	}
+}
+
+/* Determine if `b` is whitespace of this grammar. */
+func (g *Grammar) isWhitespace(b byte) bool {
+	return g.kinds.bytes.spaces[b] > 0
+}
+
+/* Determine if `s` holds only whitespace of this grammar. */
+func (g *Grammar) isBlank(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !g.isWhitespace(s[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package lexer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestWhitespaceKinds(t *testing.T) {
+	g := lexer.NewGrammar("x")
+	g.AddKind("HASH", "#", "")
+	g.AddKind("FORMFEED", "\f", "")
+	g.AddKind("NOT_IN", "not in", "")
+	if err := g.SetAnchor("HASH", lexer.AnchorStart); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		line string
+		want string
+	}{
+		// Any whitespace kind may come before an
+		// anchored kind.
+		{"\t#", "TABLINE:\t HASH:#"},
+		{"\f #", "FORMFEED:\f WHTSPACE:  HASH:#"},
+		{"a #", "GENIDEN:a WHTSPACE:  GENIDEN:#"},
+		// And separate the words of a signature.
+		{"not\fin", "NOT_IN:not\fin"},
+	}
+	for _, c := range cases {
+		if got := renderKinds(g.TokenizeLine(c.line, 1)); got != c.want {
+			t.Errorf("%q: expected %q, got %q", c.line, c.want, got)
+		}
+	}
+
+	tokens := g.TokenizeLine("\f#", 1)
+	if !tokens[0].IsTrivia() || tokens[1].IsTrivia() {
+		t.Errorf("expected only the form feed to be trivia, got %v", tokens)
+	}
+}
+
+func TestWhitespaceClasses(t *testing.T) {
+	g := lexer.NewGrammar("classes")
+	g.AddKind("PLUS", "+", "")
+	g.AddKind("CRLF", "\r\n", "")
+	g.AddKind("INDENT", "    ", "")
+
+	cases := map[string]lexer.WhitespaceClass{
+		"WHTSPACE": lexer.WhitespaceSpace,
+		"INDENT":   lexer.WhitespaceSpace,
+		"TABLINE":  lexer.WhitespaceTab,
+		"NEWLINE":  lexer.WhitespaceNewline,
+		"CRETURN":  lexer.WhitespaceCR,
+		"CRLF":     lexer.WhitespaceNewline | lexer.WhitespaceCR,
+		"PLUS":     lexer.WhitespaceNone,
+	}
+	for name, expected := range cases {
+		kind, ok := g.LookupKind(name)
+		if !ok {
+			t.Fatalf("expected kind %s", name)
+		}
+		if kind.Whitespace() != expected {
+			t.Errorf("%s: expected %s, got %s", name, expected, kind.Whitespace())
+		}
+	}
+
+	tokens := g.TokenizeLine("a +\tb", 1)
+	if got := renderKinds(tokens.WithoutWhitespace(lexer.WhitespaceSpace)); got != "GENIDEN:a PLUS:+ TABLINE:\t GENIDEN:b" {
+		t.Errorf("expected only spaces dropped, got %s", got)
+	}
+	if got := renderKinds(tokens.WithoutWhitespace(lexer.WhitespaceAll)); got != "GENIDEN:a PLUS:+ GENIDEN:b" {
+		t.Errorf("expected all whitespace dropped, got %s", got)
+	}
+
+	lx := &lexer.Lexer{Grammar: g, SkipWhitespace: lexer.WhitespaceSpace | lexer.WhitespaceTab, Newlines: lexer.NewlineEmit}
+	read, err := lx.TokenizeReader(strings.NewReader("a + b\n\tc\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := renderKinds(read); got != "GENIDEN:a PLUS:+ GENIDEN:b NEWLINE:\n GENIDEN:c NEWLINE:\n" {
+		t.Errorf("expected spaces and tabs skipped while lexing, got %q", got)
+	}
+
+	if wc, err := lexer.ParseWhitespaceClass("space, tab"); err != nil || wc.String() != "space,tab" {
+		t.Errorf("expected space,tab, got %s, %v", wc, err)
+	}
+	if wc, err := lexer.ParseWhitespaceClass("all"); err != nil || wc != lexer.WhitespaceAll {
+		t.Errorf("expected every class, got %s, %v", wc, err)
+	}
+	if _, err := lexer.ParseWhitespaceClass("space,vtab"); err == nil {
+		t.Error("expected an unknown class to be rejected")
+	}
+}
@@ -0,0 +1,87 @@
+package lexer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/* --- MULTI-WORD SIGNATURES ---
+Signatures may hold several words, e.g. `GROUP BY`
+or `not in`, defined quoted in tokens files. Such
+a signature is matched as a single token however
+much whitespace separates its words in the
+input; the token's symbol is the input as is.
+
+The last word must end where a word of the input
+does, so `GROUP BYTES` is not `GROUP BY` followed
+by `TES`. Where the words do not complete, input
+is matched word by word as usual. */
+
+/*
+Determine if a signature holds whitespace between
+words, as opposed to only before or after them.
+*/
+func isSpaced(sig string) bool {
+	trimmed := strings.TrimFunc(sig, isSpaceRune)
+	return trimmed != "" && strings.IndexFunc(trimmed, isSpaceRune) >= 0
+}
+
+/* Determine if `r` is ASCII whitespace. */
+func isSpaceRune(r rune) bool {
+	return r < utf8.RuneSelf && isSpaceByte(byte(r))
+}
+
+/*
+Match a signature of several words against the
+start of `line`. Each run of whitespace between
+words matches any run of whitespace. Returns the
+length of input matched.
+*/
+func (g *Grammar) matchWords(line string, sig string) (int, bool) {
+	i, j := 0, 0
+	for i < len(sig) {
+		if isSpaceByte(sig[i]) && i > 0 && !allSpace(sig[i:]) {
+			for i < len(sig) && isSpaceByte(sig[i]) {
+				i++
+			}
+			if j >= len(line) || !g.isWhitespace(line[j]) {
+				return 0, false
+			}
+			for j < len(line) && g.isWhitespace(line[j]) {
+				j++
+			}
+			continue
+		}
+		if j >= len(line) || line[j] != sig[i] {
+			return 0, false
+		}
+		i++
+		j++
+	}
+
+	if g.isWordRune(lastRune(sig)) && j < len(line) {
+		if r, _ := utf8.DecodeRuneInString(line[j:]); g.isWordRune(r) {
+			return 0, false
+		}
+	}
+	return j, true
+}
+
+/* The last rune of `s`. */
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+/*
+Determine if `r` may be part of a word; a letter,
+digit or '_', or of the grammar's identifier
+classes if declared.
+*/
+func (g *Grammar) isWordRune(r rune) bool {
+	if g.ident != nil {
+		return g.ident.Contains(r)
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,32 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/WilkinsonK/panza-lexer"
+)
+
+func TestMultiWordSignatures(t *testing.T) {
+	g := lexer.NewGrammar("sql")
+	g.AddKind("GROUP", "GROUP", "")
+	g.AddKind("BY", "BY", "")
+	g.AddKind("GROUP_BY", "GROUP BY", "")
+
+	cases := []struct {
+		line string
+		want string
+	}{
+		{"GROUP BY x", "GROUP_BY:GROUP BY WHTSPACE:  GENIDEN:x"},
+		{"GROUP \t BY", "GROUP_BY:GROUP \t BY"},
+		// The pair does not complete.
+		{"GROUP x", "GROUP:GROUP WHTSPACE:  GENIDEN:x"},
+		{"GROUP BYTES", "GROUP:GROUP WHTSPACE:  BY:BY GENIDEN:TES"},
+		{"GROUPBY", "GROUP:GROUP BY:BY"},
+		{"GROUP", "GROUP:GROUP"},
+	}
+	for _, c := range cases {
+		if got := renderKinds(g.TokenizeLine(c.line, 1)); got != c.want {
+			t.Errorf("%q: expected %s, got %s", c.line, c.want, got)
+		}
+	}
+}